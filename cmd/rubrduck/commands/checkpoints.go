@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hammie/rubrduck/internal/checkpoint"
+	"github.com/hammie/rubrduck/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// checkpointsCmd lists the checkpoints of the current directory
+var checkpointsCmd = &cobra.Command{
+	Use:   "checkpoints",
+	Short: "List the agent turns that can be undone",
+	Long: `List the checkpoints saved for the current directory, oldest first.
+
+With checkpoints.enabled set, a checkpoint is saved for each agent turn
+that changes files, holding the files as they were before the turn. Use
+'rubrduck undo' to roll back the latest turn or 'rubrduck restore <n>' to
+roll back turn n and every later one. Files that shell commands created
+are removed too, but shell edits to existing files aren't captured.
+Checkpoints are kept in ~/.rubrduck/checkpoints unless checkpoints.dir is
+set.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		checkpoints, err := openCheckpoints()
+		if err != nil {
+			return err
+		}
+		list, err := checkpoints.List()
+		if err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			fmt.Println("No checkpoints for this directory.")
+			return nil
+		}
+		for _, cp := range list {
+			fmt.Println(cp.Summary())
+		}
+		return nil
+	},
+}
+
+// undoCmd rolls back the latest checkpoint
+var undoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Undo the file changes of the latest agent turn",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		checkpoints, err := openCheckpoints()
+		if err != nil {
+			return err
+		}
+		cp, err := checkpoints.Undo()
+		if errors.Is(err, checkpoint.ErrNoCheckpoints) {
+			fmt.Println("Nothing to undo.")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Println("Undid " + checkpoint.Describe([]checkpoint.Checkpoint{*cp}))
+		return nil
+	},
+}
+
+// restoreCmd rolls back to before a checkpoint
+var restoreCmd = &cobra.Command{
+	Use:   "restore <n>",
+	Short: "Undo agent turn n and every later one",
+	Long: `Return the files to their state before checkpoint n, rolling back that
+turn and every later one together. If any file can't be restored, none
+are. See 'rubrduck checkpoints' for the numbers.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+		if err != nil {
+			return fmt.Errorf("invalid checkpoint %q", args[0])
+		}
+		checkpoints, err := openCheckpoints()
+		if err != nil {
+			return err
+		}
+		undone, err := checkpoints.Restore(id)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Restored " + checkpoint.Describe(undone))
+		return nil
+	},
+}
+
+// openCheckpoints opens the checkpoints of the current directory
+func openCheckpoints() (*checkpoint.Manager, error) {
+	cfg, err := config.LoadUnvalidated()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	return checkpoint.Open(cfg.Checkpoints.Path(), ".", cfg.Checkpoints.Keep)
+}
+
+func init() {
+	rootCmd.AddCommand(checkpointsCmd)
+	rootCmd.AddCommand(undoCmd)
+	rootCmd.AddCommand(restoreCmd)
+}
@@ -0,0 +1,285 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/hammie/rubrduck/internal/agent"
+	"github.com/hammie/rubrduck/internal/ai"
+	"github.com/hammie/rubrduck/internal/config"
+	tui2 "github.com/hammie/rubrduck/internal/tui2"
+	"github.com/hammie/rubrduck/internal/usage"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes of a headless run
+const (
+	// ExitFailure covers configuration, provider and streaming errors
+	ExitFailure = 1
+	// ExitUsage is returned for invalid flags
+	ExitUsage = 2
+	// ExitDenied means a tool call needed approval and was denied
+	ExitDenied = 3
+	// ExitLimit means a spending limit stopped the request
+	ExitLimit = 4
+)
+
+// ExitError ends the process with Code; main prints Err, if any, first
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit status %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// headlessModes are the modes a one-shot run can use
+var headlessModes = []string{"planning", "building", "debugging", "enhance"}
+
+// headlessTool is one tool call reported in JSON output
+type headlessTool struct {
+	ID     string `json:"id"`
+	Tool   string `json:"tool"`
+	Result string `json:"result"`
+	Denied bool   `json:"denied,omitempty"`
+}
+
+// headlessResult is the --output json document and the final stream-json
+// event
+type headlessResult struct {
+	Type      string         `json:"type,omitempty"`
+	Mode      string         `json:"mode"`
+	Provider  string         `json:"provider"`
+	Model     string         `json:"model"`
+	Response  string         `json:"response"`
+	Reasoning string         `json:"reasoning,omitempty"`
+	Tools     []headlessTool `json:"tools,omitempty"`
+	Usage     ai.Usage       `json:"usage"`
+	ExitCode  int            `json:"exit_code"`
+	Error     string         `json:"error,omitempty"`
+}
+
+// headlessEvent is one line of --output stream-json
+type headlessEvent struct {
+	Type   string `json:"type"`
+	Text   string `json:"text,omitempty"`
+	ID     string `json:"id,omitempty"`
+	Tool   string `json:"tool,omitempty"`
+	Result string `json:"result,omitempty"`
+	Denied bool   `json:"denied,omitempty"`
+}
+
+// addHeadlessFlags adds the flags shared by one-shot runs
+func addHeadlessFlags(cmd *cobra.Command) {
+	cmd.Flags().String("output", "text", "output of a one-shot prompt: text, json or stream-json")
+	cmd.Flags().Int("max-stdin", defaultStdinLimit, "bytes of piped input to send; the middle of longer input is cut")
+}
+
+// checkHeadlessMode reports an invalid --mode as a usage error
+func checkHeadlessMode(mode string) error {
+	for _, m := range headlessModes {
+		if m == mode {
+			return nil
+		}
+	}
+	return &ExitError{Code: ExitUsage, Err: fmt.Errorf("invalid mode %q: use %s", mode, strings.Join(headlessModes, ", "))}
+}
+
+// runWithPrompt runs one agent turn in mode without the TUI and writes the
+// reply to stdout as text, a JSON document or a stream of JSON events.
+// Piped stdin is attached to the prompt as context.
+func runWithPrompt(cmd *cobra.Command, mode, prompt string) error {
+	// main prints the error with the exit code
+	cmd.SilenceErrors = true
+	if err := checkHeadlessMode(mode); err != nil {
+		return err
+	}
+	output, _ := cmd.Flags().GetString("output")
+	if output != "text" && output != "json" && output != "stream-json" {
+		return &ExitError{Code: ExitUsage, Err: fmt.Errorf("invalid output %q: use text, json or stream-json", output)}
+	}
+	maxStdin, _ := cmd.Flags().GetInt("max-stdin")
+	cmd.SilenceUsage = true
+
+	input, err := readPipedStdin(maxStdin)
+	if err != nil {
+		return &ExitError{Code: ExitFailure, Err: err}
+	}
+	if strings.TrimSpace(prompt) == "" && strings.TrimSpace(input) == "" {
+		return &ExitError{Code: ExitUsage, Err: fmt.Errorf("a prompt or piped input is required")}
+	}
+	prompt = withStdinContext(prompt, input)
+
+	cfg, err := config.Load()
+	if err != nil {
+		return &ExitError{Code: ExitFailure, Err: fmt.Errorf("failed to load configuration: %w", err)}
+	}
+
+	ag, err := agent.New(cfg)
+	if err != nil {
+		return &ExitError{Code: ExitFailure, Err: err}
+	}
+	ag.SetThinkingBudget(cfg.Thinking.BudgetFor(mode))
+	ag.SetMode(mode)
+	ag.SetApprovalCallback(denyApprovals(cfg.Agent.ApprovalMode))
+
+	ctx, cancel := context.WithTimeout(context.Background(), headlessTimeout(cfg, mode))
+	defer cancel()
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	result := &headlessResult{Mode: mode, Provider: cfg.Provider, Model: ag.Model().ID}
+	emit := func(ev headlessEvent) {}
+	if output == "stream-json" {
+		encoder := json.NewEncoder(os.Stdout)
+		emit = func(ev headlessEvent) { encoder.Encode(ev) }
+	}
+
+	runErr := streamHeadless(ctx, ag, mode, prompt, cfg.Model, output, result, emit)
+
+	var exitErr *ExitError
+	if runErr != nil {
+		result.Error = runErr.Error()
+		exitErr = &ExitError{Code: ExitFailure, Err: runErr}
+		if errors.Is(runErr, usage.ErrLimitReached) {
+			exitErr.Code = ExitLimit
+		}
+	} else {
+		for _, tool := range result.Tools {
+			if tool.Denied {
+				exitErr = &ExitError{Code: ExitDenied}
+				break
+			}
+		}
+	}
+	if exitErr != nil {
+		result.ExitCode = exitErr.Code
+	}
+
+	switch output {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(result)
+	case "stream-json":
+		result.Type = "result"
+		json.NewEncoder(os.Stdout).Encode(result)
+	default:
+		if result.Response != "" && !strings.HasSuffix(result.Response, "\n") {
+			fmt.Println()
+		}
+		if exitErr != nil && exitErr.Code == ExitDenied {
+			fmt.Fprintf(os.Stderr, "Some tool calls were denied (approval mode %s); see --approval-mode.\n", cfg.Agent.ApprovalMode)
+		}
+	}
+
+	if exitErr == nil {
+		return nil
+	}
+	return exitErr
+}
+
+// streamHeadless sends the prompt through the mode's request handler and
+// collects the events into result, printing text as it arrives
+func streamHeadless(ctx context.Context, ag *agent.Agent, mode, prompt, model, output string, result *headlessResult, emit func(headlessEvent)) error {
+	var ch <-chan agent.StreamEvent
+	var err error
+	switch mode {
+	case "planning":
+		ch, err = tui2.ProcessPlanningRequest(ctx, ag, prompt, model)
+	case "building":
+		ch, err = tui2.ProcessBuildingRequest(ctx, ag, prompt, model)
+	case "debugging":
+		ch, err = tui2.ProcessDebuggingRequest(ctx, ag, prompt, model)
+	case "enhance":
+		ch, err = tui2.ProcessEnhanceRequest(ctx, ag, prompt, model)
+	}
+	if err != nil {
+		return err
+	}
+
+	var text io.Writer = io.Discard
+	if output == "text" {
+		text = os.Stdout
+	}
+
+	afterTool := false
+	for ev := range ch {
+		switch ev.Type {
+		case agent.EventTokenChunk:
+			// The reply after tool calls starts a new paragraph
+			token := ev.Token
+			if afterTool && result.Response != "" && !strings.HasSuffix(result.Response, "\n") {
+				token = "\n\n" + token
+			}
+			afterTool = false
+			result.Response += token
+			fmt.Fprint(text, token)
+			emit(headlessEvent{Type: "text", Text: ev.Token})
+		case agent.EventReasoning:
+			result.Reasoning += ev.Token
+			emit(headlessEvent{Type: "reasoning", Text: ev.Token})
+		case agent.EventToolResult:
+			afterTool = true
+			result.Tools = append(result.Tools, headlessTool{ID: ev.ToolID, Tool: ev.ToolName, Result: ev.Result, Denied: ev.Denied})
+			emit(headlessEvent{Type: "tool_result", ID: ev.ToolID, Tool: ev.ToolName, Result: ev.Result, Denied: ev.Denied})
+			if output == "text" {
+				status := "done"
+				if ev.Denied {
+					status = strings.ToLower(ev.Result)
+				}
+				fmt.Fprintf(os.Stderr, "[%s] %s\n", ev.ToolName, status)
+			}
+		case agent.EventDone:
+			result.Usage = ev.Usage
+			if ev.Err != nil {
+				return ev.Err
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("request stopped: %w", ctx.Err())
+	}
+	return nil
+}
+
+// denyApprovals answers approval requests without a terminal. Requests
+// that reach it were not auto-approved under the approval mode, so they
+// are denied with a reason the model can pass on.
+func denyApprovals(approvalMode string) agent.ApprovalCallback {
+	return func(req agent.ApprovalRequest) (agent.ApprovalResult, error) {
+		return agent.ApprovalResult{
+			Approved: false,
+			Reason:   fmt.Sprintf("%s needs interactive approval in %s mode", req.Type, approvalMode),
+		}, nil
+	}
+}
+
+// headlessTimeout returns the mode's request timeout, as in the TUI
+func headlessTimeout(cfg *config.Config, mode string) time.Duration {
+	seconds := map[string]int{
+		"planning":  cfg.TUI.PlanningTimeout,
+		"building":  cfg.TUI.BuildingTimeout,
+		"debugging": cfg.TUI.DebugTimeout,
+		"enhance":   cfg.TUI.EnhanceTimeout,
+	}[mode]
+	if seconds <= 0 {
+		seconds = cfg.Agent.Timeout
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,241 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hammie/rubrduck/internal/agent"
+	"github.com/hammie/rubrduck/internal/ai"
+	"github.com/hammie/rubrduck/internal/ai/providers"
+	"github.com/hammie/rubrduck/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// modelsCmd represents the models command
+var modelsCmd = &cobra.Command{
+	Use:   "models",
+	Short: "List the models available from configured providers",
+	Long: `List the models each configured provider offers, merged with the model
+registry's context windows, capabilities and prices.
+
+Providers with a list endpoint (OpenAI, OpenAI-compatible, Gemini, Ollama)
+are queried live; others show the models known to the registry. Use
+--provider to limit the listing to one provider.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		if format != "table" && format != "json" {
+			return fmt.Errorf("invalid format %q: use table or json", format)
+		}
+
+		cfg, err := config.LoadUnvalidated()
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+		agent.RegisterConfiguredModels(cfg.Models)
+
+		// The global --provider defaults to openai, so only honour it when set
+		var names []string
+		if cmd.Flags().Changed("provider") {
+			if _, ok := cfg.Providers[provider]; !ok {
+				return fmt.Errorf("provider %s not configured", provider)
+			}
+			names = []string{provider}
+		} else {
+			for name := range cfg.Providers {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		var listings []modelListing
+		for _, name := range names {
+			listings = append(listings, listProviderModels(ctx, cfg, name)...)
+		}
+
+		if format == "json" {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(listings)
+		}
+		printModelTable(listings)
+		return nil
+	},
+}
+
+// modelListing is one row of `rubrduck models` output
+type modelListing struct {
+	ai.ModelInfo
+	// Provider is the configured provider name, which may differ from the family
+	Provider string `json:"provider"`
+	// Live is true when the provider's list endpoint reported the model
+	Live bool  `json:"live"`
+	Size int64 `json:"size,omitempty"`
+}
+
+// listProviderModels merges a provider's live model list with the registry
+// entries for its family. Providers that can't be created or listed fall
+// back to the registry, with a warning on stderr.
+func listProviderModels(ctx context.Context, cfg *config.Config, name string) []modelListing {
+	providerType := cfg.ProviderType(name)
+	family := ai.ProviderFamily(providerType)
+
+	var remote []ai.RemoteModel
+	p, err := ai.GetProvider(providerType, cfg.Providers[name].Settings())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+	} else if lister, ok := p.(ai.ModelLister); ok {
+		remote, err = lister.ListModels(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: failed to list models: %v\n", name, err)
+		}
+	}
+
+	var listings []modelListing
+	for _, m := range remote {
+		info := ai.ModelInfoFor(family, m.ID)
+		if !info.Known {
+			// Fill in what the list endpoint knows about unregistered models
+			if m.ContextWindow > 0 {
+				info.ContextWindow = m.ContextWindow
+			}
+			if m.MaxOutputTokens > 0 {
+				info.MaxOutputTokens = m.MaxOutputTokens
+			}
+		}
+		info.ID = m.ID
+		listings = append(listings, modelListing{ModelInfo: info, Provider: name, Live: true, Size: m.Size})
+	}
+
+	// Without a live list, show what the registry knows about the family
+	if len(remote) == 0 {
+		for _, info := range ai.ListRegisteredModels(family) {
+			listings = append(listings, modelListing{ModelInfo: info, Provider: name})
+		}
+	}
+
+	sort.Slice(listings, func(i, j int) bool { return listings[i].ID < listings[j].ID })
+	return listings
+}
+
+// printModelTable writes the listings as an aligned table
+func printModelTable(listings []modelListing) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROVIDER\tMODEL\tCONTEXT\tOUTPUT\tTOOLS\tVISION\tREASONING\tPRICE IN/OUT\tSOURCE")
+	for _, l := range listings {
+		source := "registry"
+		if l.Live {
+			source = "live"
+			if !l.Known {
+				source = "live (unregistered)"
+			}
+		}
+		price := "-"
+		if l.InputPrice > 0 || l.OutputPrice > 0 {
+			price = fmt.Sprintf("$%.2f/$%.2f", l.InputPrice, l.OutputPrice)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			l.Provider, l.ID, formatTokens(l.ContextWindow), formatTokens(l.MaxOutputTokens),
+			yesNo(l.SupportsTools), yesNo(l.SupportsVision), yesNo(l.SupportsReasoning), price, source)
+	}
+	w.Flush()
+}
+
+// formatTokens renders a token count compactly, e.g. 128k or 1M
+func formatTokens(n int) string {
+	switch {
+	case n >= 1000000:
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(n)/1000000), ".0") + "M"
+	case n >= 1000:
+		return fmt.Sprintf("%dk", n/1000)
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+var modelsPullCmd = &cobra.Command{
+	Use:   "pull <name>",
+	Short: "Download a model into a local Ollama server",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// The global --provider defaults to openai, so only honour it when set
+		name := "ollama"
+		if cmd.Flags().Changed("provider") {
+			name = provider
+		}
+
+		ollama, err := ollamaProvider(name)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+
+		status := ""
+		err = ollama.PullModel(ctx, args[0], func(progress providers.PullProgress) {
+			if progress.Total > 0 {
+				fmt.Printf("\r%s %3d%%", progress.Status, progress.Completed*100/progress.Total)
+				status = ""
+				return
+			}
+			if progress.Status != status {
+				fmt.Printf("\n%s", progress.Status)
+				status = progress.Status
+			}
+		})
+		fmt.Println()
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Pulled %s\n", args[0])
+		return nil
+	},
+}
+
+// ollamaProvider builds the named provider and checks that it is Ollama
+func ollamaProvider(name string) (*providers.OllamaProvider, error) {
+	providerCfg, err := config.LoadProvider(name)
+	if err != nil {
+		return nil, err
+	}
+
+	providerType := providerCfg.Type
+	if providerType == "" {
+		providerType = name
+	}
+
+	p, err := ai.GetProvider(providerType, providerCfg.Settings())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider %s: %w", name, err)
+	}
+
+	ollama, ok := p.(*providers.OllamaProvider)
+	if !ok {
+		return nil, fmt.Errorf("provider %s does not support pulling models; only Ollama does", name)
+	}
+	return ollama, nil
+}
+
+func init() {
+	modelsCmd.Flags().String("format", "table", "Output format: table or json")
+	modelsCmd.AddCommand(modelsPullCmd)
+	rootCmd.AddCommand(modelsCmd)
+}
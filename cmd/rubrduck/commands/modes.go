@@ -0,0 +1,38 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// modeAliases are the subcommands that run a one-shot prompt in one mode
+var modeAliases = []struct {
+	use   string
+	mode  string
+	short string
+}{
+	{"plan", "planning", "Plan a change from a prompt, without the TUI"},
+	{"build", "building", "Build from a prompt, without the TUI"},
+	{"debug", "debugging", "Debug from a prompt, without the TUI"},
+	{"enhance", "enhance", "Improve code from a prompt, without the TUI"},
+}
+
+func init() {
+	for _, alias := range modeAliases {
+		mode := alias.mode
+		cmd := &cobra.Command{
+			Use:   alias.use + " [prompt]",
+			Short: alias.short,
+			Long: fmt.Sprintf(`Run one agent turn in %s mode, as 'rubrduck --mode %s' does.
+Piped input is sent with the prompt as context.`, mode, mode),
+			Args: cobra.ArbitraryArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return runWithPrompt(cmd, mode, strings.Join(args, " "))
+			},
+		}
+		addHeadlessFlags(cmd)
+		rootCmd.AddCommand(cmd)
+	}
+}
@@ -17,6 +17,9 @@ var (
 	provider     string
 	model        string
 	approvalMode string
+	recordPath   string
+	replayPath   string
+	traceFlag    bool
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -26,12 +29,34 @@ var rootCmd = &cobra.Command{
 	Long: `RubrDuck is a CLI tool that brings AI-assisted coding to your terminal and IDE.
 	
 It provides an interactive TUI for chatting with AI models, executing code,
-and managing your development workflow with built-in safety features.`,
+and managing your development workflow with built-in safety features.
+
+Given a prompt, rubrduck runs one agent turn without the TUI and prints the
+reply, for scripts, CI jobs and git hooks:
+
+  rubrduck --mode debugging "why does TestParse fail?"
+  rubrduck --output json -a auto-edit "add a doc comment to Parse"
+  go test ./... 2>&1 | rubrduck debug "why is this failing?"
+  git diff | rubrduck "review this change"
+
+Piped input is sent with the prompt; the middle of input longer than
+--max-stdin bytes is cut. Without a prompt, piped input alone is only run
+with --stdin, so wrappers that leave stdin as a pipe still get the TUI.
+plan, build, debug and enhance run a prompt in that mode.
+
+Tool calls that would need approval are denied in suggest mode. Exit codes:
+0 success, 1 error, 2 invalid flags, 3 a tool call was denied, 4 spending
+limit reached.`,
+	// Any words that aren't a subcommand are the prompt
+	Args: cobra.ArbitraryArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		// If arguments are provided, treat them as a prompt
-		if len(args) > 0 {
-			prompt := strings.Join(args, " ")
-			return runWithPrompt(prompt)
+		// Run a prompt, or piped input when asked to, without the TUI. An
+		// open pipe alone isn't enough: scripts and wrappers often leave
+		// stdin as one.
+		fromStdin, _ := cmd.Flags().GetBool("stdin")
+		if len(args) > 0 || fromStdin {
+			mode, _ := cmd.Flags().GetString("mode")
+			return runWithPrompt(cmd, mode, strings.Join(args, " "))
 		}
 
 		// Otherwise, start interactive TUI
@@ -47,16 +72,32 @@ func Execute() error {
 func init() {
 	cobra.OnInitialize(initConfig)
 
+	// Unknown or malformed flags exit with the usage code, on every command
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		cmd.SilenceErrors = true // main prints the error
+		return &ExitError{Code: ExitUsage, Err: err}
+	})
+
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.rubrduck/config.yaml)")
 	rootCmd.PersistentFlags().StringVar(&provider, "provider", "openai", "AI provider to use")
 	rootCmd.PersistentFlags().StringVar(&model, "model", "", "AI model to use (provider-specific)")
 	rootCmd.PersistentFlags().StringVarP(&approvalMode, "approval-mode", "a", "suggest", "Approval mode: suggest, auto-edit, or full-auto")
+	rootCmd.PersistentFlags().StringVar(&recordPath, "record", "", "record provider requests and responses to a cassette file")
+	rootCmd.PersistentFlags().StringVar(&replayPath, "replay", "", "play back a recorded cassette instead of calling a provider")
+	rootCmd.PersistentFlags().BoolVar(&traceFlag, "trace", false, "write provider HTTP traffic to the trace log (see rubrduck trace)")
+
+	// One-shot run flags
+	rootCmd.Flags().String("mode", "building", "mode for a one-shot prompt: planning, building, debugging or enhance")
+	rootCmd.Flags().Bool("stdin", false, "run piped input as the prompt when no prompt is given")
+	addHeadlessFlags(rootCmd)
 
 	// Bind flags to viper
 	_ = viper.BindPFlag("provider", rootCmd.PersistentFlags().Lookup("provider"))
 	_ = viper.BindPFlag("model", rootCmd.PersistentFlags().Lookup("model"))
 	_ = viper.BindPFlag("agent.approval_mode", rootCmd.PersistentFlags().Lookup("approval-mode"))
+	_ = viper.BindPFlag("record", rootCmd.PersistentFlags().Lookup("record"))
+	_ = viper.BindPFlag("trace.enabled", rootCmd.PersistentFlags().Lookup("trace"))
 
 	// Add subcommands
 	rootCmd.AddCommand(serveCmd)
@@ -99,23 +140,12 @@ func initConfig() {
 	} else {
 		log.Debug().Msg("No config file found, using defaults and environment variables")
 	}
-}
 
-func runWithPrompt(prompt string) error {
-	log.Info().Str("prompt", prompt).Msg("Running with prompt")
-
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
+	// --replay swaps in the replay provider regardless of the configured one
+	if replayPath != "" {
+		viper.Set("provider", "replay")
+		viper.Set("providers.replay.cassette", replayPath)
 	}
-
-	// TODO: Implement agent execution with prompt
-	fmt.Printf("Running with prompt: %s\n", prompt)
-	fmt.Printf("Provider: %s, Model: %s, Approval Mode: %s\n",
-		cfg.Provider, cfg.Model, cfg.Agent.ApprovalMode)
-
-	return nil
 }
 
 func runInteractiveTUI() error {
@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// defaultStdinLimit is how much piped input is sent by default, about 16k
+// tokens
+const defaultStdinLimit = 64 * 1024
+
+// stdinIsPiped reports whether stdin is a pipe or file rather than a
+// terminal
+func stdinIsPiped() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeNamedPipe != 0 || info.Mode().IsRegular()
+}
+
+// readPipedStdin returns piped input cut to limit bytes, or "" when stdin
+// is a terminal
+func readPipedStdin(limit int) (string, error) {
+	if !stdinIsPiped() {
+		return "", nil
+	}
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("failed to read stdin: %w", err)
+	}
+	return truncateMiddle(string(data), limit), nil
+}
+
+// truncateMiddle keeps the head and tail of input, about limit bytes in
+// all, cut at line breaks. Both ends matter: a diff's file headers come
+// first and a test run's failures last.
+func truncateMiddle(input string, limit int) string {
+	if limit <= 0 || len(input) <= limit {
+		return input
+	}
+
+	head := input[:limit/2]
+	if i := strings.LastIndexByte(head, '\n'); i > 0 {
+		head = head[:i+1]
+	}
+	tail := input[len(input)-limit/2:]
+	if i := strings.IndexByte(tail, '\n'); i >= 0 && i < len(tail)-1 {
+		tail = tail[i+1:]
+	}
+
+	omitted := input[len(head) : len(input)-len(tail)]
+	return fmt.Sprintf("%s[... %d lines (%d bytes) omitted ...]\n%s",
+		head, strings.Count(omitted, "\n"), len(omitted), tail)
+}
+
+// withStdinContext appends piped input to the prompt as a delimited block
+func withStdinContext(prompt, input string) string {
+	if strings.TrimSpace(input) == "" {
+		return prompt
+	}
+	block := "<stdin>\n" + strings.TrimRight(input, "\n") + "\n</stdin>"
+	if strings.TrimSpace(prompt) == "" {
+		return block
+	}
+	return prompt + "\n\nInput piped to rubrduck:\n" + block
+}
@@ -0,0 +1,182 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hammie/rubrduck/internal/ai"
+	"github.com/hammie/rubrduck/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// traceCmd represents the trace command
+var traceCmd = &cobra.Command{
+	Use:   "trace",
+	Short: "Inspect the provider trace log",
+	Long: `Inspect the provider trace log, which records every provider HTTP request
+and response body (or streamed frames) with credentials redacted.
+
+Tracing is off by default. Turn it on with --trace or trace.enabled in the
+config file; traces go to ~/.rubrduck/trace.jsonl unless trace.file is set.`,
+}
+
+var traceListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List recent traced provider calls",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		limit, _ := cmd.Flags().GetInt("limit")
+
+		exchanges, err := ai.ReadTraces(tracePath(cmd))
+		if err != nil {
+			return err
+		}
+		if len(exchanges) == 0 {
+			fmt.Println("No traces recorded. Enable tracing with --trace or trace.enabled.")
+			return nil
+		}
+		if limit > 0 && len(exchanges) > limit {
+			exchanges = exchanges[len(exchanges)-limit:]
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tTIME\tPROVIDER\tREQUEST\tSTATUS\tDURATION")
+		for _, ex := range exchanges {
+			status := fmt.Sprintf("%d", ex.Status)
+			if ex.Error != "" {
+				status = "error"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s %s\t%s\t%dms\n",
+				shortTraceID(ex.ID), ex.Time.Local().Format("2006-01-02 15:04:05"), ex.Provider,
+				ex.Method, tracePathOnly(ex.URL), status, ex.DurationMS)
+		}
+		return w.Flush()
+	},
+}
+
+var traceShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Pretty-print one traced provider call",
+	Long: `Pretty-print one traced provider call: the request as sent, the response
+status and headers, and the response body or each streamed frame. The id
+may be shortened to any unique prefix, as shown by 'rubrduck trace list'.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		exchange, err := ai.FindTrace(tracePath(cmd), args[0])
+		if err != nil {
+			return err
+		}
+
+		if raw, _ := cmd.Flags().GetBool("json"); raw {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(exchange)
+		}
+		printTrace(exchange)
+		return nil
+	},
+}
+
+// tracePath returns the trace file from --file or the configuration
+func tracePath(cmd *cobra.Command) string {
+	if file, _ := cmd.Flags().GetString("file"); file != "" {
+		return file
+	}
+	cfg, err := config.LoadUnvalidated()
+	if err != nil {
+		return config.TraceConfig{}.Path()
+	}
+	return cfg.Trace.Path()
+}
+
+func shortTraceID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+// tracePathOnly shortens a URL to its path for the list view
+func tracePathOnly(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	return u.Path
+}
+
+func printTrace(ex *ai.TraceExchange) {
+	fmt.Printf("Trace:    %s\n", ex.ID)
+	fmt.Printf("Time:     %s\n", ex.Time.Local().Format("2006-01-02 15:04:05.000"))
+	fmt.Printf("Provider: %s\n", ex.Provider)
+	fmt.Printf("Request:  %s %s\n", ex.Method, ex.URL)
+	if ex.Status != 0 {
+		fmt.Printf("Status:   %d\n", ex.Status)
+	}
+	fmt.Printf("Duration: %dms\n", ex.DurationMS)
+	if ex.Error != "" {
+		fmt.Printf("Error:    %s\n", ex.Error)
+	}
+
+	printTraceSection("Request headers", formatTraceHeaders(ex.RequestHeaders))
+	printTraceSection("Request body", formatTraceBody(ex.RequestBody))
+	printTraceSection("Response headers", formatTraceHeaders(ex.ResponseHeaders))
+	if len(ex.Frames) > 0 {
+		printTraceSection(fmt.Sprintf("Response frames (%d)", len(ex.Frames)), strings.Join(ex.Frames, "\n"))
+	} else {
+		printTraceSection("Response body", formatTraceBody(ex.ResponseBody))
+	}
+}
+
+func printTraceSection(title, content string) {
+	if content == "" {
+		return
+	}
+	fmt.Printf("\n%s:\n%s\n", title, content)
+}
+
+func formatTraceHeaders(headers map[string][]string) string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %s: %s\n", name, strings.Join(headers[name], ", "))
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// formatTraceBody indents JSON bodies; non-JSON bodies are stored as a
+// JSON string and printed as-is
+func formatTraceBody(body json.RawMessage) string {
+	if len(body) == 0 {
+		return ""
+	}
+	var text string
+	if json.Unmarshal(body, &text) == nil {
+		return text
+	}
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, body, "", "  "); err != nil {
+		return string(body)
+	}
+	return pretty.String()
+}
+
+func init() {
+	traceCmd.PersistentFlags().String("file", "", "trace file to read (default from trace.file)")
+	traceListCmd.Flags().Int("limit", 20, "show at most this many of the latest calls (0 for all)")
+	traceShowCmd.Flags().Bool("json", false, "print the raw trace entry as JSON")
+	traceCmd.AddCommand(traceListCmd)
+	traceCmd.AddCommand(traceShowCmd)
+	rootCmd.AddCommand(traceCmd)
+}
@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hammie/rubrduck/internal/config"
+	"github.com/hammie/rubrduck/internal/usage"
+	"github.com/spf13/cobra"
+)
+
+// usageCmd represents the usage command
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Report token usage and estimated cost",
+	Long: `Report token usage and estimated cost by day, model and mode.
+
+Costs are estimated from the token counts providers report and the model
+registry's prices (override them under models: in the config file). The
+ledger is kept in ~/.rubrduck/usage unless usage.dir is set.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		days, _ := cmd.Flags().GetInt("days")
+		by, _ := cmd.Flags().GetString("by")
+		format, _ := cmd.Flags().GetString("format")
+		if format != "table" && format != "json" {
+			return fmt.Errorf("invalid format %q: use table or json", format)
+		}
+		if days < 1 {
+			return fmt.Errorf("--days must be at least 1")
+		}
+
+		groupings := map[string]func(usage.Record) string{
+			"day":   usage.ByDay,
+			"model": usage.ByModel,
+			"mode":  usage.ByMode,
+		}
+		keys := []string{"day", "model", "mode"}
+		if by != "" {
+			if _, ok := groupings[by]; !ok {
+				return fmt.Errorf("invalid grouping %q: use day, model or mode", by)
+			}
+			keys = []string{by}
+		}
+
+		cfg, err := config.LoadUnvalidated()
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+
+		since := time.Now().AddDate(0, 0, 1-days)
+		records, err := usage.Load(cfg.Usage.Path(), since)
+		if err != nil {
+			return err
+		}
+
+		report := make(map[string][]usage.Summary)
+		for _, key := range keys {
+			report[key] = usage.Summarize(records, groupings[key])
+		}
+
+		if format == "json" {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(report)
+		}
+
+		if len(records) == 0 {
+			fmt.Printf("No usage recorded in the last %d days.\n", days)
+			return nil
+		}
+		for i, key := range keys {
+			if i > 0 {
+				fmt.Println()
+			}
+			printUsageTable(key, report[key])
+		}
+		printUsageLimits(cfg.Usage, records)
+		return nil
+	},
+}
+
+// printUsageTable writes one grouping as an aligned table with a total row
+func printUsageTable(key string, summaries []usage.Summary) {
+	headers := map[string]string{"day": "DAY", "model": "MODEL", "mode": "MODE"}
+	total := usage.Summary{Key: "TOTAL"}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "%s\tREQUESTS\tPROMPT\tCACHED\tCOMPLETION\tCOST\n", headers[key])
+	row := func(s usage.Summary) {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t$%.4f\n", s.Key, s.Requests,
+			formatTokens(s.PromptTokens), formatTokens(s.CacheReadTokens), formatTokens(s.CompletionTokens), s.Cost)
+	}
+	for _, s := range summaries {
+		row(s)
+		total.Requests += s.Requests
+		total.PromptTokens += s.PromptTokens
+		total.CacheReadTokens += s.CacheReadTokens
+		total.CompletionTokens += s.CompletionTokens
+		total.Cost += s.Cost
+	}
+	if len(summaries) > 1 {
+		row(total)
+	}
+	w.Flush()
+}
+
+// printUsageLimits reports today's spending against the daily limits
+func printUsageLimits(cfg config.UsageConfig, records []usage.Record) {
+	if cfg.DailyWarn == 0 && cfg.DailyLimit == 0 {
+		return
+	}
+	today := usage.ByDay(usage.Record{Time: time.Now()})
+	var spent float64
+	for _, r := range records {
+		if usage.ByDay(r) == today {
+			spent += r.Cost
+		}
+	}
+
+	fmt.Printf("\nToday: $%.2f", spent)
+	if cfg.DailyWarn > 0 {
+		fmt.Printf(", warn at $%.2f", cfg.DailyWarn)
+	}
+	if cfg.DailyLimit > 0 {
+		fmt.Printf(", limit $%.2f", cfg.DailyLimit)
+	}
+	fmt.Println()
+}
+
+func init() {
+	usageCmd.Flags().Int("days", 7, "number of days to report, including today")
+	usageCmd.Flags().String("by", "", "report one grouping only: day, model or mode")
+	usageCmd.Flags().String("format", "table", "Output format: table or json")
+	rootCmd.AddCommand(usageCmd)
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
@@ -26,6 +28,14 @@ func main() {
 
 	// Execute the root command
 	if err := commands.Execute(); err != nil {
+		// Headless runs report their own exit code
+		var exit *commands.ExitError
+		if errors.As(err, &exit) {
+			if exit.Err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", exit.Err)
+			}
+			os.Exit(exit.Code)
+		}
 		log.Fatal().Err(err).Msg("Failed to execute command")
 		os.Exit(1)
 	}
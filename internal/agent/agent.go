@@ -2,6 +2,7 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -9,8 +10,11 @@ import (
 
 	"github.com/hammie/rubrduck/internal/agent/tools"
 	"github.com/hammie/rubrduck/internal/ai"
+	"github.com/hammie/rubrduck/internal/checkpoint"
 	"github.com/hammie/rubrduck/internal/config"
+	"github.com/hammie/rubrduck/internal/index"
 	"github.com/hammie/rubrduck/internal/sandbox"
+	"github.com/hammie/rubrduck/internal/usage"
 	"github.com/rs/zerolog/log"
 )
 
@@ -21,6 +25,22 @@ type Agent struct {
 	tools          map[string]Tool
 	history        []ai.Message
 	approvalSystem *ApprovalSystem
+
+	// model is the registry entry for the configured model
+	model ai.ModelInfo
+	// thinkingBudget is the reasoning budget requested from models that
+	// support it
+	thinkingBudget int
+	// spend records the cost of each response; nil when tracking is off
+	spend *usage.Tracker
+	// mode labels usage records with the TUI mode and selects its system
+	// prompt
+	mode string
+	// systemPrompts holds the system prompt for each mode; the "" entry is
+	// used by modes without their own
+	systemPrompts map[string]string
+	// checkpoints saves the files each turn changes; nil when off
+	checkpoints *checkpoint.Manager
 }
 
 // Tool represents an action the agent can perform
@@ -37,38 +57,102 @@ func New(cfg *config.Config) (*Agent, error) {
 	// Create AI provider
 	providerConfig := make(map[string]interface{})
 	if p, ok := cfg.Providers[cfg.Provider]; ok {
-		providerConfig["name"] = p.Name
-		providerConfig["base_url"] = p.BaseURL
-		providerConfig["api_key"] = p.APIKey
+		providerConfig = p.Settings()
 	}
 
-	provider, err := ai.GetProvider(cfg.Provider, providerConfig)
+	provider, err := ai.GetProvider(cfg.ProviderType(cfg.Provider), providerConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create AI provider: %w", err)
 	}
 
+	// Look up limits and capabilities for the configured model
+	RegisterConfiguredModels(cfg.Models)
+	family := ai.ProviderFamily(cfg.ProviderType(cfg.Provider))
+	modelInfo := ai.ModelInfoFor(family, cfg.Model)
+	if !modelInfo.Known {
+		log.Debug().Str("model", modelInfo.ID).Msg("Model not in registry, using conservative limits")
+	} else if modelInfo.Provider != family && ai.DefaultModel(family) != "" {
+		log.Warn().
+			Str("model", modelInfo.ID).
+			Str("model_provider", modelInfo.Provider).
+			Str("provider", cfg.Provider).
+			Msg("Model belongs to a different provider; requests will likely fail")
+	}
+	if !modelInfo.SupportsTools {
+		log.Warn().Str("model", modelInfo.ID).Msg("Model does not support tool calling; tools are disabled")
+	}
+	// The server only keeps num_ctx tokens, so trim history to fit it
+	if numCtx := cfg.Providers[cfg.Provider].NumCtx; numCtx > 0 && numCtx < modelInfo.ContextWindow {
+		log.Debug().Int("num_ctx", numCtx).Int("context_window", modelInfo.ContextWindow).Msg("Using the provider's smaller context size")
+		modelInfo.ContextWindow = numCtx
+	}
+
+	// Catch unknown models up front for providers that can check cheaply
+	if validator, ok := provider.(ai.ModelValidator); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		err := validator.ValidateModel(ctx, modelInfo.ID)
+		cancel()
+		if errors.Is(err, ai.ErrModelNotFound) {
+			return nil, err
+		}
+		if err != nil {
+			log.Warn().Err(err).Str("model", modelInfo.ID).Msg("Could not validate model")
+		}
+	}
+
+	// Record the session to a cassette for offline replay
+	if cfg.Record != "" {
+		recorder, err := ai.NewRecordingProvider(provider, cfg.Record)
+		if err != nil {
+			return nil, err
+		}
+		log.Info().Str("cassette", cfg.Record).Msg("Recording provider requests")
+		provider = recorder
+	}
+
+	var spend *usage.Tracker
+	if cfg.Usage.Track {
+		spend, err = usage.NewTracker(cfg.Usage.Path(), usage.Limits{
+			SessionWarn:  cfg.Usage.SessionWarn,
+			SessionLimit: cfg.Usage.SessionLimit,
+			DailyWarn:    cfg.Usage.DailyWarn,
+			DailyLimit:   cfg.Usage.DailyLimit,
+		})
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to start usage tracking; costs won't be recorded")
+		}
+	}
+
+	if cfg.Trace.Enabled {
+		if err := ai.EnableTracing(cfg.Trace.Path(), cfg.Trace.MaxSize, cfg.Trace.MaxBackups); err != nil {
+			log.Warn().Err(err).Msg("Failed to enable provider tracing")
+		} else {
+			log.Info().Str("file", cfg.Trace.Path()).Msg("Tracing provider requests")
+		}
+	}
+
+	var checkpoints *checkpoint.Manager
+	if cfg.Checkpoints.Enabled {
+		checkpoints, err = checkpoint.Open(cfg.Checkpoints.Path(), ".", cfg.Checkpoints.Keep)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to open checkpoints; changes can't be undone")
+		}
+	}
+
 	agent := &Agent{
 		config:   cfg,
 		provider: provider,
 		tools:    make(map[string]Tool),
 		history:  []ai.Message{},
+		model:    modelInfo,
+
+		thinkingBudget: cfg.Thinking.Budget,
+		spend:          spend,
+		checkpoints:    checkpoints,
 	}
 
 	// Initialize approval system
-	approvalConfig := &Config{
-		Mode:               cfg.Agent.ApprovalMode,
-		AutoApproveLowRisk: true, // Always auto-approve low-risk operations like git status, file reads
-		AutoApproveSafeCommands: append(cfg.Sandbox.AllowedCommands, []string{
-			"git status", "git log", "git diff", "git show", "git branch",
-			"ls", "pwd", "cat", "head", "tail", "grep", "find", "which",
-		}...),
-		AutoApproveSafePaths: cfg.Sandbox.AllowWritePaths,
-		BlockedCommands:      cfg.Sandbox.BlockedCommands,
-		BlockedPaths:         cfg.Sandbox.BlockPaths,
-		MaxBatchSize:         10,
-		Timeout:              time.Duration(cfg.Agent.Timeout) * time.Second,
-		Policies:             make(map[string]Policy),
-	}
+	approvalConfig := newApprovalConfig(cfg)
 
 	// Create approval callback that will be set by the TUI
 	agent.approvalSystem = NewApprovalSystem(approvalConfig, nil)
@@ -79,8 +163,34 @@ func New(cfg *config.Config) (*Agent, error) {
 	return agent, nil
 }
 
+// newApprovalConfig builds the approval policy from the sandbox settings
+func newApprovalConfig(cfg *config.Config) *Config {
+	safeCommands := append([]string{}, cfg.Sandbox.AllowedCommands...)
+	safeCommands = append(safeCommands,
+		"git status", "git log", "git diff", "git show", "git branch",
+		"ls", "pwd", "cat", "head", "tail", "grep", "find", "which",
+	)
+	return &Config{
+		Mode:                    cfg.Agent.ApprovalMode,
+		AutoApproveLowRisk:      true, // Always auto-approve low-risk operations like git status, file reads
+		AutoApproveSafeCommands: safeCommands,
+		AutoApproveSafePaths:    cfg.Sandbox.AllowWritePaths,
+		BlockedCommands:         cfg.Sandbox.BlockedCommands,
+		BlockedPaths:            cfg.Sandbox.BlockPaths,
+		MaxBatchSize:            10,
+		Timeout:                 time.Duration(cfg.Agent.Timeout) * time.Second,
+		Policies:                make(map[string]Policy),
+	}
+}
+
 // Chat processes a user message and returns the response
 func (a *Agent) Chat(ctx context.Context, message string) (string, error) {
+	if err := a.checkSpending(); err != nil {
+		return "", err
+	}
+	a.beginTurn(message)
+	defer a.endTurn()
+
 	// Add user message to history
 	a.history = append(a.history, ai.Message{
 		Role:    "user",
@@ -89,9 +199,12 @@ func (a *Agent) Chat(ctx context.Context, message string) (string, error) {
 
 	// Prepare chat request
 	req := &ai.ChatRequest{
-		Model:    a.config.Model,
-		Messages: a.history,
-		Tools:    a.getToolDefinitions(),
+		Model:           a.model.ID,
+		Messages:        a.contextMessages(),
+		Tools:           a.requestTools(),
+		CacheTools:      a.config.Tokens.PromptCaching,
+		MaxTokens:       a.completionTokens(),
+		ReasoningBudget: a.reasoningBudget(),
 	}
 
 	// Send request to AI provider
@@ -99,6 +212,7 @@ func (a *Agent) Chat(ctx context.Context, message string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("failed to get AI response: %w", err)
 	}
+	a.logUsage(resp.Usage)
 
 	if len(resp.Choices) == 0 {
 		return "", fmt.Errorf("no response from AI")
@@ -117,11 +231,12 @@ func (a *Agent) Chat(ctx context.Context, message string) (string, error) {
 		a.history = append(a.history, toolResults...)
 
 		// Get final response after tool execution
-		req.Messages = a.history
+		req.Messages = a.contextMessages()
 		resp, err = a.provider.Chat(ctx, req)
 		if err != nil {
 			return "", fmt.Errorf("failed to get AI response after tool execution: %w", err)
 		}
+		a.logUsage(resp.Usage)
 
 		if len(resp.Choices) > 0 {
 			finalMsg := resp.Choices[0].Message
@@ -135,6 +250,12 @@ func (a *Agent) Chat(ctx context.Context, message string) (string, error) {
 
 // StreamChat processes a user message and streams the response
 func (a *Agent) StreamChat(ctx context.Context, message string, callback func(chunk string)) error {
+	if err := a.checkSpending(); err != nil {
+		return err
+	}
+	a.beginTurn(message)
+	defer a.endTurn()
+
 	// Add user message to history
 	a.history = append(a.history, ai.Message{
 		Role:    "user",
@@ -143,20 +264,24 @@ func (a *Agent) StreamChat(ctx context.Context, message string, callback func(ch
 
 	// Prepare chat request
 	req := &ai.ChatRequest{
-		Model:    a.config.Model,
-		Messages: a.history,
-		Tools:    a.getToolDefinitions(),
-		Stream:   true,
+		Model:           a.model.ID,
+		Messages:        a.contextMessages(),
+		Tools:           a.requestTools(),
+		CacheTools:      a.config.Tokens.PromptCaching,
+		MaxTokens:       a.completionTokens(),
+		ReasoningBudget: a.reasoningBudget(),
+		Stream:          true,
 	}
 
 	// Send request to AI provider
-	stream, err := a.provider.StreamChat(ctx, req)
+	stream, err := a.openStream(ctx, req)
 	if err != nil {
 		return fmt.Errorf("failed to start streaming: %w", err)
 	}
 	defer stream.Close()
 
 	var fullResponse strings.Builder
+	var streamUsage ai.Usage
 	for {
 		chunk, err := stream.Recv()
 		if err != nil {
@@ -166,6 +291,9 @@ func (a *Agent) StreamChat(ctx context.Context, message string, callback func(ch
 			return fmt.Errorf("streaming error: %w", err)
 		}
 
+		if chunk.Usage != nil {
+			streamUsage = *chunk.Usage
+		}
 		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
 			content := chunk.Choices[0].Delta.Content
 			fullResponse.WriteString(content)
@@ -173,6 +301,8 @@ func (a *Agent) StreamChat(ctx context.Context, message string, callback func(ch
 		}
 	}
 
+	a.logUsage(streamUsage)
+
 	// Add complete response to history
 	a.history = append(a.history, ai.Message{
 		Role:    "assistant",
@@ -184,7 +314,12 @@ func (a *Agent) StreamChat(ctx context.Context, message string, callback func(ch
 
 // StreamEvents processes a user message and emits streaming events.
 func (a *Agent) StreamEvents(ctx context.Context, message string) (<-chan StreamEvent, error) {
+	if err := a.checkSpending(); err != nil {
+		return nil, err
+	}
+
 	events := make(chan StreamEvent)
+	a.beginTurn(message)
 
 	a.history = append(a.history, ai.Message{
 		Role:    "user",
@@ -192,27 +327,31 @@ func (a *Agent) StreamEvents(ctx context.Context, message string) (<-chan Stream
 	})
 
 	req := &ai.ChatRequest{
-		Model:    a.config.Model,
-		Messages: a.history,
-		Tools:    a.getToolDefinitions(),
-		Stream:   true,
+		Model:           a.model.ID,
+		Messages:        a.contextMessages(),
+		Tools:           a.requestTools(),
+		CacheTools:      a.config.Tokens.PromptCaching,
+		MaxTokens:       a.completionTokens(),
+		ReasoningBudget: a.reasoningBudget(),
+		Stream:          true,
 	}
 
 	log.Debug().
 		Str("provider", a.config.Provider).
-		Str("model", a.config.Model).
-		Int("message_count", len(a.history)).
+		Str("model", a.model.ID).
+		Int("message_count", len(req.Messages)).
 		Int("tool_count", len(a.tools)).
 		Str("user_message", message).
 		Msg("Starting streaming chat")
 
-	stream, err := a.provider.StreamChat(ctx, req)
+	stream, err := a.openStream(ctx, req)
 	if err != nil {
 		log.Error().
 			Err(err).
 			Str("provider", a.config.Provider).
-			Str("model", a.config.Model).
+			Str("model", a.model.ID).
 			Msg("Failed to start streaming chat")
+		a.endTurn()
 		return nil, fmt.Errorf("failed to start streaming: %w", err)
 	}
 
@@ -220,8 +359,16 @@ func (a *Agent) StreamEvents(ctx context.Context, message string) (<-chan Stream
 		defer stream.Close()
 		defer close(events)
 
+		// The checkpoint is stored before the turn is reported done, so it
+		// can be undone as soon as the caller sees the reply
+		done := func(ev StreamEvent) {
+			a.endTurn()
+			events <- ev
+		}
+
 		assistant := ai.Message{Role: "assistant"}
 		var pendingToolCalls []ai.ToolCall
+		var streamUsage ai.Usage
 		chunkCount := 0
 
 		log.Debug().Msg("Starting to process streaming chunks")
@@ -242,7 +389,7 @@ func (a *Agent) StreamEvents(ctx context.Context, message string) (<-chan Stream
 					Int("chunk_number", chunkCount).
 					Str("provider", a.config.Provider).
 					Msg("Streaming error occurred")
-				events <- StreamEvent{Type: EventDone, Err: err}
+				done(StreamEvent{Type: EventDone, Err: err})
 				return
 			}
 
@@ -251,6 +398,11 @@ func (a *Agent) StreamEvents(ctx context.Context, message string) (<-chan Stream
 				Int("choices_count", len(chunk.Choices)).
 				Msg("Processing chunk")
 
+			// Providers report usage on the chunk that carries it, usually the last
+			if chunk.Usage != nil {
+				streamUsage = *chunk.Usage
+			}
+
 			if len(chunk.Choices) > 0 {
 				delta := chunk.Choices[0].Delta
 
@@ -264,6 +416,16 @@ func (a *Agent) StreamEvents(ctx context.Context, message string) (<-chan Stream
 					assistant.Content += delta.Content
 				}
 
+				// Reasoning is shown apart from the answer and kept with the
+				// message for providers that need it sent back
+				if delta.Reasoning != "" {
+					events <- StreamEvent{Type: EventReasoning, Token: delta.Reasoning}
+					assistant.Reasoning += delta.Reasoning
+				}
+				if delta.ReasoningSignature != "" {
+					assistant.ReasoningSignature += delta.ReasoningSignature
+				}
+
 				// Handle tool call deltas - accumulate properly
 				if len(delta.ToolCalls) > 0 {
 					log.Debug().
@@ -307,149 +469,28 @@ func (a *Agent) StreamEvents(ctx context.Context, message string) (<-chan Stream
 
 		// Process any complete tool calls
 		if len(pendingToolCalls) > 0 {
-			// Emit tool request events for approval
-			for i, toolCall := range pendingToolCalls {
-				log.Debug().
-					Int("tool_call_index", i).
-					Str("tool_call_id", toolCall.ID).
-					Str("tool_name", toolCall.Function.Name).
-					Str("tool_args", toolCall.Function.Arguments).
-					Msg("Processing tool call")
-
-				// Validate tool call before requesting approval
-				if toolCall.Function.Name == "" || toolCall.Function.Arguments == "" {
-					log.Warn().
-						Str("tool_call_id", toolCall.ID).
-						Str("function_name", toolCall.Function.Name).
-						Str("arguments", toolCall.Function.Arguments).
-						Msg("Skipping incomplete tool call")
-
-					events <- StreamEvent{
-						Type:   EventToolResult,
-						ToolID: toolCall.ID,
-						Result: fmt.Sprintf("Error: Incomplete tool call - name='%s', args='%s'", toolCall.Function.Name, toolCall.Function.Arguments),
-					}
-					continue
-				}
-
-				log.Debug().
-					Str("tool_call_id", toolCall.ID).
-					Str("tool_name", toolCall.Function.Name).
-					Msg("Requesting approval for tool call")
-
-				// Request approval for the tool execution
-				approvalResult, err := a.approvalSystem.RequestApproval(ctx, toolCall.Function.Name, toolCall.Function.Arguments, toolCall)
-				if err != nil {
-					log.Error().
-						Err(err).
-						Str("tool_call_id", toolCall.ID).
-						Str("tool_name", toolCall.Function.Name).
-						Msg("Approval request failed")
-
-					events <- StreamEvent{
-						Type:     EventToolResult,
-						ToolID:   toolCall.ID,
-						ToolName: toolCall.Function.Name,
-						Result:   fmt.Sprintf("Error: Approval failed for %s: %v", toolCall.Function.Name, err),
-					}
-					continue
-				}
-
-				if !approvalResult.Approved {
-					log.Info().
-						Str("tool_call_id", toolCall.ID).
-						Str("tool_name", toolCall.Function.Name).
-						Str("denial_reason", approvalResult.Reason).
-						Msg("Tool call denied")
-
-					events <- StreamEvent{
-						Type:     EventToolResult,
-						ToolID:   toolCall.ID,
-						ToolName: toolCall.Function.Name,
-						Result:   fmt.Sprintf("Operation denied: %s", approvalResult.Reason),
-					}
-					continue
-				}
-
-				log.Info().
-					Str("tool_call_id", toolCall.ID).
-					Str("tool_name", toolCall.Function.Name).
-					Str("approval_reason", approvalResult.Reason).
-					Msg("Tool call approved, executing")
-
-				// Execute the approved tool
-				tool, ok := a.tools[toolCall.Function.Name]
-				if !ok {
-					log.Error().
-						Str("tool_call_id", toolCall.ID).
-						Str("tool_name", toolCall.Function.Name).
-						Strs("available_tools", func() []string {
-							var names []string
-							for name := range a.tools {
-								names = append(names, name)
-							}
-							return names
-						}()).
-						Msg("Unknown tool requested")
-
-					events <- StreamEvent{
-						Type:     EventToolResult,
-						ToolID:   toolCall.ID,
-						ToolName: toolCall.Function.Name,
-						Result:   fmt.Sprintf("Error: Unknown tool '%s'", toolCall.Function.Name),
-					}
-					continue
+			a.runToolCalls(ctx, pendingToolCalls, func(outcome toolOutcome) {
+				events <- StreamEvent{
+					Type:     EventToolResult,
+					ToolID:   outcome.call.ID,
+					ToolName: outcome.call.Function.Name,
+					Result:   outcome.result,
+					Denied:   outcome.denied,
 				}
-
-				startTime := time.Now()
-				result, err := tool.Execute(ctx, toolCall.Function.Arguments)
-				duration := time.Since(startTime)
-
-				if err != nil {
-					log.Error().
-						Err(err).
-						Str("tool_call_id", toolCall.ID).
-						Str("tool_name", toolCall.Function.Name).
-						Dur("execution_duration", duration).
-						Msg("Tool execution failed")
-
-					events <- StreamEvent{
-						Type:     EventToolResult,
-						ToolID:   toolCall.ID,
-						ToolName: toolCall.Function.Name,
-						Result:   fmt.Sprintf("Error executing %s: %v", toolCall.Function.Name, err),
-					}
-				} else {
-					log.Info().
-						Str("tool_call_id", toolCall.ID).
-						Str("tool_name", toolCall.Function.Name).
-						Dur("execution_duration", duration).
-						Int("result_length", len(result)).
-						Msg("Tool execution completed successfully")
-
-					events <- StreamEvent{
-						Type:     EventToolResult,
-						ToolID:   toolCall.ID,
-						ToolName: toolCall.Function.Name,
-						Result:   result,
-					}
+				if outcome.call.ID != "" {
+					a.history = append(a.history, outcome.message())
 				}
-
-				// Add tool result to history
-				a.history = append(a.history, ai.Message{
-					Role:       "tool",
-					Content:    result,
-					ToolCallID: toolCall.ID,
-				})
-			}
+			})
 
 			log.Debug().Msg("Getting final response after tool execution")
 
 			// Get final response after tool execution
 			// Don't include tools since we've already executed them
 			req := &ai.ChatRequest{
-				Model:    a.config.Model,
-				Messages: a.history,
+				Model:           a.model.ID,
+				Messages:        a.contextMessages(),
+				MaxTokens:       a.completionTokens(),
+				ReasoningBudget: a.reasoningBudget(),
 				// Tools:    a.getToolDefinitions(), // Remove tools to avoid confusion
 			}
 			resp, err := a.provider.Chat(ctx, req)
@@ -458,7 +499,7 @@ func (a *Agent) StreamEvents(ctx context.Context, message string) (<-chan Stream
 					Err(err).
 					Str("provider", a.config.Provider).
 					Msg("Failed to get final response after tool execution")
-				events <- StreamEvent{Type: EventDone, Err: err}
+				done(StreamEvent{Type: EventDone, Err: err})
 				return
 			}
 			if len(resp.Choices) > 0 {
@@ -469,19 +510,41 @@ func (a *Agent) StreamEvents(ctx context.Context, message string) (<-chan Stream
 					Int("final_response_length", len(final.Content)).
 					Msg("Final response received")
 
+				if final.Reasoning != "" {
+					events <- StreamEvent{Type: EventReasoning, Token: final.Reasoning}
+				}
 				events <- StreamEvent{Type: EventTokenChunk, Token: final.Content}
-				events <- StreamEvent{Type: EventDone, Usage: resp.Usage}
+				a.logUsage(resp.Usage)
+				done(StreamEvent{Type: EventDone, Usage: resp.Usage})
 				return
 			}
 		}
 
 		log.Info().Msg("Stream processing completed")
-		events <- StreamEvent{Type: EventDone}
+		a.logUsage(streamUsage)
+		done(StreamEvent{Type: EventDone, Usage: streamUsage})
 	}()
 
 	return events, nil
 }
 
+// openStream starts a streaming request, falling back to a regular request
+// replayed as a stream when the model can't call tools while streaming
+func (a *Agent) openStream(ctx context.Context, req *ai.ChatRequest) (ai.ChatStream, error) {
+	if len(req.Tools) == 0 || a.model.SupportsStreamingTools {
+		return a.provider.StreamChat(ctx, req)
+	}
+
+	log.Debug().Str("model", a.model.ID).Msg("Model can't stream tool calls, using a regular request")
+	nonStreaming := *req
+	nonStreaming.Stream = false
+	resp, err := a.provider.Chat(ctx, &nonStreaming)
+	if err != nil {
+		return nil, err
+	}
+	return ai.NewResponseStream(resp), nil
+}
+
 // mergeToolCallDeltas properly accumulates tool call deltas from streaming
 func (a *Agent) mergeToolCallDeltas(existing []ai.ToolCall, newDeltas []ai.ToolCall) []ai.ToolCall {
 	// OpenAI sends tool call deltas with an index to indicate which tool call they belong to
@@ -634,6 +697,9 @@ func (a *Agent) registerDefaultTools() {
 
 	// Register file operations tool
 	fileTool := tools.NewFileTool(basePath)
+	if a.checkpoints != nil {
+		fileTool.SetSnapshotter(a.checkpoints)
+	}
 	a.RegisterTool("file_operations", fileTool)
 
 	// Register shell execution tool
@@ -652,6 +718,9 @@ func (a *Agent) registerDefaultTools() {
 		BlockedEnvVars:  a.config.Sandbox.BlockedEnvVars,
 	}
 	shellTool := tools.NewShellTool(basePath, shellPolicy)
+	if a.checkpoints != nil {
+		shellTool.SetTracker(a.checkpoints)
+	}
 	a.RegisterTool("shell_execute", shellTool)
 
 	// Register git operations tool
@@ -659,6 +728,59 @@ func (a *Agent) registerDefaultTools() {
 	a.RegisterTool("git_operations", gitTool)
 
 	log.Debug().Msg("Registered default tools: file_operations, shell_execute, git_operations")
+
+	// Register semantic code search when a provider can embed
+	if embedder, model := a.embedder(); embedder != nil {
+		a.RegisterTool("code_search", tools.NewCodeSearchTool(basePath, embedder, index.Options{
+			Model:       model,
+			MaxFileSize: a.config.Index.MaxFileSize,
+		}))
+		log.Debug().Str("embedding_model", model).Msg("Registered code_search tool")
+	}
+}
+
+// embedder returns the provider and model used to embed code for
+// code_search, or nil when indexing is disabled or no provider can embed
+func (a *Agent) embedder() (ai.Embedder, string) {
+	cfg := a.config.Index
+	if !cfg.Enabled {
+		return nil, ""
+	}
+
+	name, provider := a.config.Provider, a.provider
+	if cfg.Provider != "" && cfg.Provider != name {
+		providerCfg, ok := a.config.Providers[cfg.Provider]
+		if !ok {
+			log.Warn().Str("provider", cfg.Provider).Msg("Index provider not configured; code_search is disabled")
+			return nil, ""
+		}
+		p, err := ai.GetProvider(a.config.ProviderType(cfg.Provider), providerCfg.Settings())
+		if err != nil {
+			log.Warn().Err(err).Str("provider", cfg.Provider).Msg("Failed to create index provider; code_search is disabled")
+			return nil, ""
+		}
+		name, provider = cfg.Provider, p
+	}
+
+	// Look through wrappers such as the cassette recorder
+	if wrapper, ok := provider.(interface{ Unwrap() ai.Provider }); ok {
+		provider = wrapper.Unwrap()
+	}
+	embedder, ok := provider.(ai.Embedder)
+	if !ok {
+		log.Debug().Str("provider", name).Msg("Provider can't embed; code_search is disabled")
+		return nil, ""
+	}
+
+	model := cfg.EmbeddingModel
+	if model == "" {
+		model = ai.DefaultEmbeddingModel(ai.ProviderFamily(a.config.ProviderType(name)))
+	}
+	if model == "" {
+		log.Debug().Str("provider", name).Msg("No embedding model configured; code_search is disabled")
+		return nil, ""
+	}
+	return embedder, model
 }
 
 // getToolDefinitions returns tool definitions for the AI
@@ -680,57 +802,29 @@ func (a *Agent) SetApprovalCallback(callback ApprovalCallback) {
 // executeToolCalls executes the requested tool calls
 func (a *Agent) executeToolCalls(ctx context.Context, toolCalls []ai.ToolCall) []ai.Message {
 	var results []ai.Message
+	a.runToolCalls(ctx, toolCalls, func(outcome toolOutcome) {
+		results = append(results, outcome.message())
+	})
+	return results
+}
 
-	// First pass: collect all approval requests
-	for _, call := range toolCalls {
-		tool, ok := a.tools[call.Function.Name]
-		if !ok {
-			results = append(results, ai.Message{
-				Role:       "tool",
-				Content:    fmt.Sprintf("Error: Unknown tool '%s'", call.Function.Name),
-				ToolCallID: call.ID,
-			})
-			continue
-		}
-
-		// Request approval for the tool execution
-		approvalResult, err := a.approvalSystem.RequestApproval(ctx, call.Function.Name, call.Function.Arguments, call)
-		if err != nil {
-			results = append(results, ai.Message{
-				Role:       "tool",
-				Content:    fmt.Sprintf("Error: Approval failed for %s: %v", call.Function.Name, err),
-				ToolCallID: call.ID,
-			})
-			continue
-		}
-
-		if !approvalResult.Approved {
-			results = append(results, ai.Message{
-				Role:       "tool",
-				Content:    fmt.Sprintf("Operation denied: %s", approvalResult.Reason),
-				ToolCallID: call.ID,
-			})
-			continue
-		}
-
-		// Execute the approved tool
-		result, err := tool.Execute(ctx, call.Function.Arguments)
-		if err != nil {
-			results = append(results, ai.Message{
-				Role:       "tool",
-				Content:    fmt.Sprintf("Error executing %s: %v", call.Function.Name, err),
-				ToolCallID: call.ID,
-			})
-		} else {
-			results = append(results, ai.Message{
-				Role:       "tool",
-				Content:    result,
-				ToolCallID: call.ID,
-			})
-		}
+// SetSystemPrompt sets the system prompt sent with requests made in mode.
+// An empty mode sets the default for modes without their own prompt. The
+// prompt is sent in the provider's system field and never stored in the
+// history.
+func (a *Agent) SetSystemPrompt(mode, prompt string) {
+	if a.systemPrompts == nil {
+		a.systemPrompts = make(map[string]string)
 	}
+	a.systemPrompts[mode] = prompt
+}
 
-	return results
+// SystemPrompt returns the system prompt for the current mode
+func (a *Agent) SystemPrompt() string {
+	if prompt, ok := a.systemPrompts[a.mode]; ok {
+		return prompt
+	}
+	return a.systemPrompts[""]
 }
 
 // ClearHistory clears the conversation history
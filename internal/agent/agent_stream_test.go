@@ -31,7 +31,7 @@ type mockProvider struct{}
 func (m *mockProvider) Chat(ctx context.Context, req *ai.ChatRequest) (*ai.ChatResponse, error) {
 	return &ai.ChatResponse{
 		Choices: []ai.Choice{{Message: ai.Message{Role: "assistant", Content: "final"}}},
-		Usage:   ai.Usage{TotalTokens: 1},
+		Usage:   ai.Usage{CompletionTokens: 1, TotalTokens: 1},
 	}, nil
 }
 
@@ -231,3 +231,68 @@ func TestMergeToolCallDeltas(t *testing.T) {
 	require.Len(t, result2, 1)
 	require.Equal(t, `{"type":"list","path":"."}`, result2[0].Function.Arguments)
 }
+
+// reasoningProvider streams thinking before its answer
+type reasoningProvider struct {
+	mockProvider
+	last *ai.ChatRequest
+}
+
+func (r *reasoningProvider) StreamChat(ctx context.Context, req *ai.ChatRequest) (ai.ChatStream, error) {
+	r.last = req
+	return &mockStream{chunks: []*ai.ChatStreamChunk{
+		{Choices: []ai.ChatStreamChoice{{Delta: ai.ChatStreamDelta{Reasoning: "Let me "}}}},
+		{Choices: []ai.ChatStreamChoice{{Delta: ai.ChatStreamDelta{Reasoning: "think", ReasoningSignature: "sig"}}}},
+		{Choices: []ai.ChatStreamChoice{{Delta: ai.ChatStreamDelta{Content: "Answer"}}}},
+	}}, nil
+}
+
+func TestAgentStreamEventsReasoning(t *testing.T) {
+	provider := &reasoningProvider{}
+	ai.RegisterProvider("mock-reasoning", func(cfg map[string]interface{}) (ai.Provider, error) { return provider, nil })
+
+	supportsReasoning := true
+	ag, err := New(&config.Config{
+		Provider:  "mock-reasoning",
+		Model:     "thinker",
+		Providers: map[string]config.Provider{"mock-reasoning": {Name: "mock-reasoning"}},
+		Models:    []config.ModelConfig{{ID: "thinker", Provider: "mock-reasoning", SupportsReasoning: &supportsReasoning}},
+		Agent:     config.AgentConfig{ApprovalMode: "suggest"},
+		Thinking:  config.ThinkingConfig{Budget: 2048},
+	})
+	require.NoError(t, err)
+
+	ag.SetThinkingBudget(4096)
+	ch, err := ag.StreamEvents(context.Background(), "why?")
+	require.NoError(t, err)
+
+	var reasoning, answer string
+	for ev := range ch {
+		switch ev.Type {
+		case EventReasoning:
+			reasoning += ev.Token
+		case EventTokenChunk:
+			answer += ev.Token
+		}
+	}
+
+	require.Equal(t, 4096, provider.last.ReasoningBudget)
+	require.Equal(t, "Let me think", reasoning)
+	require.Equal(t, "Answer", answer)
+
+	// The reasoning stays with the reply for providers that need it back
+	history := ag.GetHistory()
+	last := history[len(history)-1]
+	require.Equal(t, "Answer", last.Content)
+	require.Equal(t, "Let me think", last.Reasoning)
+	require.Equal(t, "sig", last.ReasoningSignature)
+}
+
+func TestAgentReasoningBudgetNeedsSupport(t *testing.T) {
+	ag, recorder := newRecordingAgent(t, "mock-no-reasoning", nil, "gpt-4")
+	ag.SetThinkingBudget(4096)
+
+	_, err := ag.Chat(context.Background(), "hi")
+	require.NoError(t, err)
+	require.Zero(t, recorder.last.ReasoningBudget)
+}
@@ -4,9 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/hammie/rubrduck/internal/agent/tools"
 	"github.com/hammie/rubrduck/internal/ai"
 	"github.com/rs/zerolog/log"
 )
@@ -228,17 +232,50 @@ func (a *ApprovalSystem) analyzeOperation(tool, args string) (opType string, ris
 		return a.analyzeShellOperation(args)
 	case "git_operations":
 		return a.analyzeGitOperation(args)
+	case "code_search":
+		return a.analyzeCodeSearch(args)
 	default:
 		return "unknown", RiskHigh, "Unknown operation type", nil
 	}
 }
 
+// IsReadOnly reports whether a tool call is low risk and only reads, so it
+// can safely run alongside other such calls
+func (a *ApprovalSystem) IsReadOnly(tool, args string) bool {
+	opType, risk, _, err := a.analyzeOperation(tool, args)
+	if err != nil || risk != RiskLow {
+		return false
+	}
+
+	switch opType {
+	case "file_read", "file_list", "file_search", "code_search":
+		return true
+	case "git_operation":
+		var params struct {
+			Operation string `json:"operation"`
+		}
+		json.Unmarshal([]byte(args), &params)
+		switch params.Operation {
+		case "status", "log", "diff", "show":
+			return true
+		}
+	}
+	return false
+}
+
 // analyzeFileOperation analyzes file operations
 func (a *ApprovalSystem) analyzeFileOperation(args string) (opType string, risk RiskLevel, preview string, err error) {
 	var params struct {
-		Type    string `json:"type"`
-		Path    string `json:"path"`
-		Content string `json:"content"`
+		Type    string       `json:"type"`
+		Path    string       `json:"path"`
+		Content string       `json:"content"`
+		NewText string       `json:"new_text"`
+		Edits   []tools.Edit `json:"edits"`
+		Diff    string       `json:"diff"`
+		// Destination, Overwrite and Recursive are for move, copy and delete
+		Destination string `json:"destination"`
+		Overwrite   bool   `json:"overwrite"`
+		Recursive   bool   `json:"recursive"`
 	}
 
 	if err := json.Unmarshal([]byte(args), &params); err != nil {
@@ -253,14 +290,40 @@ func (a *ApprovalSystem) analyzeFileOperation(args string) (opType string, risk
 	switch params.Type {
 	case "read":
 		return "file_read", RiskLow, fmt.Sprintf("Reading file: %s", params.Path), nil
+	case "stat":
+		return "file_read", RiskLow, fmt.Sprintf("Checking file: %s", params.Path), nil
 	case "write":
 		risk = a.assessFileWriteRisk(params.Path, params.Content)
 		preview = a.generateFileWritePreview(params.Path, params.Content)
 		return "file_write", risk, preview, nil
+	case "edit", "patch", "insert_at_line":
+		// Judge the text being added, as for a write
+		added := params.Content + params.NewText + params.Diff
+		for _, e := range params.Edits {
+			added += e.NewText
+		}
+		risk = a.assessFileWriteRisk(params.Path, added)
+		preview = a.generateFileChangePreview(params.Path, args)
+		return "file_write", risk, preview, nil
 	case "list":
 		return "file_list", RiskLow, fmt.Sprintf("Listing directory: %s", params.Path), nil
 	case "search":
 		return "file_search", RiskLow, fmt.Sprintf("Searching in: %s", params.Path), nil
+	case "grep":
+		return "file_search", RiskLow, fmt.Sprintf("Searching contents of: %s", params.Path), nil
+	case "mkdir":
+		return "file_write", a.assessFileWriteRisk(params.Path, ""), fmt.Sprintf("Creating directory: %s", params.Path), nil
+	case "copy", "move":
+		risk = a.assessTransferRisk(params.Type, params.Path, params.Destination, params.Overwrite)
+		preview = fmt.Sprintf("%s %s to %s", strings.ToUpper(params.Type[:1])+params.Type[1:], params.Path, params.Destination)
+		if params.Overwrite {
+			preview += " (replacing it if it exists)"
+		}
+		return "file_write", risk, preview, nil
+	case "delete":
+		risk = a.assessDeleteRisk(params.Path)
+		preview = a.generateDeletePreview(params.Path, params.Recursive)
+		return "file_delete", risk, preview, nil
 	default:
 		return "file_unknown", RiskMedium, "Unknown file operation", nil
 	}
@@ -302,6 +365,20 @@ func (a *ApprovalSystem) analyzeGitOperation(args string) (opType string, risk R
 	return "git_operation", risk, preview, nil
 }
 
+// analyzeCodeSearch analyzes semantic code searches, which only read the
+// project and its index
+func (a *ApprovalSystem) analyzeCodeSearch(args string) (opType string, risk RiskLevel, preview string, err error) {
+	var params struct {
+		Query string `json:"query"`
+	}
+
+	if err := json.Unmarshal([]byte(args), &params); err != nil {
+		return "code_search_invalid", RiskHigh, fmt.Sprintf("Invalid code search arguments: %s", args), fmt.Errorf("failed to parse code search args: %w", err)
+	}
+
+	return "code_search", RiskLow, fmt.Sprintf("Searching code for: %s", params.Query), nil
+}
+
 // assessFileWriteRisk assesses the risk of a file write operation
 func (a *ApprovalSystem) assessFileWriteRisk(path, content string) RiskLevel {
 	// Check for dangerous file extensions
@@ -340,6 +417,52 @@ func (a *ApprovalSystem) assessFileWriteRisk(path, content string) RiskLevel {
 	return RiskLow
 }
 
+// assessTransferRisk assesses a copy or move. Moving takes the file away
+// from where other code may expect it, and replacing a file loses its
+// content, so both raise the risk; so does the kind of file created.
+func (a *ApprovalSystem) assessTransferRisk(op, src, dst string, overwrite bool) RiskLevel {
+	risk := RiskLow
+	if op == "move" {
+		risk = RiskMedium
+	}
+	if overwrite {
+		risk = RiskHigh
+	}
+	for _, path := range []string{src, dst} {
+		if r := a.assessFileWriteRisk(path, ""); riskRank(r) > riskRank(risk) {
+			risk = r
+		}
+	}
+	return risk
+}
+
+// assessDeleteRisk assesses a delete, which is always high risk and
+// critical for the project root or system paths
+func (a *ApprovalSystem) assessDeleteRisk(path string) RiskLevel {
+	clean := filepath.Clean(path)
+	if path == "" || clean == "." || clean == "/" {
+		return RiskCritical
+	}
+	if a.assessFileWriteRisk(clean, "") == RiskCritical {
+		return RiskCritical
+	}
+	return RiskHigh
+}
+
+// riskRank orders risk levels from low to critical
+func riskRank(risk RiskLevel) int {
+	switch risk {
+	case RiskLow:
+		return 0
+	case RiskMedium:
+		return 1
+	case RiskHigh:
+		return 2
+	default:
+		return 3
+	}
+}
+
 // assessShellCommandRisk assesses the risk of a shell command
 func (a *ApprovalSystem) assessShellCommandRisk(command string) RiskLevel {
 	// Check for critical patterns first (highest priority)
@@ -431,6 +554,68 @@ func (a *ApprovalSystem) generateFileWritePreview(path, content string) string {
 	return preview.String()
 }
 
+// generateFileChangePreview shows the diff an edit, patch or
+// insert_at_line operation would make, or why it can't be applied
+func (a *ApprovalSystem) generateFileChangePreview(path, args string) string {
+	const maxLines = 120
+
+	change, err := tools.PreviewFileChange(".", args)
+	if err != nil {
+		return fmt.Sprintf("File: %s\nCannot apply: %v\n", path, err)
+	}
+
+	var preview strings.Builder
+	preview.WriteString(fmt.Sprintf("File: %s\n", path))
+	added, removed := change.Stats()
+	preview.WriteString(fmt.Sprintf("Changes: +%d -%d lines\n", added, removed))
+
+	lines := strings.Split(strings.TrimRight(change.Diff(), "\n"), "\n")
+	if len(lines) > maxLines {
+		preview.WriteString(strings.Join(lines[:maxLines], "\n"))
+		preview.WriteString(fmt.Sprintf("\n... and %d more lines\n", len(lines)-maxLines))
+	} else {
+		preview.WriteString(strings.Join(lines, "\n") + "\n")
+	}
+	return preview.String()
+}
+
+// generateDeletePreview shows what a delete would remove
+func (a *ApprovalSystem) generateDeletePreview(path string, recursive bool) string {
+	const maxFiles = 20
+
+	info, err := os.Lstat(path)
+	if err != nil {
+		return fmt.Sprintf("Delete: %s\nCannot delete: %v\n", path, err)
+	}
+	if !info.IsDir() {
+		return fmt.Sprintf("Delete file: %s\nSize: %d bytes\n", path, info.Size())
+	}
+
+	var files []string
+	filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			files = append(files, p)
+		}
+		return nil
+	})
+
+	var preview strings.Builder
+	preview.WriteString(fmt.Sprintf("Delete directory: %s\n", path))
+	if len(files) > 0 && !recursive {
+		preview.WriteString("Cannot delete: the directory is not empty and recursive is not set\n")
+		return preview.String()
+	}
+	preview.WriteString(fmt.Sprintf("Files: %d\n", len(files)))
+	for i, file := range files {
+		if i == maxFiles {
+			preview.WriteString(fmt.Sprintf("  ... and %d more files\n", len(files)-maxFiles))
+			break
+		}
+		preview.WriteString(fmt.Sprintf("  %s\n", file))
+	}
+	return preview.String()
+}
+
 // generateShellCommandPreview generates a preview for shell commands
 func (a *ApprovalSystem) generateShellCommandPreview(command string) string {
 	return fmt.Sprintf("Command: %s\nWorking Directory: Current project directory", command)
@@ -446,11 +631,28 @@ func (a *ApprovalSystem) generateDescription(tool, args, opType string) string {
 	switch opType {
 	case "file_write":
 		var params struct {
-			Path string `json:"path"`
+			Type        string `json:"type"`
+			Path        string `json:"path"`
+			Destination string `json:"destination"`
 		}
 		if json.Unmarshal([]byte(args), &params) == nil {
+			switch params.Type {
+			case "move":
+				return fmt.Sprintf("Move %s to %s", params.Path, params.Destination)
+			case "copy":
+				return fmt.Sprintf("Copy %s to %s", params.Path, params.Destination)
+			case "mkdir":
+				return fmt.Sprintf("Create directory: %s", params.Path)
+			}
 			return fmt.Sprintf("Write file: %s", params.Path)
 		}
+	case "file_delete":
+		var params struct {
+			Path string `json:"path"`
+		}
+		if json.Unmarshal([]byte(args), &params) == nil {
+			return fmt.Sprintf("Delete: %s", params.Path)
+		}
 	case "shell_execute":
 		var params struct {
 			Command string `json:"command"`
@@ -533,6 +735,13 @@ func (a *ApprovalSystem) extractMetadata(tool, args string) map[string]interface
 
 // isBlocked checks if an operation is blocked by policy
 func (a *ApprovalSystem) isBlocked(tool, args, opType string) bool {
+	// File operations name their paths, so check those rather than the
+	// text of the arguments, which may mention a blocked command or path
+	// in file content
+	if tool == "file_operations" {
+		return a.isFilePathBlocked(args)
+	}
+
 	// Check blocked commands
 	for _, blocked := range a.config.BlockedCommands {
 		if strings.Contains(args, blocked) {
@@ -550,6 +759,54 @@ func (a *ApprovalSystem) isBlocked(tool, args, opType string) bool {
 	return false
 }
 
+// isFilePathBlocked checks the paths of a file operation against
+// BlockedPaths. A path inside a blocked path is blocked, and so is a
+// delete or move of a directory holding one.
+func (a *ApprovalSystem) isFilePathBlocked(args string) bool {
+	var params struct {
+		Type        string `json:"type"`
+		Path        string `json:"path"`
+		Destination string `json:"destination"`
+	}
+	if err := json.Unmarshal([]byte(args), &params); err != nil {
+		return true
+	}
+
+	paths := []string{params.Path}
+	if params.Destination != "" {
+		paths = append(paths, params.Destination)
+	}
+	for _, blocked := range a.config.BlockedPaths {
+		if blocked == "" {
+			continue
+		}
+		for _, path := range paths {
+			if pathWithin(blocked, path) {
+				return true
+			}
+		}
+		if (params.Type == "delete" || params.Type == "move") && pathWithin(params.Path, blocked) {
+			return true
+		}
+	}
+	return false
+}
+
+// pathWithin reports whether path is dir or inside it, comparing absolute
+// paths so that relative and absolute spellings agree
+func pathWithin(dir, path string) bool {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 // canAutoApprove checks if an operation can be auto-approved
 func (a *ApprovalSystem) canAutoApprove(tool, args, opType string, risk RiskLevel) bool {
 	// Check approval mode
@@ -557,26 +814,94 @@ func (a *ApprovalSystem) canAutoApprove(tool, args, opType string, risk RiskLeve
 		return true
 	}
 
+	// auto-edit applies edits to file content without asking, up to medium
+	// risk; moves and copies, which can replace files, still ask
+	if a.config.Mode == "auto-edit" && tool == "file_operations" && autoEditTypes[fileOperationType(args)] &&
+		riskRank(risk) <= riskRank(RiskMedium) {
+		return true
+	}
+
 	// Auto-approve low risk operations if configured
 	if a.config.AutoApproveLowRisk && risk == RiskLow {
 		return true
 	}
 
-	// Check safe commands
+	// The safe lists below never approve high-risk operations or deletes
+	if risk == RiskHigh || risk == RiskCritical || opType == "file_delete" {
+		return false
+	}
+
+	switch tool {
+	case "shell_execute":
+		return a.isSafeCommand(args)
+	case "file_operations":
+		return a.isSafePath(args)
+	}
+	return false
+}
+
+// autoEditTypes are the file operations auto-edit mode may apply unasked
+var autoEditTypes = map[string]bool{
+	"write": true, "edit": true, "patch": true, "insert_at_line": true, "mkdir": true,
+}
+
+// fileOperationType returns the type of a file_operations call
+func fileOperationType(args string) string {
+	var params struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal([]byte(args), &params); err != nil {
+		return ""
+	}
+	return params.Type
+}
+
+// isSafeCommand reports whether a shell command is one of
+// AutoApproveSafeCommands, alone or with arguments
+func (a *ApprovalSystem) isSafeCommand(args string) bool {
+	var params struct {
+		Command string `json:"command"`
+	}
+	if err := json.Unmarshal([]byte(args), &params); err != nil {
+		return false
+	}
+	command := strings.TrimSpace(params.Command)
 	for _, safe := range a.config.AutoApproveSafeCommands {
-		if strings.Contains(args, safe) {
+		if safe != "" && (command == safe || strings.HasPrefix(command, safe+" ")) {
 			return true
 		}
 	}
+	return false
+}
 
-	// Check safe paths
-	for _, safe := range a.config.AutoApproveSafePaths {
-		if strings.Contains(args, safe) {
-			return true
-		}
+// isSafePath reports whether every path a file operation names is inside
+// one of AutoApproveSafePaths
+func (a *ApprovalSystem) isSafePath(args string) bool {
+	var params struct {
+		Path        string `json:"path"`
+		Destination string `json:"destination"`
+	}
+	if err := json.Unmarshal([]byte(args), &params); err != nil {
+		return false
 	}
 
-	return false
+	paths := []string{params.Path}
+	if params.Destination != "" {
+		paths = append(paths, params.Destination)
+	}
+	for _, path := range paths {
+		safe := false
+		for _, dir := range a.config.AutoApproveSafePaths {
+			if dir != "" && pathWithin(dir, path) {
+				safe = true
+				break
+			}
+		}
+		if !safe {
+			return false
+		}
+	}
+	return true
 }
 
 // canAutoApproveBatch checks if a batch can be auto-approved
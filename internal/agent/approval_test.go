@@ -2,10 +2,13 @@ package agent
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/hammie/rubrduck/internal/ai"
+	"github.com/hammie/rubrduck/internal/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -159,6 +162,75 @@ func TestAnalyzeFileOperation(t *testing.T) {
 	}
 }
 
+func TestAnalyzeFileEditShowsDiff(t *testing.T) {
+	t.Chdir(t.TempDir())
+	require.NoError(t, os.WriteFile("notes.txt", []byte("alpha\nbeta\ngamma\n"), 0644))
+	system := NewApprovalSystem(&Config{}, nil)
+
+	opType, risk, preview, err := system.analyzeFileOperation(`{"type": "edit", "path": "notes.txt", "old_text": "beta", "new_text": "BETA"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "file_write", opType)
+	assert.Equal(t, RiskLow, risk)
+	assert.Contains(t, preview, "Changes: +1 -1 lines")
+	assert.Contains(t, preview, "-beta\n+BETA")
+
+	// Edits that can't apply say why, before anything is approved
+	_, _, preview, err = system.analyzeFileOperation(`{"type": "edit", "path": "notes.txt", "old_text": "delta", "new_text": "x"}`)
+	require.NoError(t, err)
+	assert.Contains(t, preview, "Cannot apply: edit 1: old_text was not found")
+
+	// Added text is judged as for a write
+	_, risk, _, err = system.analyzeFileOperation(`{"type": "insert_at_line", "path": "notes.txt", "line": 1, "content": "api_key = 123"}`)
+	require.NoError(t, err)
+	assert.Equal(t, RiskHigh, risk)
+}
+
+func TestAnalyzeFileManageOperations(t *testing.T) {
+	t.Chdir(t.TempDir())
+	require.NoError(t, os.MkdirAll("build/sub", 0755))
+	require.NoError(t, os.WriteFile("build/out.txt", []byte("out"), 0644))
+	require.NoError(t, os.WriteFile("build/sub/x.txt", []byte("x"), 0644))
+	system := NewApprovalSystem(&Config{}, nil)
+
+	opType, risk, preview, err := system.analyzeFileOperation(`{"type": "delete", "path": "build", "recursive": true}`)
+	require.NoError(t, err)
+	assert.Equal(t, "file_delete", opType)
+	assert.Equal(t, RiskHigh, risk)
+	assert.Contains(t, preview, "Files: 2\n")
+	assert.Contains(t, preview, filepath.Join("build", "sub", "x.txt"))
+
+	_, _, preview, err = system.analyzeFileOperation(`{"type": "delete", "path": "build"}`)
+	require.NoError(t, err)
+	assert.Contains(t, preview, "Cannot delete: the directory is not empty")
+
+	_, risk, _, err = system.analyzeFileOperation(`{"type": "delete", "path": "."}`)
+	require.NoError(t, err)
+	assert.Equal(t, RiskCritical, risk)
+
+	tests := []struct {
+		args string
+		risk RiskLevel
+	}{
+		{`{"type": "mkdir", "path": "docs"}`, RiskLow},
+		{`{"type": "copy", "path": "a.txt", "destination": "b.txt"}`, RiskLow},
+		{`{"type": "move", "path": "a.txt", "destination": "b.txt"}`, RiskMedium},
+		{`{"type": "copy", "path": "a.txt", "destination": "b.txt", "overwrite": true}`, RiskHigh},
+		{`{"type": "move", "path": "a.txt", "destination": "run.sh"}`, RiskHigh},
+	}
+	for _, tt := range tests {
+		opType, risk, _, err := system.analyzeFileOperation(tt.args)
+		require.NoError(t, err)
+		assert.Equal(t, "file_write", opType, tt.args)
+		assert.Equal(t, tt.risk, risk, tt.args)
+	}
+
+	// auto-edit asks before deleting
+	system = NewApprovalSystem(&Config{Mode: "auto-edit"}, nil)
+	assert.False(t, system.canAutoApprove("file_operations", `{"type":"delete"}`, "file_delete", RiskHigh))
+	assert.Equal(t, "Delete: build", system.generateDescription("file_operations", `{"type": "delete", "path": "build"}`, "file_delete"))
+	assert.Equal(t, "Move a to b", system.generateDescription("file_operations", `{"type": "move", "path": "a", "destination": "b"}`, "file_write"))
+}
+
 func TestAnalyzeShellOperation(t *testing.T) {
 	config := &Config{}
 	system := NewApprovalSystem(config, nil)
@@ -289,6 +361,16 @@ func TestAnalyzeGitOperation(t *testing.T) {
 	}
 }
 
+func TestAnalyzeCodeSearch(t *testing.T) {
+	system := NewApprovalSystem(&Config{}, nil)
+
+	opType, risk, preview, err := system.analyzeOperation("code_search", `{"query": "token validation"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "code_search", opType)
+	assert.Equal(t, RiskLow, risk)
+	assert.Equal(t, "Searching code for: token validation", preview)
+}
+
 func TestAssessFileWriteRisk(t *testing.T) {
 	config := &Config{}
 	system := NewApprovalSystem(config, nil)
@@ -547,6 +629,76 @@ func TestCanAutoApprove(t *testing.T) {
 	}
 }
 
+func TestSafeListsDoNotApproveDeletes(t *testing.T) {
+	// The default sandbox lists, as the agent turns them into a policy
+	cfg := &config.Config{}
+	cfg.Agent.ApprovalMode = "suggest"
+	cfg.Sandbox.AllowWritePaths = []string{"./"}
+	cfg.Sandbox.AllowedCommands = []string{
+		"ls", "cat", "head", "tail", "grep", "find", "wc", "sort", "uniq",
+		"echo", "pwd", "whoami", "date", "ps", "git", "go", "npm", "yarn", "python", "node", "make",
+	}
+	system := NewApprovalSystem(newApprovalConfig(cfg), nil)
+
+	for _, args := range []string{
+		`{"type": "delete", "path": "internal/agent/tools", "recursive": true}`,
+		`{"type": "delete", "path": "./src", "recursive": true}`,
+		`{"type": "delete", "path": "find.txt"}`,
+		`{"type": "move", "path": "./src", "destination": "./run.sh"}`,
+		`{"type": "copy", "path": "a.txt", "destination": "./b.txt", "overwrite": true}`,
+	} {
+		result, err := system.RequestApproval(context.Background(), "file_operations", args, ai.ToolCall{ID: "call_1"})
+		require.NoError(t, err)
+		assert.False(t, result.Approved, args)
+	}
+
+	// Safe paths are matched on the paths, not the arguments' text
+	result, err := system.RequestApproval(context.Background(), "file_operations",
+		`{"type": "move", "path": "./notes.txt", "destination": "./docs/notes.txt"}`, ai.ToolCall{ID: "call_2"})
+	require.NoError(t, err)
+	assert.True(t, result.Approved)
+	result, err = system.RequestApproval(context.Background(), "file_operations",
+		`{"type": "move", "path": "/tmp/x/notes.txt", "destination": "./notes.txt"}`, ai.ToolCall{ID: "call_3"})
+	require.NoError(t, err)
+	assert.False(t, result.Approved)
+
+	// Safe commands match the command itself, not any word in it
+	assert.True(t, system.canAutoApprove("shell_execute", `{"command": "git log -5"}`, "shell_execute", RiskMedium))
+	assert.False(t, system.canAutoApprove("shell_execute", `{"command": "ls | sh"}`, "shell_execute", RiskHigh))
+	assert.False(t, system.canAutoApprove("shell_execute", `{"command": "make-everything --ls"}`, "shell_execute", RiskMedium))
+}
+
+func TestCanAutoApproveAutoEdit(t *testing.T) {
+	system := NewApprovalSystem(&Config{Mode: "auto-edit"}, nil)
+
+	assert.True(t, system.canAutoApprove("file_operations", `{"type":"write"}`, "file_write", RiskMedium))
+	assert.True(t, system.canAutoApprove("file_operations", `{"type":"mkdir"}`, "file_write", RiskLow))
+	assert.False(t, system.canAutoApprove("file_operations", `{"type":"write"}`, "file_write", RiskCritical))
+	assert.False(t, system.canAutoApprove("shell_execute", `{"command":"make"}`, "shell_execute", RiskMedium))
+
+	// High-risk writes, such as scripts or content with secrets, still ask
+	for _, args := range []string{
+		`{"type": "write", "path": "deploy.sh", "content": "echo hi"}`,
+		`{"type": "edit", "path": "config.txt", "old_text": "a", "new_text": "password: hunter2"}`,
+	} {
+		opType, risk, _, err := system.analyzeFileOperation(args)
+		require.NoError(t, err)
+		assert.Equal(t, RiskHigh, risk, args)
+		assert.False(t, system.canAutoApprove("file_operations", args, opType, risk), args)
+	}
+
+	// So do moves and copies, above all ones that replace a file
+	for _, args := range []string{
+		`{"type": "move", "path": "a.txt", "destination": "b.txt", "overwrite": true}`,
+		`{"type": "copy", "path": "a.txt", "destination": "b.txt", "overwrite": true}`,
+		`{"type": "move", "path": "a.txt", "destination": "b.txt"}`,
+	} {
+		opType, risk, _, err := system.analyzeFileOperation(args)
+		require.NoError(t, err)
+		assert.False(t, system.canAutoApprove("file_operations", args, opType, risk), args)
+	}
+}
+
 func TestIsBlocked(t *testing.T) {
 	config := &Config{
 		BlockedCommands: []string{"rm"},
@@ -583,6 +735,34 @@ func TestIsBlocked(t *testing.T) {
 			opType:   "file_read",
 			expected: false,
 		},
+		{
+			name:     "blocked move destination",
+			tool:     "file_operations",
+			args:     `{"type": "move", "path": "a.txt", "destination": "/etc/a.txt"}`,
+			opType:   "file_write",
+			expected: true,
+		},
+		{
+			name:     "delete of a directory holding a blocked path",
+			tool:     "file_operations",
+			args:     `{"type": "delete", "path": "/", "recursive": true}`,
+			opType:   "file_delete",
+			expected: true,
+		},
+		{
+			name:     "file operations are not matched against blocked commands",
+			tool:     "file_operations",
+			args:     `{"type": "write", "path": "notes.txt", "content": "rm the old format"}`,
+			opType:   "file_write",
+			expected: false,
+		},
+		{
+			name:     "file content mentioning a blocked path",
+			tool:     "file_operations",
+			args:     `{"type": "write", "path": "README.md", "content": "config lives in /etc/app"}`,
+			opType:   "file_write",
+			expected: false,
+		},
 	}
 
 	for _, tt := range tests {
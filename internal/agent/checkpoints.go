@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"strings"
+
+	"github.com/hammie/rubrduck/internal/checkpoint"
+	"github.com/rs/zerolog/log"
+)
+
+// maxCheckpointLabel bounds the prompt text kept as a checkpoint's label
+const maxCheckpointLabel = 80
+
+// Checkpoints returns the workspace checkpoints, or nil when they are off
+func (a *Agent) Checkpoints() *checkpoint.Manager {
+	return a.checkpoints
+}
+
+// beginTurn starts the checkpoint that lets the turn for message be undone
+func (a *Agent) beginTurn(message string) {
+	if a.checkpoints == nil {
+		return
+	}
+	if err := a.checkpoints.Begin(checkpointLabel(message)); err != nil {
+		log.Warn().Err(err).Msg("Failed to start checkpoint; this turn can't be undone")
+	}
+}
+
+// endTurn stores the turn's checkpoint
+func (a *Agent) endTurn() {
+	if a.checkpoints == nil {
+		return
+	}
+	cp, err := a.checkpoints.End()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to save checkpoint; this turn can't be undone")
+		return
+	}
+	if cp != nil {
+		log.Debug().Int("checkpoint", cp.ID).Int("paths", len(cp.Entries)).Msg("Saved checkpoint")
+	}
+}
+
+// checkpointLabel returns the first line of the prompt, shortened
+func checkpointLabel(message string) string {
+	label := strings.TrimSpace(message)
+	if i := strings.IndexByte(label, '\n'); i >= 0 {
+		label = strings.TrimSpace(label[:i])
+	}
+	if runes := []rune(label); len(runes) > maxCheckpointLabel {
+		label = string(runes[:maxCheckpointLabel-3]) + "..."
+	}
+	return label
+}
@@ -18,6 +18,9 @@ const (
 	EventToolEnd
 	// EventDone signals the end of the streaming conversation and includes usage stats.
 	EventDone
+	// EventReasoning carries a chunk of the model's thinking in Token, kept
+	// apart from the answer.
+	EventReasoning
 )
 
 // StreamEvent is emitted by Agent.StreamEvents to report incremental progress.
@@ -28,6 +31,8 @@ type StreamEvent struct {
 	ToolID   string
 	ToolName string
 	Result   string
-	Usage    ai.Usage
-	Err      error
+	// Denied is set on a tool result when the call was not approved
+	Denied bool
+	Usage  ai.Usage
+	Err    error
 }
@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"github.com/hammie/rubrduck/internal/ai"
+	"github.com/rs/zerolog/log"
+)
+
+// contextMessages returns the most recent part of the history that fits in
+// the model's context window next to the completion budget. Older turns are
+// dropped whole, so the window always starts on a user message and never
+// on a tool result whose call was cut. The mode's system prompt, if any,
+// leads the result, which is a copy carrying cache breakpoints (see
+// withCacheBreakpoints).
+func (a *Agent) contextMessages() []ai.Message {
+	limit := a.model.ContextWindow
+	if max := a.config.Tokens.MaxContextTokens; max > 0 && max < limit {
+		limit = max
+	}
+	limit -= a.completionTokens()
+
+	// The system prompt is sent with every request and never trimmed
+	var system []ai.Message
+	if prompt := a.SystemPrompt(); prompt != "" {
+		system = append(system, ai.Message{Role: "system", Content: prompt})
+		limit -= estimateTokens(system[0])
+	}
+
+	total := 0
+	for _, msg := range a.history {
+		total += estimateTokens(msg)
+	}
+
+	start := 0
+	for total > limit && start < len(a.history)-1 {
+		total -= estimateTokens(a.history[start])
+		start++
+		for start < len(a.history)-1 && a.history[start].Role != "user" {
+			total -= estimateTokens(a.history[start])
+			start++
+		}
+	}
+
+	if start > 0 {
+		log.Debug().
+			Int("dropped_messages", start).
+			Int("estimated_tokens", total).
+			Int("context_limit", limit).
+			Msg("Trimmed history to fit the context window")
+	}
+
+	return a.withCacheBreakpoints(append(system, a.history[start:]...))
+}
+
+// withCacheBreakpoints returns a copy of messages with prompt cache
+// breakpoints after the leading system messages and on the last message.
+// Each request in a conversation extends the previous one, so caching the
+// whole request lets the next one read everything but its new messages
+// from the cache.
+func (a *Agent) withCacheBreakpoints(messages []ai.Message) []ai.Message {
+	marked := make([]ai.Message, len(messages))
+	copy(marked, messages)
+	if !a.config.Tokens.PromptCaching || len(marked) == 0 {
+		return marked
+	}
+
+	system := 0
+	for system < len(marked) && marked[system].Role == "system" {
+		system++
+	}
+	if system > 0 {
+		marked[system-1].CacheBreakpoint = true
+	}
+	marked[len(marked)-1].CacheBreakpoint = true
+	return marked
+}
+
+// estimateTokens approximates a message's token count at four characters
+// per token, plus a small per-message overhead
+func estimateTokens(msg ai.Message) int {
+	chars := len(msg.Content)
+	for _, part := range msg.Parts {
+		chars += len(part.Text)
+	}
+	for _, call := range msg.ToolCalls {
+		chars += len(call.Function.Name) + len(call.Function.Arguments)
+	}
+	return chars/4 + 4
+}
@@ -0,0 +1,55 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hammie/rubrduck/internal/ai"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgentCacheBreakpoints(t *testing.T) {
+	ag, recorder := newRecordingAgent(t, "mock-cache", nil, "gpt-4o")
+	ag.config.Tokens.PromptCaching = true
+	ag.history = append(ag.history,
+		ai.Message{Role: "system", Content: "You are RubrDuck."},
+		ai.Message{Role: "user", Content: "first"},
+		ai.Message{Role: "assistant", Content: "answer"},
+	)
+
+	_, err := ag.Chat(context.Background(), "second")
+	require.NoError(t, err)
+
+	req := recorder.last
+	require.True(t, req.CacheTools)
+	require.True(t, req.Messages[0].CacheBreakpoint)
+	require.False(t, req.Messages[1].CacheBreakpoint)
+	require.True(t, req.Messages[len(req.Messages)-1].CacheBreakpoint)
+
+	// Breakpoints are set on the request copy, not the stored history
+	for _, msg := range ag.GetHistory() {
+		require.False(t, msg.CacheBreakpoint)
+	}
+}
+
+func TestAgentSystemPrompt(t *testing.T) {
+	ag, recorder := newRecordingAgent(t, "mock-system", nil, "gpt-4o")
+	ag.SetSystemPrompt("", "You are RubrDuck.")
+	ag.SetSystemPrompt("planning", "You plan.")
+
+	_, err := ag.Chat(context.Background(), "first")
+	require.NoError(t, err)
+	require.Equal(t, ai.Message{Role: "system", Content: "You are RubrDuck."}, recorder.last.Messages[0])
+
+	// Switching modes swaps the prompt; the history keeps only real turns
+	ag.SetMode("planning")
+	_, err = ag.Chat(context.Background(), "second")
+	require.NoError(t, err)
+	req := recorder.last
+	require.Equal(t, "You plan.", req.Messages[0].Content)
+	require.Equal(t, "second", req.Messages[len(req.Messages)-1].Content)
+	require.Len(t, req.Messages, 4) // the prompt, first, its reply and second
+	for _, msg := range ag.GetHistory() {
+		require.NotEqual(t, "system", msg.Role)
+	}
+}
@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"github.com/hammie/rubrduck/internal/ai"
+	"github.com/hammie/rubrduck/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// RegisterConfiguredModels applies the models section of the config to the
+// model registry. Entries matching a registered model override only the
+// fields that are set.
+func RegisterConfiguredModels(models []config.ModelConfig) {
+	for _, m := range models {
+		if m.ID == "" {
+			log.Warn().Msg("Ignoring configured model without an id")
+			continue
+		}
+
+		// Unknown models start from the same assumptions as unregistered ones
+		info, ok := ai.LookupModel(m.ID)
+		if !ok {
+			info = ai.ModelInfoFor(m.Provider, m.ID)
+		}
+		if info.ID != m.ID {
+			// Matched by alias or base name; register a separate entry
+			info.ID = m.ID
+			info.Aliases = nil
+		}
+
+		if m.Provider != "" {
+			info.Provider = m.Provider
+		}
+		for _, alias := range m.Aliases {
+			if !containsString(info.Aliases, alias) {
+				info.Aliases = append(info.Aliases, alias)
+			}
+		}
+		if m.ContextWindow > 0 {
+			info.ContextWindow = m.ContextWindow
+		}
+		if m.MaxOutputTokens > 0 {
+			info.MaxOutputTokens = m.MaxOutputTokens
+		}
+		if m.SupportsTools != nil {
+			info.SupportsTools = *m.SupportsTools
+		}
+		if m.SupportsVision != nil {
+			info.SupportsVision = *m.SupportsVision
+		}
+		if m.SupportsStreamingTools != nil {
+			info.SupportsStreamingTools = *m.SupportsStreamingTools
+		}
+		if m.SupportsReasoning != nil {
+			info.SupportsReasoning = *m.SupportsReasoning
+		}
+		if m.InputPrice != nil {
+			info.InputPrice = *m.InputPrice
+		}
+		if m.OutputPrice != nil {
+			info.OutputPrice = *m.OutputPrice
+		}
+		if m.CacheReadPrice != nil {
+			info.CacheReadPrice = *m.CacheReadPrice
+		}
+		if m.CacheWritePrice != nil {
+			info.CacheWritePrice = *m.CacheWritePrice
+		}
+
+		ai.RegisterModel(info)
+	}
+}
+
+// Model returns the registry entry for the model the agent is using
+func (a *Agent) Model() ai.ModelInfo {
+	return a.model
+}
+
+// requestTools returns the tool definitions to send, or nil when the model
+// can't call tools
+func (a *Agent) requestTools() []ai.Tool {
+	if !a.model.SupportsTools {
+		return nil
+	}
+	return a.getToolDefinitions()
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hammie/rubrduck/internal/ai"
+	"github.com/hammie/rubrduck/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingProvider captures the last request and counts calls per method
+type recordingProvider struct {
+	mockProvider
+	last        *ai.ChatRequest
+	chatCalls   int
+	streamCalls int
+}
+
+func (r *recordingProvider) Chat(ctx context.Context, req *ai.ChatRequest) (*ai.ChatResponse, error) {
+	r.last = req
+	r.chatCalls++
+	return r.mockProvider.Chat(ctx, req)
+}
+
+func (r *recordingProvider) StreamChat(ctx context.Context, req *ai.ChatRequest) (ai.ChatStream, error) {
+	r.last = req
+	r.streamCalls++
+	return r.mockProvider.StreamChat(ctx, req)
+}
+
+func newRecordingAgent(t *testing.T, name string, models []config.ModelConfig, model string) (*Agent, *recordingProvider) {
+	recorder := &recordingProvider{}
+	ai.RegisterProvider(name, func(cfg map[string]interface{}) (ai.Provider, error) { return recorder, nil })
+
+	ag, err := New(&config.Config{
+		Provider:  name,
+		Model:     model,
+		Providers: map[string]config.Provider{name: {Name: name}},
+		Models:    models,
+		Agent:     config.AgentConfig{ApprovalMode: "suggest"},
+	})
+	require.NoError(t, err)
+	return ag, recorder
+}
+
+func TestAgentUsesRegistryLimits(t *testing.T) {
+	noTools := false
+	ag, recorder := newRecordingAgent(t, "mock-budget", []config.ModelConfig{{
+		ID:              "tiny-model",
+		Provider:        "mock-budget",
+		ContextWindow:   400,
+		MaxOutputTokens: 50,
+		SupportsTools:   &noTools,
+	}}, "tiny-model")
+
+	require.Equal(t, 400, ag.Model().ContextWindow)
+
+	// Fill the history well past the 350 tokens left for the prompt
+	for i := 0; i < 5; i++ {
+		ag.history = append(ag.history,
+			ai.Message{Role: "user", Content: strings.Repeat("q", 400)},
+			ai.Message{Role: "assistant", Content: strings.Repeat("a", 400)},
+		)
+	}
+
+	_, err := ag.Chat(context.Background(), "latest question")
+	require.NoError(t, err)
+
+	req := recorder.last
+	require.Equal(t, "tiny-model", req.Model)
+	require.Equal(t, 50, req.MaxTokens)
+	require.Nil(t, req.Tools)
+	require.Less(t, len(req.Messages), len(ag.GetHistory()))
+	require.Equal(t, "user", req.Messages[0].Role)
+	require.Equal(t, "latest question", req.Messages[len(req.Messages)-1].Content)
+}
+
+func TestAgentProviderNumCtxLimitsContext(t *testing.T) {
+	ai.RegisterProvider("mock-numctx", func(cfg map[string]interface{}) (ai.Provider, error) { return &recordingProvider{}, nil })
+
+	ag, err := New(&config.Config{
+		Provider:  "mock-numctx",
+		Model:     "llama3.1",
+		Providers: map[string]config.Provider{"mock-numctx": {Name: "mock-numctx", NumCtx: 16384}},
+		Agent:     config.AgentConfig{ApprovalMode: "suggest"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 16384, ag.Model().ContextWindow)
+	require.Equal(t, 131072, ai.ModelInfoFor("ollama", "llama3.1").ContextWindow, "the registry keeps the model's maximum")
+}
+
+func TestAgentStreamingToolsFallback(t *testing.T) {
+	noStreamingTools := false
+	ag, recorder := newRecordingAgent(t, "mock-nostream", []config.ModelConfig{{
+		ID:                     "batch-model",
+		Provider:               "mock-nostream",
+		SupportsStreamingTools: &noStreamingTools,
+	}}, "batch-model")
+
+	ch, err := ag.StreamEvents(context.Background(), "hi")
+	require.NoError(t, err)
+
+	var out string
+	for ev := range ch {
+		if ev.Type == EventTokenChunk {
+			out += ev.Token
+		}
+	}
+
+	require.Equal(t, "final", out)
+	require.Equal(t, 1, recorder.chatCalls)
+	require.Equal(t, 0, recorder.streamCalls)
+}
@@ -0,0 +1,75 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/hammie/rubrduck/internal/ai"
+	"github.com/hammie/rubrduck/internal/ai/providers"
+	"github.com/hammie/rubrduck/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+// runEvents sends prompt and returns the streamed text and tool results
+func runEvents(t *testing.T, ag *Agent, prompt string) (string, []string) {
+	ag.SetApprovalCallback(func(req ApprovalRequest) (ApprovalResult, error) {
+		return ApprovalResult{Approved: true}, nil
+	})
+	ch, err := ag.StreamEvents(context.Background(), prompt)
+	require.NoError(t, err)
+
+	var text string
+	var results []string
+	for ev := range ch {
+		require.NoError(t, ev.Err)
+		switch ev.Type {
+		case EventTokenChunk:
+			text += ev.Token
+		case EventToolResult:
+			results = append(results, ev.Result)
+		}
+	}
+	return text, results
+}
+
+func TestAgentRecordAndReplay(t *testing.T) {
+	cassette := filepath.Join(t.TempDir(), "session.jsonl")
+	ai.RegisterProvider("mock-record", func(cfg map[string]interface{}) (ai.Provider, error) {
+		return &mockProviderWithToolCalls{}, nil
+	})
+
+	recorded, err := New(&config.Config{
+		Provider:  "mock-record",
+		Model:     "gpt-4",
+		Providers: map[string]config.Provider{"mock-record": {Name: "mock-record"}},
+		Agent:     config.AgentConfig{ApprovalMode: "auto-edit"},
+		Record:    cassette,
+	})
+	require.NoError(t, err)
+	wantText, wantResults := runEvents(t, recorded, "list files")
+	require.NotEmpty(t, wantResults)
+
+	replayConfig := func() *config.Config {
+		return &config.Config{
+			Provider:  "replay",
+			Model:     "gpt-4",
+			Providers: map[string]config.Provider{"replay": {Name: "Replay", Cassette: cassette}},
+			Agent:     config.AgentConfig{ApprovalMode: "auto-edit"},
+		}
+	}
+
+	replayed, err := New(replayConfig())
+	require.NoError(t, err)
+	text, results := runEvents(t, replayed, "list files")
+	require.Equal(t, wantText, text)
+	require.Equal(t, wantResults, results)
+
+	// A different conversation is caught at the first request
+	diverged, err := New(replayConfig())
+	require.NoError(t, err)
+	_, err = diverged.StreamEvents(context.Background(), "delete everything")
+	require.True(t, errors.Is(err, providers.ErrReplayMismatch), "got %v", err)
+	require.Contains(t, err.Error(), `messages[0].content: recorded "list files", got "delete everything"`)
+}
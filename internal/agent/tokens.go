@@ -0,0 +1,31 @@
+package agent
+
+// completionTokens returns the completion budget for a request: the model's
+// default budget, lowered by tokens.max_completion_tokens when set. Thinking
+// counts against the budget, so the reasoning budget is added to the limit
+// to leave the answer its full share.
+func (a *Agent) completionTokens() int {
+	budget := a.model.CompletionBudget()
+	if limit := a.config.Tokens.MaxCompletionTokens; limit > 0 {
+		limit += a.reasoningBudget()
+		if limit < budget {
+			budget = limit
+		}
+	}
+	return budget
+}
+
+// SetThinkingBudget sets the reasoning budget for following requests, e.g.
+// when the TUI switches mode. 0 turns thinking off.
+func (a *Agent) SetThinkingBudget(tokens int) {
+	a.thinkingBudget = tokens
+}
+
+// reasoningBudget returns the thinking budget to request, or 0 when the
+// model can't think
+func (a *Agent) reasoningBudget() int {
+	if !a.model.SupportsReasoning || a.thinkingBudget <= 0 {
+		return 0
+	}
+	return a.thinkingBudget
+}
@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hammie/rubrduck/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgentCompletionTokensCap(t *testing.T) {
+	ag, recorder := newRecordingAgent(t, "mock-cap", nil, "gpt-4o")
+	ag.config.Tokens.MaxCompletionTokens = 1000
+
+	_, err := ag.Chat(context.Background(), "hi")
+	require.NoError(t, err)
+	require.Equal(t, 1000, recorder.last.MaxTokens)
+	require.NotEmpty(t, recorder.last.Tools)
+}
+
+func TestAgentCompletionTokensLeaveRoomForThinking(t *testing.T) {
+	reasoning := true
+	ag, recorder := newRecordingAgent(t, "mock-think", []config.ModelConfig{{
+		ID:                "thinking-model",
+		Provider:          "mock-think",
+		ContextWindow:     200000,
+		MaxOutputTokens:   64000,
+		SupportsReasoning: &reasoning,
+	}}, "thinking-model")
+	ag.config.Tokens.MaxCompletionTokens = 8192
+	ag.SetThinkingBudget(4096)
+
+	_, err := ag.Chat(context.Background(), "hi")
+	require.NoError(t, err)
+	require.Equal(t, 8192+4096, recorder.last.MaxTokens)
+	require.Equal(t, 4096, recorder.last.ReasoningBudget)
+
+	// The model's own budget still caps the total
+	ag.SetThinkingBudget(60000)
+	_, err = ag.Chat(context.Background(), "again")
+	require.NoError(t, err)
+	require.Equal(t, 50000, recorder.last.MaxTokens)
+}
@@ -0,0 +1,159 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hammie/rubrduck/internal/ai"
+	"github.com/rs/zerolog/log"
+)
+
+// maxParallelTools bounds how many read-only tool calls run at once
+const maxParallelTools = 4
+
+// toolOutcome is the result of one tool call as sent back to the model
+type toolOutcome struct {
+	call   ai.ToolCall
+	result string
+	denied bool
+}
+
+// message returns the outcome as a tool message for the history
+func (o toolOutcome) message() ai.Message {
+	return ai.Message{Role: "tool", Content: o.result, ToolCallID: o.call.ID}
+}
+
+// runToolCalls approves and executes calls, reporting each outcome in the
+// calls' order. Approval is asked one call at a time. Consecutive calls
+// that only read and are low risk run together on up to maxParallelTools
+// workers; anything else runs alone, after the reads before it, so writes
+// and shell commands keep their order.
+func (a *Agent) runToolCalls(ctx context.Context, calls []ai.ToolCall, report func(toolOutcome)) {
+	outcomes := make([]toolOutcome, len(calls))
+	var reads []int
+
+	// flush runs the pending reads and reports them, with any outcomes
+	// that were settled while they waited, up to index end
+	reported := 0
+	flush := func(end int) {
+		a.runParallel(ctx, calls, reads, outcomes)
+		reads = nil
+		for ; reported < end; reported++ {
+			report(outcomes[reported])
+		}
+	}
+
+	for i, call := range calls {
+		outcomes[i].call = call
+		tool, settled := a.approveToolCall(ctx, call, &outcomes[i])
+		switch {
+		case settled:
+			if len(reads) == 0 {
+				flush(i + 1)
+			}
+		case a.approvalSystem.IsReadOnly(call.Function.Name, call.Function.Arguments):
+			reads = append(reads, i)
+		default:
+			flush(i)
+			outcomes[i].result = a.executeTool(ctx, tool, call)
+			flush(i + 1)
+		}
+	}
+	flush(len(calls))
+}
+
+// approveToolCall checks that call is complete, known and approved. It
+// returns the tool to run, or settled with the outcome already filled in.
+func (a *Agent) approveToolCall(ctx context.Context, call ai.ToolCall, outcome *toolOutcome) (tool Tool, settled bool) {
+	name := call.Function.Name
+	if name == "" || call.Function.Arguments == "" {
+		log.Warn().
+			Str("tool_call_id", call.ID).
+			Str("function_name", name).
+			Str("arguments", call.Function.Arguments).
+			Msg("Skipping incomplete tool call")
+		outcome.result = fmt.Sprintf("Error: Incomplete tool call - name='%s', args='%s'", name, call.Function.Arguments)
+		return nil, true
+	}
+
+	tool, ok := a.tools[name]
+	if !ok {
+		log.Error().Str("tool_call_id", call.ID).Str("tool_name", name).Msg("Unknown tool requested")
+		outcome.result = fmt.Sprintf("Error: Unknown tool '%s'", name)
+		return nil, true
+	}
+
+	approval, err := a.approvalSystem.RequestApproval(ctx, name, call.Function.Arguments, call)
+	if err != nil {
+		log.Error().Err(err).Str("tool_call_id", call.ID).Str("tool_name", name).Msg("Approval request failed")
+		outcome.result = fmt.Sprintf("Error: Approval failed for %s: %v", name, err)
+		return nil, true
+	}
+	if !approval.Approved {
+		log.Info().
+			Str("tool_call_id", call.ID).
+			Str("tool_name", name).
+			Str("denial_reason", approval.Reason).
+			Msg("Tool call denied")
+		outcome.result = fmt.Sprintf("Operation denied: %s", approval.Reason)
+		outcome.denied = true
+		return nil, true
+	}
+
+	log.Info().
+		Str("tool_call_id", call.ID).
+		Str("tool_name", name).
+		Str("approval_reason", approval.Reason).
+		Msg("Tool call approved, executing")
+	return tool, false
+}
+
+// runParallel executes the approved read-only calls at indexes on a
+// bounded pool, storing each result in its outcome
+func (a *Agent) runParallel(ctx context.Context, calls []ai.ToolCall, indexes []int, outcomes []toolOutcome) {
+	if len(indexes) == 1 {
+		i := indexes[0]
+		outcomes[i].result = a.executeTool(ctx, a.tools[calls[i].Function.Name], calls[i])
+		return
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, maxParallelTools)
+	for _, i := range indexes {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			outcomes[i].result = a.executeTool(ctx, a.tools[calls[i].Function.Name], calls[i])
+		}(i)
+	}
+	wg.Wait()
+}
+
+// executeTool runs an approved call and returns the result for the model
+func (a *Agent) executeTool(ctx context.Context, tool Tool, call ai.ToolCall) string {
+	start := time.Now()
+	result, err := tool.Execute(ctx, call.Function.Arguments)
+	duration := time.Since(start)
+
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("tool_call_id", call.ID).
+			Str("tool_name", call.Function.Name).
+			Dur("execution_duration", duration).
+			Msg("Tool execution failed")
+		return fmt.Sprintf("Error executing %s: %v", call.Function.Name, err)
+	}
+
+	log.Info().
+		Str("tool_call_id", call.ID).
+		Str("tool_name", call.Function.Name).
+		Dur("execution_duration", duration).
+		Int("result_length", len(result)).
+		Msg("Tool execution completed successfully")
+	return result
+}
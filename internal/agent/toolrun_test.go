@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hammie/rubrduck/internal/ai"
+	"github.com/stretchr/testify/require"
+)
+
+// timedTool records when each call runs and how many overlap
+type timedTool struct {
+	mu      sync.Mutex
+	running int
+	peak    int
+	order   []string
+}
+
+func (t *timedTool) GetDefinition() ai.Tool {
+	return ai.Tool{Type: "function"}
+}
+
+func (t *timedTool) Execute(ctx context.Context, args string) (string, error) {
+	var params struct {
+		Type string `json:"type"`
+		Path string `json:"path"`
+	}
+	json.Unmarshal([]byte(args), &params)
+
+	t.mu.Lock()
+	t.running++
+	t.peak = max(t.peak, t.running)
+	t.mu.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	t.mu.Lock()
+	t.running--
+	t.order = append(t.order, params.Path)
+	t.mu.Unlock()
+	return params.Type + " " + params.Path, nil
+}
+
+func fileCall(id, op, path string) ai.ToolCall {
+	call := ai.ToolCall{ID: id, Type: "function"}
+	call.Function.Name = "file_operations"
+	call.Function.Arguments = fmt.Sprintf(`{"type":%q,"path":%q,"content":"x"}`, op, path)
+	return call
+}
+
+func TestRunToolCallsParallelReads(t *testing.T) {
+	ag, _ := newRecordingAgent(t, "mock-parallel", nil, "gpt-4o")
+	ag.approvalSystem.config.Mode = "full-auto"
+	tool := &timedTool{}
+	ag.RegisterTool("file_operations", tool)
+
+	calls := []ai.ToolCall{
+		fileCall("c1", "read", "a.go"),
+		fileCall("c2", "read", "b.go"),
+		fileCall("c3", "list", "."),
+		fileCall("c4", "write", "notes.txt"),
+		fileCall("c5", "read", "c.go"),
+		fileCall("c6", "read", "d.go"),
+	}
+	results := ag.executeToolCalls(context.Background(), calls)
+
+	// Results keep the calls' order and IDs
+	require.Len(t, results, len(calls))
+	for i, msg := range results {
+		require.Equal(t, calls[i].ID, msg.ToolCallID)
+		require.Equal(t, "tool", msg.Role)
+	}
+	require.Equal(t, "write notes.txt", results[3].Content)
+
+	// Reads overlapped, but the write ran after the reads before it and
+	// before the reads after it
+	require.Greater(t, tool.peak, 1)
+	require.ElementsMatch(t, []string{"a.go", "b.go", "."}, tool.order[:3])
+	require.Equal(t, "notes.txt", tool.order[3])
+	require.ElementsMatch(t, []string{"c.go", "d.go"}, tool.order[4:])
+}
+
+func TestIsReadOnly(t *testing.T) {
+	system := NewApprovalSystem(&Config{Mode: "suggest"}, nil)
+
+	require.True(t, system.IsReadOnly("file_operations", `{"type":"read","path":"a.go"}`))
+	require.True(t, system.IsReadOnly("git_operations", `{"operation":"diff"}`))
+	require.False(t, system.IsReadOnly("git_operations", `{"operation":"commit"}`))
+	require.False(t, system.IsReadOnly("file_operations", `{"type":"write","path":"a.go","content":"x"}`))
+	require.False(t, system.IsReadOnly("shell_execute", `{"command":"ls"}`))
+}
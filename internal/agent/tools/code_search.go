@@ -0,0 +1,132 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/hammie/rubrduck/internal/ai"
+	"github.com/hammie/rubrduck/internal/index"
+	"github.com/rs/zerolog/log"
+)
+
+// CodeSearchTool finds code by meaning using the repository's vector index
+type CodeSearchTool struct {
+	basePath string
+	embedder ai.Embedder
+	options  index.Options
+
+	// index is opened on first use so agents that never search don't pay
+	// for loading or building it
+	mu    sync.Mutex
+	index *index.Index
+}
+
+// NewCodeSearchTool creates a new code search tool instance
+func NewCodeSearchTool(basePath string, embedder ai.Embedder, options index.Options) *CodeSearchTool {
+	return &CodeSearchTool{
+		basePath: basePath,
+		embedder: embedder,
+		options:  options,
+	}
+}
+
+// GetDefinition returns the tool definition for the AI
+func (c *CodeSearchTool) GetDefinition() ai.Tool {
+	return ai.Tool{
+		Type: "function",
+		Function: ai.ToolFunction{
+			Name: "code_search",
+			Description: "Semantic code search: find functions, types and docs relevant to a natural-language " +
+				"description (e.g. \"where are auth tokens validated\"). Use file_operations to read full files.",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "What the code you're looking for does",
+					},
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Only search files under this directory (relative to project root)",
+					},
+					"max_results": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum number of snippets to return",
+						"default":     5,
+					},
+				},
+				"required": []string{"query"},
+			},
+		},
+	}
+}
+
+// Execute runs the search with the given arguments
+func (c *CodeSearchTool) Execute(ctx context.Context, args string) (string, error) {
+	var params struct {
+		Query      string `json:"query"`
+		Path       string `json:"path"`
+		MaxResults int    `json:"max_results"`
+	}
+
+	if err := json.Unmarshal([]byte(args), &params); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	if params.MaxResults == 0 {
+		params.MaxResults = 5
+	}
+
+	idx, err := c.openIndex()
+	if err != nil {
+		return "", err
+	}
+
+	// Re-embed whatever changed since the last search
+	stats, err := idx.Update(ctx)
+	if err != nil {
+		if stats.Chunks == 0 {
+			return "", fmt.Errorf("failed to build code index: %w", err)
+		}
+		log.Warn().Err(err).Msg("Code index is partially out of date")
+	}
+
+	results, err := idx.Search(ctx, params.Query, params.MaxResults, params.Path)
+	if err != nil {
+		return "", fmt.Errorf("search failed: %w", err)
+	}
+
+	if len(results) == 0 {
+		return fmt.Sprintf("No code found matching '%s'", params.Query), nil
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Found %d snippets relevant to '%s':\n", len(results), params.Query))
+	for _, r := range results {
+		location := fmt.Sprintf("%s:%d-%d", r.Path, r.StartLine, r.EndLine)
+		if r.Symbol != "" {
+			location += " (" + r.Symbol + ")"
+		}
+		result.WriteString(fmt.Sprintf("\n%s  score %.2f\n```\n%s\n```\n", location, r.Score, r.Content))
+	}
+
+	return result.String(), nil
+}
+
+// openIndex opens the index on first use
+func (c *CodeSearchTool) openIndex() (*index.Index, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.index == nil {
+		idx, err := index.Open(c.basePath, c.embedder, c.options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open code index: %w", err)
+		}
+		c.index = idx
+	}
+	return c.index, nil
+}
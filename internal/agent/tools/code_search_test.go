@@ -0,0 +1,59 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hammie/rubrduck/internal/ai"
+	"github.com/hammie/rubrduck/internal/index"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// keywordEmbedder scores texts on a fixed vocabulary, enough to rank
+// snippets in tests
+type keywordEmbedder struct{}
+
+func (keywordEmbedder) Embed(ctx context.Context, req *ai.EmbeddingRequest) (*ai.EmbeddingResponse, error) {
+	vocabulary := []string{"token", "retry", "parse", "config"}
+	resp := &ai.EmbeddingResponse{Model: req.Model}
+	for _, text := range req.Input {
+		vector := make([]float32, len(vocabulary))
+		for i, word := range vocabulary {
+			vector[i] = float32(strings.Count(strings.ToLower(text), word))
+		}
+		resp.Embeddings = append(resp.Embeddings, vector)
+	}
+	return resp, nil
+}
+
+func TestCodeSearchTool_Execute(t *testing.T) {
+	tempDir := t.TempDir()
+	source := `package client
+
+// RetryRequest retries a failed request with backoff
+func RetryRequest() {}
+
+// ParseConfig parses the config file
+func ParseConfig() {}
+`
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "client.go"), []byte(source), 0644))
+
+	tool := NewCodeSearchTool(tempDir, keywordEmbedder{}, index.Options{Model: "test-embed"})
+	assert.Equal(t, "code_search", tool.GetDefinition().Function.Name)
+
+	result, err := tool.Execute(context.Background(), `{"query": "where do we retry", "max_results": 1}`)
+	require.NoError(t, err)
+	assert.Contains(t, result, "client.go:3-4 (RetryRequest)")
+	assert.NotContains(t, result, "ParseConfig")
+
+	// The index is stored in the project
+	_, err = os.Stat(filepath.Join(tempDir, index.Dir, "index.json"))
+	require.NoError(t, err)
+
+	_, err = tool.Execute(context.Background(), `{"query": ""}`)
+	assert.Error(t, err)
+}
@@ -0,0 +1,567 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// patchFuzz is how many context lines at each end of a hunk may be
+// ignored when the hunk doesn't apply as written, as with patch -F2
+const patchFuzz = 2
+
+// Edit is one exact search/replace block
+type Edit struct {
+	OldText    string `json:"old_text"`
+	NewText    string `json:"new_text"`
+	ReplaceAll bool   `json:"replace_all,omitempty"`
+}
+
+// FileChange is the content of a file before and after an edit, patch or
+// insert_at_line operation
+type FileChange struct {
+	Path    string
+	Before  string
+	After   string
+	Existed bool
+}
+
+// Diff returns the change as a unified diff
+func (c *FileChange) Diff() string {
+	before := difflib.SplitLines(c.Before)
+	if c.Before == "" {
+		before = nil
+	}
+	diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        before,
+		B:        difflib.SplitLines(c.After),
+		FromFile: "a/" + c.Path,
+		ToFile:   "b/" + c.Path,
+		Context:  3,
+	})
+	return diff
+}
+
+// Stats returns the number of lines added and removed
+func (c *FileChange) Stats() (added, removed int) {
+	for _, line := range strings.Split(c.Diff(), "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+		case strings.HasPrefix(line, "+"):
+			added++
+		case strings.HasPrefix(line, "-"):
+			removed++
+		}
+	}
+	return added, removed
+}
+
+// editParams are the arguments of the operations that change part of a file
+type editParams struct {
+	Type       string `json:"type"`
+	Path       string `json:"path"`
+	Content    string `json:"content"`
+	OldText    string `json:"old_text"`
+	NewText    string `json:"new_text"`
+	ReplaceAll bool   `json:"replace_all"`
+	Edits      []Edit `json:"edits"`
+	Diff       string `json:"diff"`
+	Line       int    `json:"line"`
+}
+
+// PreviewFileChange works out what an edit, patch or insert_at_line call
+// would do to its file without writing it, for approval previews
+func PreviewFileChange(basePath, args string) (*FileChange, error) {
+	var params editParams
+	if err := json.Unmarshal([]byte(args), &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	return NewFileTool(basePath).planChange(params)
+}
+
+// planChange reads the file and applies the operation in memory
+func (f *FileTool) planChange(params editParams) (*FileChange, error) {
+	if params.Path == "" {
+		return nil, fmt.Errorf("path is required for %s", params.Type)
+	}
+	fullPath, err := f.sanitizePath(params.Path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path: %w", err)
+	}
+
+	change := &FileChange{Path: params.Path}
+	data, err := os.ReadFile(fullPath)
+	switch {
+	case err == nil:
+		change.Existed = true
+		change.Before = string(data)
+	case errors.Is(err, os.ErrNotExist) && params.Type == "patch":
+		// A patch from /dev/null creates the file
+	case errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("%s does not exist; use write to create it", params.Path)
+	default:
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	switch params.Type {
+	case "edit":
+		edits := params.Edits
+		if params.OldText != "" || params.NewText != "" {
+			edits = append([]Edit{{OldText: params.OldText, NewText: params.NewText, ReplaceAll: params.ReplaceAll}}, edits...)
+		}
+		if len(edits) == 0 {
+			return nil, fmt.Errorf("edit needs old_text and new_text, or a list of edits")
+		}
+		change.After, err = withLineEndings(change.Before, func(content string) (string, error) {
+			return applyEdits(content, edits)
+		})
+	case "patch":
+		if strings.TrimSpace(params.Diff) == "" {
+			return nil, fmt.Errorf("patch needs a unified diff in diff")
+		}
+		if !change.Existed && !strings.Contains(params.Diff, "/dev/null") {
+			return nil, fmt.Errorf("%s does not exist; use write to create it, or a diff from /dev/null", params.Path)
+		}
+		change.After, err = withLineEndings(change.Before, func(content string) (string, error) {
+			return applyPatch(content, params.Diff)
+		})
+	case "insert_at_line":
+		change.After, err = withLineEndings(change.Before, func(content string) (string, error) {
+			return insertAtLine(content, params.Line, params.Content)
+		})
+	default:
+		return nil, fmt.Errorf("unknown operation type: %s", params.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if change.After == change.Before {
+		return nil, fmt.Errorf("%s leaves %s unchanged", params.Type, params.Path)
+	}
+	return change, nil
+}
+
+// applyChange plans the operation and writes the result
+func (f *FileTool) applyChange(params editParams) (string, error) {
+	change, err := f.planChange(params)
+	if err != nil {
+		return "", err
+	}
+	fullPath, _ := f.sanitizePath(params.Path)
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(fullPath); err == nil {
+		if info.Mode()&0200 == 0 {
+			return "", fmt.Errorf("file is read-only")
+		}
+		mode = info.Mode().Perm()
+	}
+	if err := f.save(fullPath); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(fullPath, []byte(change.After), mode); err != nil {
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+
+	added, removed := change.Stats()
+	verb := map[string]string{"edit": "Edited", "patch": "Patched", "insert_at_line": "Inserted into"}[params.Type]
+	return fmt.Sprintf("%s %s (+%d -%d lines)", verb, params.Path, added, removed), nil
+}
+
+// withLineEndings runs apply on content with CRLF line endings turned into
+// LF, so model-written text matches, and turns them back afterwards
+func withLineEndings(content string, apply func(string) (string, error)) (string, error) {
+	crlf := strings.Contains(content, "\r\n")
+	if crlf {
+		content = strings.ReplaceAll(content, "\r\n", "\n")
+	}
+	result, err := apply(content)
+	if err != nil || !crlf {
+		return result, err
+	}
+	return strings.ReplaceAll(result, "\n", "\r\n"), nil
+}
+
+// applyEdits applies search/replace blocks in order. Each old_text must
+// match exactly once unless replace_all is set; if any edit fails, none
+// are applied.
+func applyEdits(content string, edits []Edit) (string, error) {
+	for i, e := range edits {
+		n := i + 1
+		if e.OldText == "" {
+			return "", fmt.Errorf("edit %d: old_text is empty; use insert_at_line to add text without replacing any", n)
+		}
+		e.OldText = strings.ReplaceAll(e.OldText, "\r\n", "\n")
+		e.NewText = strings.ReplaceAll(e.NewText, "\r\n", "\n")
+
+		count := strings.Count(content, e.OldText)
+		switch {
+		case count == 0:
+			msg := fmt.Sprintf("edit %d: old_text was not found. It must match the file exactly, including whitespace and indentation", n)
+			if line := fuzzyLine(content, e.OldText); line > 0 {
+				msg += fmt.Sprintf("; text at line %d matches apart from whitespace", line)
+			}
+			if i > 0 {
+				msg += "; earlier edits in this call have already been applied to the text it is matched against"
+			}
+			return "", errors.New(msg + ". Read the file again and copy the text to replace")
+		case count > 1 && !e.ReplaceAll:
+			return "", fmt.Errorf("edit %d: old_text matches %d places (lines %s); include more surrounding lines to make it unique, or set replace_all",
+				n, count, joinInts(matchLines(content, e.OldText)))
+		}
+
+		if e.ReplaceAll {
+			content = strings.ReplaceAll(content, e.OldText, e.NewText)
+		} else {
+			content = strings.Replace(content, e.OldText, e.NewText, 1)
+		}
+	}
+	return content, nil
+}
+
+// matchLines returns the line numbers where text starts in content
+func matchLines(content, text string) []int {
+	var lines []int
+	offset := 0
+	for {
+		i := strings.Index(content[offset:], text)
+		if i < 0 {
+			return lines
+		}
+		lines = append(lines, strings.Count(content[:offset+i], "\n")+1)
+		offset += i + len(text)
+	}
+}
+
+// fuzzyLine returns the line where text's lines appear ignoring leading
+// and trailing whitespace, or 0
+func fuzzyLine(content, text string) int {
+	want := trimmedLines(strings.TrimRight(text, "\n"))
+	have := trimmedLines(content)
+	if i := findLines(have, want, 0); i >= 0 {
+		return i + 1
+	}
+	return 0
+}
+
+func trimmedLines(s string) []string {
+	lines := strings.Split(s, "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	return lines
+}
+
+// insertAtLine inserts text before line, counted from 1; one past the last
+// line appends
+func insertAtLine(content string, line int, text string) (string, error) {
+	if text == "" {
+		return "", fmt.Errorf("insert_at_line needs the text to insert in content")
+	}
+	lines, trailing := splitLines(content)
+	if line < 1 || line > len(lines)+1 {
+		return "", fmt.Errorf("line %d is out of range: the file has %d lines; use 1 to insert at the start or %d to append",
+			line, len(lines), len(lines)+1)
+	}
+	inserted, _ := splitLines(strings.ReplaceAll(text, "\r\n", "\n"))
+	if len(lines) == 0 {
+		trailing = strings.HasSuffix(text, "\n")
+	}
+
+	result := make([]string, 0, len(lines)+len(inserted))
+	result = append(result, lines[:line-1]...)
+	result = append(result, inserted...)
+	result = append(result, lines[line-1:]...)
+	return joinLines(result, trailing), nil
+}
+
+// hunk is one @@ section of a unified diff
+type hunk struct {
+	header string
+	start  int // first old line, counted from 1
+	old    []string
+	new    []string
+	// noNewline is set when the new side ends without a newline
+	noNewline bool
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// parsePatch reads the hunks of a unified diff. File headers are skipped.
+// The line counts in hunk headers decide where a hunk ends only as far as
+// they go, since models often get them wrong: lines past the counts still
+// belong to the hunk unless they start the next file's headers.
+func parsePatch(diff string) ([]hunk, error) {
+	var hunks []hunk
+	var cur *hunk
+	lastNew := false
+	// oldLeft and newLeft count down the lines the header promises
+	oldLeft, newLeft := 0, 0
+	diff = strings.TrimSuffix(strings.ReplaceAll(diff, "\r\n", "\n"), "\n")
+	lines := strings.Split(diff, "\n")
+	for i, line := range lines {
+		if m := hunkHeader.FindStringSubmatch(line); m != nil {
+			hunks = append(hunks, hunk{header: m[0]})
+			cur = &hunks[len(hunks)-1]
+			cur.start, _ = strconv.Atoi(m[1])
+			oldLeft, newLeft = hunkCount(m[2]), hunkCount(m[4])
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		// Inside the counted lines, "--- " is a removed line such as a
+		// "-- comment"; after them it may start the next file
+		if oldLeft <= 0 && newLeft <= 0 && isFileHeader(line, lines[i+1:]) {
+			cur = nil
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "+"):
+			newLeft--
+		case strings.HasPrefix(line, "-"):
+			oldLeft--
+		case strings.HasPrefix(line, " "), line == "":
+			oldLeft--
+			newLeft--
+		}
+		switch {
+		case strings.HasPrefix(line, `\`):
+			// "\ No newline at end of file" after the new side's last line
+			if lastNew {
+				cur.noNewline = true
+			}
+		case strings.HasPrefix(line, "+"):
+			cur.new = append(cur.new, line[1:])
+			lastNew = true
+		case strings.HasPrefix(line, "-"):
+			cur.old = append(cur.old, line[1:])
+			lastNew = false
+		case strings.HasPrefix(line, " "):
+			cur.old = append(cur.old, line[1:])
+			cur.new = append(cur.new, line[1:])
+			lastNew = true
+		case line == "":
+			// An empty context line whose leading space was dropped
+			cur.old = append(cur.old, "")
+			cur.new = append(cur.new, "")
+			lastNew = true
+		default:
+			return nil, fmt.Errorf("invalid diff line %q in hunk %s; lines must start with ' ', '+' or '-'", line, cur.header)
+		}
+	}
+	if len(hunks) == 0 {
+		return nil, fmt.Errorf("diff has no hunks; it needs @@ -start,count +start,count @@ headers")
+	}
+
+	return hunks, nil
+}
+
+// hunkCount reads a hunk header's line count, which is 1 when left out
+func hunkCount(s string) int {
+	if s == "" {
+		return 1
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// isFileHeader reports whether line starts a file's headers: a diff
+// command line, or a "--- " line followed by a "+++ " line
+func isFileHeader(line string, rest []string) bool {
+	switch {
+	case strings.HasPrefix(line, "diff "):
+		return true
+	case strings.HasPrefix(line, "--- "):
+		return len(rest) > 0 && strings.HasPrefix(rest[0], "+++ ")
+	}
+	return false
+}
+
+// applyPatch applies a unified diff. Each hunk is looked for near the line
+// its header names; if its lines aren't there as written it is matched
+// ignoring whitespace and then with up to patchFuzz context lines dropped
+// from each end.
+func applyPatch(content, diff string) (string, error) {
+	hunks, err := parsePatch(diff)
+	if err != nil {
+		return "", err
+	}
+	lines, trailing := splitLines(content)
+	if len(lines) == 0 {
+		trailing = true
+	}
+
+	// offset is how far lines have moved from the header's numbering
+	offset := 0
+	for i, h := range hunks {
+		pos, old, repl, trail, ok := locateHunk(lines, h, h.start-1+offset)
+		if !ok {
+			return "", fmt.Errorf("hunk %d (%s) does not apply: its context and removed lines were not found. Read the file again and regenerate the diff, or use edit", i+1, h.header)
+		}
+		end := pos + len(old)
+		if end == len(lines) && h.noNewline {
+			trailing = false
+		}
+		result := make([]string, 0, len(lines)-len(old)+len(repl))
+		result = append(result, lines[:pos]...)
+		result = append(result, repl...)
+		lines = append(result, lines[end:]...)
+
+		// Line numbers after the hunk move by where it landed and how it
+		// changed the length, counting context that fuzz left out; a pure
+		// insertion comes after its header's line
+		headerEnd := h.start - 1 + len(h.old)
+		if len(h.old) == 0 {
+			headerEnd = h.start
+		}
+		offset = pos + len(repl) + trail - headerEnd
+	}
+	return joinLines(lines, trailing), nil
+}
+
+// locateHunk finds where h applies, nearest to the expected line, and
+// returns the old and new lines to swap there after any fuzz, and how
+// many trailing context lines fuzz left out
+func locateHunk(lines []string, h hunk, expected int) (pos int, old, repl []string, trail int, ok bool) {
+	if len(h.old) == 0 {
+		// A pure insertion goes after the header's line; that is all there
+		// is to go on
+		pos = expected + 1
+		if pos < 0 || pos > len(lines) {
+			return 0, nil, nil, 0, false
+		}
+		return pos, nil, h.new, 0, true
+	}
+
+	for fuzz := 0; fuzz <= patchFuzz; fuzz++ {
+		old, repl, lead, trail := trimContext(h.old, h.new, fuzz)
+		if fuzz > 0 && len(old) == len(h.old) {
+			break
+		}
+		if len(old) == 0 {
+			break
+		}
+		for _, exact := range []bool{true, false} {
+			want, have := old, lines
+			if !exact {
+				want, have = trimAll(old), trimAll(lines)
+			}
+			if pos := nearestMatch(have, want, expected+lead); pos >= 0 {
+				if !exact {
+					// Keep the file's text on the lines left as context
+					repl = keepContext(lines[pos:pos+len(old)], old, repl)
+				}
+				return pos, lines[pos : pos+len(old)], repl, trail, true
+			}
+		}
+	}
+	return 0, nil, nil, 0, false
+}
+
+// trimContext drops up to n context lines from each end of a hunk and
+// says how many it dropped from the start and the end
+func trimContext(old, repl []string, n int) (trimmedOld, trimmedRepl []string, lead, trail int) {
+	for lead < n && len(old) > 0 && len(repl) > 0 && old[0] == repl[0] {
+		old, repl = old[1:], repl[1:]
+		lead++
+	}
+	for trail < n && len(old) > 0 && len(repl) > 0 && old[len(old)-1] == repl[len(repl)-1] {
+		old, repl = old[:len(old)-1], repl[:len(repl)-1]
+		trail++
+	}
+	return old, repl, lead, trail
+}
+
+// keepContext replaces the leading and trailing context of repl with the
+// file's own lines, so a whitespace-fuzzy match doesn't rewrite them
+func keepContext(file, old, repl []string) []string {
+	result := append([]string(nil), repl...)
+	for i := 0; i < len(old) && i < len(repl) && old[i] == repl[i]; i++ {
+		result[i] = file[i]
+	}
+	for i := 1; i <= len(old) && i <= len(repl) && old[len(old)-i] == repl[len(repl)-i]; i++ {
+		result[len(result)-i] = file[len(file)-i]
+	}
+	return result
+}
+
+func trimAll(lines []string) []string {
+	trimmed := make([]string, len(lines))
+	for i, line := range lines {
+		trimmed[i] = strings.TrimSpace(line)
+	}
+	return trimmed
+}
+
+// nearestMatch returns the position of want in lines closest to expected,
+// or -1
+func nearestMatch(lines, want []string, expected int) int {
+	best := -1
+	for pos := findLines(lines, want, 0); pos >= 0; pos = findLines(lines, want, pos+1) {
+		if best < 0 || abs(pos-expected) < abs(best-expected) {
+			best = pos
+		}
+	}
+	return best
+}
+
+// findLines returns the first position at or after from where want
+// appears in lines, or -1
+func findLines(lines, want []string, from int) int {
+	for pos := from; pos+len(want) <= len(lines); pos++ {
+		match := true
+		for j := range want {
+			if lines[pos+j] != want[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return pos
+		}
+	}
+	return -1
+}
+
+// splitLines splits content into lines and reports whether it ended with
+// a newline
+func splitLines(content string) ([]string, bool) {
+	if content == "" {
+		return nil, false
+	}
+	trailing := strings.HasSuffix(content, "\n")
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n"), trailing
+}
+
+func joinLines(lines []string, trailing bool) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	content := strings.Join(lines, "\n")
+	if trailing {
+		content += "\n"
+	}
+	return content
+}
+
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
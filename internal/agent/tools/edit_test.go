@@ -0,0 +1,314 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const goSource = `package main
+
+import "fmt"
+
+func main() {
+	fmt.Println("hello")
+}
+`
+
+func TestFileTool_Edit(t *testing.T) {
+	tempDir := t.TempDir()
+	fileTool := NewFileTool(tempDir)
+	path := filepath.Join(tempDir, "main.go")
+	require.NoError(t, os.WriteFile(path, []byte(goSource), 0600))
+
+	result, err := fileTool.Execute(context.Background(), `{"type": "edit", "path": "main.go", "old_text": "\"hello\"", "new_text": "\"hello, world\""}`)
+	require.NoError(t, err)
+	assert.Equal(t, "Edited main.go (+1 -1 lines)", result)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `fmt.Println("hello, world")`)
+
+	// The file's permissions are kept
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestApplyEdits(t *testing.T) {
+	t.Run("several edits apply in order", func(t *testing.T) {
+		result, err := applyEdits("a b c", []Edit{
+			{OldText: "a", NewText: "x"},
+			{OldText: "x b", NewText: "y"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "y c", result)
+	})
+
+	t.Run("ambiguous match names the lines", func(t *testing.T) {
+		_, err := applyEdits("foo\nbar\nfoo\n", []Edit{{OldText: "foo", NewText: "baz"}})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "matches 2 places (lines 1, 3)")
+	})
+
+	t.Run("replace_all replaces every match", func(t *testing.T) {
+		result, err := applyEdits("foo\nbar\nfoo\n", []Edit{{OldText: "foo", NewText: "baz", ReplaceAll: true}})
+		require.NoError(t, err)
+		assert.Equal(t, "baz\nbar\nbaz\n", result)
+	})
+
+	t.Run("whitespace mismatch is pointed out", func(t *testing.T) {
+		_, err := applyEdits(goSource, []Edit{{OldText: "func main() {\n  fmt.Println(\"hello\")", NewText: "x"}})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "not found")
+		assert.Contains(t, err.Error(), "line 5 matches apart from whitespace")
+	})
+
+	t.Run("empty old_text is rejected", func(t *testing.T) {
+		_, err := applyEdits("abc", []Edit{{NewText: "x"}})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "insert_at_line")
+	})
+}
+
+func TestApplyPatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		diff    string
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "exact",
+			content: goSource,
+			diff: `--- a/main.go
++++ b/main.go
+@@ -5,3 +5,4 @@
+ func main() {
+ 	fmt.Println("hello")
++	fmt.Println("bye")
+ }
+`,
+			want: `package main
+
+import "fmt"
+
+func main() {
+	fmt.Println("hello")
+	fmt.Println("bye")
+}
+`,
+		},
+		{
+			name:    "wrong line numbers and counts",
+			content: goSource,
+			diff: `@@ -40,9 +40,9 @@
+ import "fmt"
+-
++// comment
+ func main() {
+`,
+			want: `package main
+
+import "fmt"
+// comment
+func main() {
+	fmt.Println("hello")
+}
+`,
+		},
+		{
+			name:    "indentation differs",
+			content: goSource,
+			diff: `@@ -5,3 +5,3 @@
+ func main() {
+-    fmt.Println("hello")
++	fmt.Println("hi")
+ }
+`,
+			want: `package main
+
+import "fmt"
+
+func main() {
+	fmt.Println("hi")
+}
+`,
+		},
+		{
+			name:    "stale context is fuzzed away",
+			content: goSource,
+			diff: `@@ -4,4 +4,4 @@
+ // a comment that was never there
+ func main() {
+-	fmt.Println("hello")
++	fmt.Println("hi")
+ }
+`,
+			want: `package main
+
+import "fmt"
+
+func main() {
+	fmt.Println("hi")
+}
+`,
+		},
+		{
+			name:    "two hunks",
+			content: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			diff: `@@ -1,2 +1,3 @@
+ 1
++1.5
+ 2
+@@ -9,2 +10,2 @@
+ 9
+-10
++ten
+`,
+			want: "1\n1.5\n2\n3\n4\n5\n6\n7\n8\n9\nten\n",
+		},
+		{
+			name:    "removed lines that look like file headers",
+			content: "-- setup\nSELECT 1;\n-- old comment\nSELECT 2;\n+++ banner\nSELECT 3;\n",
+			diff: `--- a/query.sql
++++ b/query.sql
+@@ -2,5 +2,4 @@
+ SELECT 1;
+--- old comment
++-- new comment
+ SELECT 2;
+-+++ banner
+ SELECT 3;
+`,
+			want: "-- setup\nSELECT 1;\n-- new comment\nSELECT 2;\nSELECT 3;\n",
+		},
+		{
+			name:    "file headers after a hunk",
+			content: "1\n2\n3\n",
+			diff: `--- a/numbers.txt
++++ b/numbers.txt
+@@ -1,2 +1,2 @@
+-1
++one
+ 2
+--- a/numbers.txt
++++ b/numbers.txt
+@@ -3 +3 @@
+-3
++three
+`,
+			want: "one\n2\nthree\n",
+		},
+		{
+			name: "fuzzed hunk keeps later hunks on their lines",
+			// The first hunk only applies with its stale last context line
+			// left out; the second must then land on the second "k m"
+			content: "a\nb\nc\nX\np\nq\nr\ns\nk\nm\nk\nm\nend\n",
+			diff: `@@ -1,4 +1,5 @@
+ a
++NEW
+ b
+ c
+ Y
+@@ -11,2 +12,2 @@
+ k
+-m
++M
+`,
+			want: "a\nNEW\nb\nc\nX\np\nq\nr\ns\nk\nm\nk\nM\nend\n",
+		},
+		{
+			name:    "new file",
+			content: "",
+			diff: `--- /dev/null
++++ b/notes.txt
+@@ -0,0 +1,2 @@
++first
++second
+`,
+			want: "first\nsecond\n",
+		},
+		{
+			name:    "no newline at end",
+			content: "a\nb\n",
+			diff: `@@ -1,2 +1,2 @@
+ a
+-b
++c
+\ No newline at end of file
+`,
+			want: "a\nc",
+		},
+		{
+			name:    "missing context",
+			content: goSource,
+			diff: `@@ -5,3 +5,3 @@
+ func other() {
+-	return nil
++	return err
+ }
+`,
+			wantErr: "hunk 1 (@@ -5,3 +5,3 @@) does not apply",
+		},
+		{
+			name:    "no hunks",
+			content: goSource,
+			diff:    "just some text",
+			wantErr: "no hunks",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyPatch(tt.content, tt.diff)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestInsertAtLine(t *testing.T) {
+	got, err := insertAtLine("a\nb\n", 2, "x\ny\n")
+	require.NoError(t, err)
+	assert.Equal(t, "a\nx\ny\nb\n", got)
+
+	got, err = insertAtLine("a\nb\n", 3, "c")
+	require.NoError(t, err)
+	assert.Equal(t, "a\nb\nc\n", got)
+
+	_, err = insertAtLine("a\nb\n", 5, "c")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the file has 2 lines; use 1 to insert at the start or 3 to append")
+}
+
+func TestPreviewFileChange(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "notes.txt")
+	require.NoError(t, os.WriteFile(path, []byte("one\r\ntwo\r\n"), 0644))
+
+	change, err := PreviewFileChange(tempDir, `{"type": "insert_at_line", "path": "notes.txt", "line": 2, "content": "one and a half"}`)
+	require.NoError(t, err)
+	// CRLF line endings are kept
+	assert.Equal(t, "one\r\none and a half\r\ntwo\r\n", change.After)
+	assert.Contains(t, change.Diff(), "+one and a half")
+
+	// Nothing is written
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "one\r\ntwo\r\n", string(content))
+
+	_, err = PreviewFileChange(tempDir, `{"type": "edit", "path": "missing.txt", "old_text": "a", "new_text": "b"}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "does not exist; use write to create it")
+}
@@ -12,12 +12,23 @@ import (
 	"time"
 
 	"github.com/hammie/rubrduck/internal/ai"
+	"github.com/hammie/rubrduck/internal/ignore"
 	"github.com/rs/zerolog/log"
 )
 
+// Snapshotter saves a path before a tool changes it, so the change can be
+// undone
+type Snapshotter interface {
+	// Save records a file or directory as it is, or that it is missing
+	Save(path string) error
+	// SaveDir records a missing path where a directory will be created
+	SaveDir(path string) error
+}
+
 // FileTool provides file system operations
 type FileTool struct {
 	basePath string
+	snapshot Snapshotter
 }
 
 // NewFileTool creates a new file tool instance
@@ -27,20 +38,36 @@ func NewFileTool(basePath string) *FileTool {
 	}
 }
 
+// SetSnapshotter makes the tool save each file before changing it
+func (f *FileTool) SetSnapshotter(s Snapshotter) {
+	f.snapshot = s
+}
+
+// save checkpoints path before it is changed
+func (f *FileTool) save(path string) error {
+	if f.snapshot == nil {
+		return nil
+	}
+	if err := f.snapshot.Save(path); err != nil {
+		return fmt.Errorf("failed to checkpoint file: %w", err)
+	}
+	return nil
+}
+
 // GetDefinition returns the tool definition for the AI
 func (f *FileTool) GetDefinition() ai.Tool {
 	return ai.Tool{
 		Type: "function",
 		Function: ai.ToolFunction{
 			Name:        "file_operations",
-			Description: "Perform file system operations including read, write, list, and search",
+			Description: "Perform file system operations including read, write, list, search, move, copy and delete. To change part of an existing file, prefer edit, patch or insert_at_line over rewriting it with write.",
 			Parameters: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
 					"type": map[string]interface{}{
 						"type":        "string",
-						"enum":        []string{"read", "write", "list", "search", "append"},
-						"description": "The type of file operation to perform",
+						"enum":        []string{"read", "stat", "write", "list", "search", "grep", "append", "edit", "patch", "insert_at_line", "move", "copy", "delete", "mkdir"},
+						"description": "The type of file operation to perform. search finds files by name and grep searches file contents; stat reports a file's size, modification time and line count; edit replaces exact text, patch applies a unified diff and insert_at_line adds lines before a line number; move, copy, delete and mkdir manage files and directories. list, search and grep skip files excluded by .gitignore and .rubrduckignore",
 					},
 					"path": map[string]interface{}{
 						"type":        "string",
@@ -48,11 +75,92 @@ func (f *FileTool) GetDefinition() ai.Tool {
 					},
 					"content": map[string]interface{}{
 						"type":        "string",
-						"description": "Content to write to file (only for write operations)",
+						"description": "Content to write to file (for write and append), or the lines to insert (for insert_at_line)",
+					},
+					"old_text": map[string]interface{}{
+						"type":        "string",
+						"description": "Exact text to replace, including whitespace and indentation; it must occur once unless replace_all is set (for edit)",
+					},
+					"new_text": map[string]interface{}{
+						"type":        "string",
+						"description": "Text that replaces old_text (for edit)",
+					},
+					"replace_all": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Replace every occurrence of old_text (for edit)",
+					},
+					"edits": map[string]interface{}{
+						"type":        "array",
+						"description": "Several replacements applied in order, all or none (for edit)",
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"old_text":    map[string]interface{}{"type": "string"},
+								"new_text":    map[string]interface{}{"type": "string"},
+								"replace_all": map[string]interface{}{"type": "boolean"},
+							},
+							"required": []string{"old_text", "new_text"},
+						},
+					},
+					"diff": map[string]interface{}{
+						"type":        "string",
+						"description": "Unified diff for the file with @@ hunk headers and a few lines of context (for patch)",
+					},
+					"line": map[string]interface{}{
+						"type":        "integer",
+						"description": "Line number, from 1, to insert content before; one past the last line appends (for insert_at_line)",
 					},
 					"pattern": map[string]interface{}{
 						"type":        "string",
-						"description": "Search pattern for file search (only for search operations)",
+						"description": "Part of a file name (for search), or a Go regular expression matched against file contents (for grep)",
+					},
+					"include": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Only grep files matching these globs, e.g. *.go or internal/**/*.go (for grep)",
+					},
+					"exclude": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Skip files matching these globs, e.g. *_test.go (for grep)",
+					},
+					"context_lines": map[string]interface{}{
+						"type":        "integer",
+						"description": "Lines of context to show around each match, up to 10 (for grep)",
+					},
+					"max_matches": map[string]interface{}{
+						"type":        "integer",
+						"description": "Most matches to return, up to 1000 (for grep)",
+						"default":     defaultGrepMatches,
+					},
+					"case_insensitive": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Ignore case when matching (for grep)",
+					},
+					"start_line": map[string]interface{}{
+						"type":        "integer",
+						"description": "First line to read, from 1; with start_line or end_line the lines are numbered (for read)",
+					},
+					"end_line": map[string]interface{}{
+						"type":        "integer",
+						"description": "Last line to read, inclusive (for read)",
+					},
+					"max_bytes": map[string]interface{}{
+						"type":        "integer",
+						"description": "Most bytes to return, up to 1048576; longer output is cut with a notice (for read)",
+						"default":     defaultReadBytes,
+					},
+					"destination": map[string]interface{}{
+						"type":        "string",
+						"description": "The new path, including the file or directory name (for move and copy)",
+					},
+					"overwrite": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Replace an existing file at destination (for move and copy)",
+					},
+					"recursive": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Delete a directory that isn't empty, with everything in it (for delete)",
 					},
 					"max_results": map[string]interface{}{
 						"type":        "integer",
@@ -74,6 +182,13 @@ func (f *FileTool) Execute(ctx context.Context, args string) (string, error) {
 		Content    string `json:"content"`
 		Pattern    string `json:"pattern"`
 		MaxResults int    `json:"max_results"`
+		StartLine  int    `json:"start_line"`
+		EndLine    int    `json:"end_line"`
+		MaxBytes   int    `json:"max_bytes"`
+		// Destination, Overwrite and Recursive are for move, copy and delete
+		Destination string `json:"destination"`
+		Overwrite   bool   `json:"overwrite"`
+		Recursive   bool   `json:"recursive"`
 	}
 
 	if err := json.Unmarshal([]byte(args), &params); err != nil {
@@ -93,7 +208,9 @@ func (f *FileTool) Execute(ctx context.Context, args string) (string, error) {
 
 	switch params.Type {
 	case "read":
-		return f.readFile(fullPath)
+		return f.readFile(fullPath, params.StartLine, params.EndLine, params.MaxBytes)
+	case "stat":
+		return f.statFile(fullPath)
 	case "write":
 		return f.writeFile(fullPath, params.Content)
 	case "append":
@@ -102,6 +219,34 @@ func (f *FileTool) Execute(ctx context.Context, args string) (string, error) {
 		return f.listDirectory(fullPath, params.MaxResults)
 	case "search":
 		return f.searchFiles(fullPath, params.Pattern, params.MaxResults)
+	case "grep":
+		var opts grepOptions
+		if err := json.Unmarshal([]byte(args), &opts); err != nil {
+			return "", fmt.Errorf("invalid arguments: %w", err)
+		}
+		return f.grep(ctx, fullPath, opts)
+	case "move", "copy":
+		if params.Destination == "" {
+			return "", fmt.Errorf("%s needs a destination", params.Type)
+		}
+		dst, err := f.sanitizePath(params.Destination)
+		if err != nil {
+			return "", fmt.Errorf("invalid destination: %w", err)
+		}
+		if params.Type == "move" {
+			return f.movePath(fullPath, dst, params.Overwrite)
+		}
+		return f.copyPath(fullPath, dst, params.Overwrite)
+	case "delete":
+		return f.deletePath(fullPath, params.Recursive)
+	case "mkdir":
+		return f.makeDirectory(fullPath)
+	case "edit", "patch", "insert_at_line":
+		var edit editParams
+		if err := json.Unmarshal([]byte(args), &edit); err != nil {
+			return "", fmt.Errorf("invalid arguments: %w", err)
+		}
+		return f.applyChange(edit)
 	default:
 		return "", fmt.Errorf("unknown operation type: %s", params.Type)
 	}
@@ -137,24 +282,6 @@ func (f *FileTool) sanitizePath(path string) (string, error) {
 	return fullPath, nil
 }
 
-// readFile reads the contents of a file
-func (f *FileTool) readFile(path string) (string, error) {
-	log.Debug().Str("path", path).Msg("Reading file")
-
-	content, err := os.ReadFile(path)
-	if err != nil {
-		return "", fmt.Errorf("failed to read file: %w", err)
-	}
-
-	// Check file size to avoid reading huge files
-	if len(content) > 1024*1024 { // 1MB limit
-		return fmt.Sprintf("File too large (%d bytes). Showing first 1KB:\n\n%s",
-			len(content), string(content[:1024])), nil
-	}
-
-	return string(content), nil
-}
-
 // writeFile writes content to a file
 func (f *FileTool) writeFile(path, content string) (string, error) {
 	log.Debug().
@@ -186,6 +313,10 @@ func (f *FileTool) writeFile(path, content string) (string, error) {
 			Msg("Writing large file - this may take time")
 	}
 
+	if err := f.save(path); err != nil {
+		return "", err
+	}
+
 	// Create directory if it doesn't exist
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0755); err != nil {
@@ -234,6 +365,10 @@ func (f *FileTool) appendFile(path, content string) (string, error) {
 		Int("content_size", len(content)).
 		Msg("Appending to file")
 
+	if err := f.save(path); err != nil {
+		return "", err
+	}
+
 	// Create directory if it doesn't exist
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0755); err != nil {
@@ -294,6 +429,17 @@ func (f *FileTool) listDirectory(path string, maxResults int) (string, error) {
 		return "", fmt.Errorf("failed to read directory: %w", err)
 	}
 
+	// Leave out what .gitignore and .rubrduckignore exclude
+	rules := ignore.New(f.basePath)
+	visible := entries[:0]
+	for _, entry := range entries {
+		if !rules.Ignored(filepath.Join(path, entry.Name()), entry.IsDir()) {
+			visible = append(visible, entry)
+		}
+	}
+	hidden := len(entries) - len(visible)
+	entries = visible
+
 	var result strings.Builder
 	result.WriteString(fmt.Sprintf("Contents of %s:\n\n", path))
 
@@ -321,6 +467,9 @@ func (f *FileTool) listDirectory(path string, maxResults int) (string, error) {
 			entry.Name(), size, info.Mode().String()))
 		count++
 	}
+	if hidden > 0 {
+		result.WriteString(fmt.Sprintf("\n(%d ignored entries not shown)\n", hidden))
+	}
 
 	return result.String(), nil
 }
@@ -335,13 +484,14 @@ func (f *FileTool) searchFiles(basePath, pattern string, maxResults int) (string
 
 	var results []string
 	stopErr := errors.New("stop search")
+	rules := ignore.New(f.basePath)
 	err := filepath.WalkDir(basePath, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return nil // Skip files we can't access
 		}
 
-		// Skip hidden files and directories
-		if strings.HasPrefix(filepath.Base(path), ".") {
+		// Skip .git and what .gitignore and .rubrduckignore exclude
+		if rules.Ignored(path, d.IsDir()) {
 			if d.IsDir() {
 				return filepath.SkipDir
 			}
@@ -46,6 +46,44 @@ func TestFileTool_WriteFile(t *testing.T) {
 	assert.Equal(t, content, string(readContent))
 }
 
+// recordingSnapshotter notes the paths saved and their content at the time
+type recordingSnapshotter struct {
+	saved map[string]string
+}
+
+func (r *recordingSnapshotter) Save(path string) error {
+	data, _ := os.ReadFile(path)
+	r.saved[path] = string(data)
+	return nil
+}
+
+func (r *recordingSnapshotter) SaveDir(path string) error {
+	r.saved[path] = "<dir>"
+	return nil
+}
+
+func TestFileTool_SnapshotsBeforeChanging(t *testing.T) {
+	tempDir := t.TempDir()
+	fileTool := NewFileTool(tempDir)
+	snapshots := &recordingSnapshotter{saved: make(map[string]string)}
+	fileTool.SetSnapshotter(snapshots)
+
+	existing := filepath.Join(tempDir, "notes.txt")
+	require.NoError(t, os.WriteFile(existing, []byte("before"), 0644))
+
+	_, err := fileTool.Execute(context.Background(), `{"type": "write", "path": "notes.txt", "content": "after"}`)
+	require.NoError(t, err)
+	_, err = fileTool.Execute(context.Background(), `{"type": "append", "path": "log.txt", "content": "line"}`)
+	require.NoError(t, err)
+	_, err = fileTool.Execute(context.Background(), `{"type": "read", "path": "notes.txt"}`)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		existing:                          "before",
+		filepath.Join(tempDir, "log.txt"): "",
+	}, snapshots.saved)
+}
+
 func TestFileTool_ListDirectory(t *testing.T) {
 	tempDir := t.TempDir()
 	fileTool := NewFileTool(tempDir)
@@ -134,8 +172,8 @@ func TestFileTool_LargeFileHandling(t *testing.T) {
 	args := `{"type": "read", "path": "large.txt"}`
 	result, err := fileTool.Execute(context.Background(), args)
 	require.NoError(t, err)
-	assert.Contains(t, result, "File too large")
-	assert.Contains(t, result, "Showing first 1KB")
+	assert.Contains(t, result, "[Truncated at 102400 bytes: showed lines 1-1 of 1 (file is 2.0 MB)")
+	assert.Less(t, len(result), 110*1024)
 }
 
 func TestFileTool_InvalidArguments(t *testing.T) {
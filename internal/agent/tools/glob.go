@@ -0,0 +1,41 @@
+package tools
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/hammie/rubrduck/internal/ignore"
+)
+
+// globMatcher matches paths against include or exclude globs. A glob with
+// a slash matches the path from the project root; one without matches the
+// file name.
+type globMatcher []*regexp.Regexp
+
+func newGlobMatcher(globs []string) globMatcher {
+	var m globMatcher
+	for _, glob := range globs {
+		glob = strings.TrimPrefix(strings.TrimSpace(glob), "./")
+		if glob == "" {
+			continue
+		}
+		expr := ignore.GlobRegexp(glob)
+		if !strings.Contains(glob, "/") {
+			expr = "(?:.*/)?" + expr
+		}
+		if re, err := regexp.Compile("^" + expr + "$"); err == nil {
+			m = append(m, re)
+		}
+	}
+	return m
+}
+
+// Match reports whether the slash-separated relative path matches any glob
+func (m globMatcher) Match(rel string) bool {
+	for _, re := range m {
+		if re.MatchString(rel) {
+			return true
+		}
+	}
+	return false
+}
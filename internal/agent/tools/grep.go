@@ -0,0 +1,253 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hammie/rubrduck/internal/ignore"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// defaultGrepMatches and maxGrepMatches bound the matches returned
+	defaultGrepMatches = 100
+	maxGrepMatches     = 1000
+	// maxGrepContext bounds the context lines around each match
+	maxGrepContext = 10
+	// maxGrepFileSize skips files too large to be source code
+	maxGrepFileSize = 10 * 1024 * 1024
+	// maxGrepLineLength cuts long lines, such as minified code, in results
+	maxGrepLineLength = 300
+)
+
+// grepOptions are the arguments of a grep operation
+type grepOptions struct {
+	Pattern         string   `json:"pattern"`
+	Include         []string `json:"include"`
+	Exclude         []string `json:"exclude"`
+	ContextLines    int      `json:"context_lines"`
+	MaxMatches      int      `json:"max_matches"`
+	CaseInsensitive bool     `json:"case_insensitive"`
+}
+
+// grepFile is the matches found in one file
+type grepFile struct {
+	path    string
+	lines   []string
+	matches []grepMatch
+}
+
+type grepMatch struct {
+	line int // from 1
+	col  int // from 1, in characters
+}
+
+// grep searches file contents under root for a regular expression. Each
+// match is reported as path:line:col: text, with context lines as
+// path-line- text, as grep prints them.
+func (f *FileTool) grep(ctx context.Context, root string, opts grepOptions) (string, error) {
+	log.Debug().Str("path", root).Str("pattern", opts.Pattern).Msg("Searching file contents")
+
+	if opts.Pattern == "" {
+		return "", fmt.Errorf("grep pattern is required")
+	}
+	expr := opts.Pattern
+	if opts.CaseInsensitive {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return "", fmt.Errorf("invalid pattern: %v; patterns are Go regular expressions, so escape characters such as ( [ . with a backslash to match them literally", err)
+	}
+
+	maxMatches := opts.MaxMatches
+	if maxMatches <= 0 {
+		maxMatches = defaultGrepMatches
+	}
+	if maxMatches > maxGrepMatches {
+		maxMatches = maxGrepMatches
+	}
+	contextLines := opts.ContextLines
+	if contextLines < 0 {
+		contextLines = 0
+	}
+	if contextLines > maxGrepContext {
+		contextLines = maxGrepContext
+	}
+
+	info, err := os.Stat(root)
+	if err != nil {
+		return "", fmt.Errorf("failed to search: %w", err)
+	}
+	rules := ignore.New(f.basePath)
+	include, exclude := newGlobMatcher(opts.Include), newGlobMatcher(opts.Exclude)
+
+	var files []grepFile
+	total := 0
+	capped := false
+	stop := errors.New("stop grep")
+
+	search := func(path string) error {
+		rel, _ := filepath.Rel(f.basePath, path)
+		rel = filepath.ToSlash(rel)
+		if len(include) > 0 && !include.Match(rel) || exclude.Match(rel) {
+			return nil
+		}
+
+		file, err := grepOne(path, rel, re, maxMatches-total)
+		if err != nil || file == nil {
+			return nil // Skip unreadable and binary files
+		}
+		files = append(files, *file)
+		total += len(file.matches)
+		if total >= maxMatches {
+			capped = true
+			return stop
+		}
+		return ctx.Err()
+	}
+
+	if info.IsDir() {
+		err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil // Skip files we can't access
+			}
+			if path != root && rules.Ignored(path, d.IsDir()) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() || !d.Type().IsRegular() {
+				return nil
+			}
+			return search(path)
+		})
+	} else {
+		err = search(root)
+	}
+	if err != nil && !errors.Is(err, stop) {
+		return "", fmt.Errorf("search failed: %w", err)
+	}
+
+	if total == 0 {
+		return fmt.Sprintf("No matches for /%s/ in %s", opts.Pattern, root), nil
+	}
+
+	var result strings.Builder
+	filesNoun := "files"
+	if len(files) == 1 {
+		filesNoun = "file"
+	}
+	result.WriteString(fmt.Sprintf("Found %d matches for /%s/ in %d %s", total, opts.Pattern, len(files), filesNoun))
+	if capped {
+		result.WriteString(fmt.Sprintf(" (stopped at max_matches=%d; narrow the pattern, path or include globs to see the rest)", maxMatches))
+	}
+	result.WriteString(":\n\n")
+	for _, file := range files {
+		writeGrepFile(&result, file, contextLines)
+	}
+	return result.String(), nil
+}
+
+// grepOne returns up to limit matches in the file at path, or nil for
+// binary files, oversized files and files without matches
+func grepOne(path, rel string, re *regexp.Regexp, limit int) (*grepFile, error) {
+	info, err := os.Stat(path)
+	if err != nil || info.Size() > maxGrepFileSize {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReaderSize(file, sniffBytes)
+	sniff, _ := reader.Peek(sniffBytes)
+	if detectEncoding(sniff) != "" {
+		return nil, nil
+	}
+
+	result := &grepFile{path: rel}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), maxGrepFileSize)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		result.lines = append(result.lines, line)
+		if len(result.matches) >= limit {
+			continue
+		}
+		for _, loc := range re.FindAllStringIndex(line, -1) {
+			if len(result.matches) >= limit {
+				break
+			}
+			result.matches = append(result.matches, grepMatch{
+				line: len(result.lines),
+				col:  utf8.RuneCountInString(line[:loc[0]]) + 1,
+			})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(result.matches) == 0 {
+		return nil, nil
+	}
+	return result, nil
+}
+
+// writeGrepFile prints a file's matches with their context, separating
+// groups of lines that aren't adjacent with --
+func writeGrepFile(out *strings.Builder, file grepFile, contextLines int) {
+	// Columns of each matching line; a line may match more than once
+	cols := make(map[int][]int)
+	show := make(map[int]bool)
+	for _, m := range file.matches {
+		cols[m.line] = append(cols[m.line], m.col)
+		for n := m.line - contextLines; n <= m.line+contextLines; n++ {
+			if n >= 1 && n <= len(file.lines) {
+				show[n] = true
+			}
+		}
+	}
+
+	last := 0
+	for n := 1; n <= len(file.lines); n++ {
+		if !show[n] {
+			continue
+		}
+		if contextLines > 0 && last > 0 && n > last+1 {
+			out.WriteString("--\n")
+		}
+		last = n
+
+		text := grepLine(file.lines[n-1])
+		if matchCols, ok := cols[n]; ok {
+			for _, col := range matchCols {
+				fmt.Fprintf(out, "%s:%d:%d: %s\n", file.path, n, col, text)
+			}
+		} else {
+			fmt.Fprintf(out, "%s-%d- %s\n", file.path, n, text)
+		}
+	}
+	if contextLines > 0 {
+		out.WriteString("\n")
+	}
+}
+
+// grepLine shortens a long line for display
+func grepLine(line string) string {
+	if len(line) <= maxGrepLineLength {
+		return line
+	}
+	return truncateUTF8(line, maxGrepLineLength) + " ..."
+}
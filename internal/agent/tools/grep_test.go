@@ -0,0 +1,147 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTree writes files relative to dir, creating their directories
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+func TestFileTool_Grep(t *testing.T) {
+	tempDir := t.TempDir()
+	fileTool := NewFileTool(tempDir)
+	writeTree(t, tempDir, map[string]string{
+		"main.go":      "package main\n\nfunc main() {\n\trun()\n}\n",
+		"run.go":       "package main\n\nfunc run() {}\n",
+		"run_test.go":  "package main\n\nfunc TestRun() { run() }\n",
+		"docs/run.md":  "Call run() to start.\n",
+		"data/img.bin": "run()\x00\x01",
+	})
+
+	result, err := fileTool.Execute(context.Background(), `{"type": "grep", "path": ".", "pattern": "run\\(\\)"}`)
+	require.NoError(t, err)
+	assert.Contains(t, result, "Found 4 matches for /run\\(\\)/ in 4 files")
+	assert.Contains(t, result, "main.go:4:2: \trun()\n")
+	assert.Contains(t, result, "run.go:3:6: func run() {}\n")
+	assert.Contains(t, result, "docs/run.md:1:6: Call run() to start.\n")
+	assert.NotContains(t, result, "img.bin", "binary files are skipped")
+
+	// Include and exclude globs
+	result, err = fileTool.Execute(context.Background(), `{"type": "grep", "path": ".", "pattern": "run", "include": ["*.go"], "exclude": ["*_test.go"]}`)
+	require.NoError(t, err)
+	assert.Contains(t, result, "main.go:4:2:")
+	assert.NotContains(t, result, "run_test.go")
+	assert.NotContains(t, result, "docs/run.md")
+
+	result, err = fileTool.Execute(context.Background(), `{"type": "grep", "path": ".", "pattern": "run", "include": ["docs/**"]}`)
+	require.NoError(t, err)
+	assert.Contains(t, result, "in 1 file:")
+	assert.Contains(t, result, "docs/run.md:1:6:")
+
+	result, err = fileTool.Execute(context.Background(), `{"type": "grep", "path": ".", "pattern": "RUN", "case_insensitive": true, "include": ["run.go"]}`)
+	require.NoError(t, err)
+	assert.Contains(t, result, "run.go:3:6:")
+
+	result, err = fileTool.Execute(context.Background(), `{"type": "grep", "path": ".", "pattern": "nothing here"}`)
+	require.NoError(t, err)
+	assert.Contains(t, result, "No matches for /nothing here/")
+}
+
+func TestFileTool_GrepContext(t *testing.T) {
+	tempDir := t.TempDir()
+	fileTool := NewFileTool(tempDir)
+	writeLines(t, filepath.Join(tempDir, "lines.txt"), 20)
+
+	result, err := fileTool.Execute(context.Background(), `{"type": "grep", "path": "lines.txt", "pattern": "^line (5|7|15)$", "context_lines": 1}`)
+	require.NoError(t, err)
+	assert.Contains(t, result, "lines.txt-4- line 4\n"+
+		"lines.txt:5:1: line 5\n"+
+		"lines.txt-6- line 6\n"+
+		"lines.txt:7:1: line 7\n"+
+		"lines.txt-8- line 8\n"+
+		"--\n"+
+		"lines.txt-14- line 14\n"+
+		"lines.txt:15:1: line 15\n"+
+		"lines.txt-16- line 16\n")
+}
+
+func TestFileTool_GrepMaxMatches(t *testing.T) {
+	tempDir := t.TempDir()
+	fileTool := NewFileTool(tempDir)
+	writeLines(t, filepath.Join(tempDir, "lines.txt"), 50)
+
+	result, err := fileTool.Execute(context.Background(), `{"type": "grep", "path": ".", "pattern": "line", "max_matches": 3}`)
+	require.NoError(t, err)
+	assert.Contains(t, result, "Found 3 matches")
+	assert.Contains(t, result, "stopped at max_matches=3")
+	assert.Contains(t, result, "lines.txt:3:1: line 3\n")
+	assert.NotContains(t, result, "lines.txt:4:")
+}
+
+func TestFileTool_GrepInvalidPattern(t *testing.T) {
+	fileTool := NewFileTool(t.TempDir())
+
+	_, err := fileTool.Execute(context.Background(), `{"type": "grep", "path": ".", "pattern": "run("}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid pattern")
+	assert.Contains(t, err.Error(), "escape")
+
+	_, err = fileTool.Execute(context.Background(), `{"type": "grep", "path": "."}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "grep pattern is required")
+}
+
+func TestFileTool_IgnoreFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	fileTool := NewFileTool(tempDir)
+	writeTree(t, tempDir, map[string]string{
+		".gitignore":             "*.log\nbuild/\n!keep.log\n",
+		".rubrduckignore":        "secrets.txt\n",
+		"app.go":                 "needle\n",
+		"debug.log":              "needle\n",
+		"keep.log":               "needle\n",
+		"secrets.txt":            "needle\n",
+		"build/out.go":           "needle\n",
+		"pkg/.gitignore":         "generated.go\n",
+		"pkg/generated.go":       "needle\n",
+		"pkg/lib.go":             "needle\n",
+		".git/config":            "needle\n",
+		"vendor/build/readme.md": "needle\n",
+	})
+
+	result, err := fileTool.Execute(context.Background(), `{"type": "grep", "path": ".", "pattern": "needle"}`)
+	require.NoError(t, err)
+	assert.Contains(t, result, "app.go:1:1:")
+	assert.Contains(t, result, "keep.log:1:1:")
+	assert.Contains(t, result, "pkg/lib.go:1:1:")
+	assert.Contains(t, result, "Found 3 matches")
+
+	result, err = fileTool.Execute(context.Background(), `{"type": "search", "path": ".", "pattern": "go"}`)
+	require.NoError(t, err)
+	assert.Contains(t, result, "app.go")
+	assert.Contains(t, result, "lib.go")
+	assert.NotContains(t, result, "generated.go")
+	assert.NotContains(t, result, "out.go")
+
+	result, err = fileTool.Execute(context.Background(), `{"type": "list", "path": "."}`)
+	require.NoError(t, err)
+	assert.Contains(t, result, "app.go")
+	assert.Contains(t, result, "keep.log")
+	assert.NotContains(t, result, "debug.log")
+	assert.NotContains(t, result, "secrets.txt")
+	assert.NotContains(t, result, "build/")
+	assert.Contains(t, result, "ignored entries not shown")
+}
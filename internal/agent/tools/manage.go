@@ -0,0 +1,252 @@
+package tools
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// movePath renames a file or directory within the project
+func (f *FileTool) movePath(src, dst string, overwrite bool) (string, error) {
+	log.Debug().Str("path", src).Str("destination", dst).Msg("Moving path")
+
+	info, err := f.checkTransfer(src, dst, overwrite)
+	if err != nil {
+		return "", fmt.Errorf("failed to move: %w", err)
+	}
+
+	// Save both sides, so undo puts the source back and removes the copy
+	if err := f.saveTree(src, src); err != nil {
+		return "", err
+	}
+	if err := f.saveTree(src, dst); err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.Rename(src, dst); err != nil {
+		return "", fmt.Errorf("failed to move: %w", err)
+	}
+
+	if info.IsDir() {
+		return fmt.Sprintf("Moved directory %s to %s", src, dst), nil
+	}
+	return fmt.Sprintf("Moved %s to %s", src, dst), nil
+}
+
+// copyPath copies a file, or a directory and its contents, within the
+// project, keeping file modes
+func (f *FileTool) copyPath(src, dst string, overwrite bool) (string, error) {
+	log.Debug().Str("path", src).Str("destination", dst).Msg("Copying path")
+
+	info, err := f.checkTransfer(src, dst, overwrite)
+	if err != nil {
+		return "", fmt.Errorf("failed to copy: %w", err)
+	}
+	if err := f.saveTree(src, dst); err != nil {
+		return "", err
+	}
+
+	if !info.IsDir() {
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return "", fmt.Errorf("failed to create directory: %w", err)
+		}
+		if err := copyFile(src, dst, info.Mode().Perm()); err != nil {
+			return "", fmt.Errorf("failed to copy: %w", err)
+		}
+		return fmt.Sprintf("Copied %s to %s (%s)", src, dst, formatFileSize(info.Size())), nil
+	}
+
+	files := 0
+	var size int64
+	err = filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, path)
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case d.Type().IsRegular():
+			files++
+			size += info.Size()
+			return copyFile(path, target, info.Mode().Perm())
+		default:
+			return fmt.Errorf("cannot copy %s: not a regular file", path)
+		}
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to copy: %w", err)
+	}
+	return fmt.Sprintf("Copied directory %s to %s (%d files, %s)", src, dst, files, formatFileSize(size)), nil
+}
+
+// deletePath removes a file or directory. A directory that isn't empty is
+// only removed when recursive is set.
+func (f *FileTool) deletePath(path string, recursive bool) (string, error) {
+	log.Debug().Str("path", path).Bool("recursive", recursive).Msg("Deleting path")
+
+	if f.isRoot(path) {
+		return "", fmt.Errorf("refusing to delete the project root")
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to delete: %w", err)
+	}
+
+	files := 0
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read directory: %w", err)
+		}
+		if len(entries) > 0 && !recursive {
+			return "", fmt.Errorf("%s is a directory with %d entries; set recursive to delete it and everything in it", path, len(entries))
+		}
+		filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				files++
+			}
+			return nil
+		})
+	}
+
+	if err := f.saveTree(path, path); err != nil {
+		return "", err
+	}
+	if err := os.RemoveAll(path); err != nil {
+		return "", fmt.Errorf("failed to delete: %w", err)
+	}
+
+	if info.IsDir() {
+		return fmt.Sprintf("Deleted directory %s (%d files)", path, files), nil
+	}
+	return fmt.Sprintf("Deleted %s", path), nil
+}
+
+// makeDirectory creates a directory and any missing parents
+func (f *FileTool) makeDirectory(path string) (string, error) {
+	log.Debug().Str("path", path).Msg("Creating directory")
+
+	info, err := os.Stat(path)
+	if err == nil {
+		if info.IsDir() {
+			return fmt.Sprintf("Directory %s already exists", path), nil
+		}
+		return "", fmt.Errorf("failed to create directory: %s is a file", path)
+	}
+
+	if f.snapshot != nil {
+		if err := f.snapshot.SaveDir(path); err != nil {
+			return "", fmt.Errorf("failed to checkpoint file: %w", err)
+		}
+	}
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+	return fmt.Sprintf("Created directory %s", path), nil
+}
+
+// checkTransfer validates a move or copy from src to dst and returns the
+// source's info. An existing file is only replaced with overwrite set, and
+// a directory is never replaced.
+func (f *FileTool) checkTransfer(src, dst string, overwrite bool) (os.FileInfo, error) {
+	if f.isRoot(src) {
+		return nil, fmt.Errorf("cannot move or copy the project root")
+	}
+	info, err := os.Lstat(src)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() && !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a regular file", src)
+	}
+	if filepath.Clean(src) == filepath.Clean(dst) {
+		return nil, fmt.Errorf("source and destination are the same")
+	}
+	if info.IsDir() && within(src, dst) {
+		return nil, fmt.Errorf("cannot put %s inside itself", src)
+	}
+
+	target, err := os.Lstat(dst)
+	switch {
+	case os.IsNotExist(err):
+		return info, nil
+	case err != nil:
+		return nil, err
+	case target.IsDir():
+		return nil, fmt.Errorf("%s is an existing directory; give the full new path, such as %s", dst, filepath.Join(dst, filepath.Base(src)))
+	case info.IsDir():
+		return nil, fmt.Errorf("%s already exists and is a file", dst)
+	case !overwrite:
+		return nil, fmt.Errorf("%s already exists; set overwrite to replace it", dst)
+	}
+	return info, nil
+}
+
+// saveTree checkpoints the paths a change to the tree at src touches,
+// mapped to the same places under dst: src itself for a delete, or the
+// destination of a copy
+func (f *FileTool) saveTree(src, dst string) error {
+	if f.snapshot == nil {
+		return nil
+	}
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, path)
+		target := filepath.Join(dst, rel)
+		save := f.snapshot.Save
+		if d.IsDir() && target != path {
+			save = f.snapshot.SaveDir
+		}
+		if err := save(target); err != nil {
+			return fmt.Errorf("failed to checkpoint file: %w", err)
+		}
+		return nil
+	})
+}
+
+// isRoot reports whether path is the project root
+func (f *FileTool) isRoot(path string) bool {
+	rel, err := filepath.Rel(f.basePath, path)
+	return err == nil && rel == "."
+}
+
+// within reports whether path is dir or inside it
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// copyFile copies the content of a regular file, creating or replacing dst
+func copyFile(src, dst string, mode fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
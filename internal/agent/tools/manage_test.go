@@ -0,0 +1,201 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hammie/rubrduck/internal/checkpoint"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readTestFile returns a file's content
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestFileTool_Move(t *testing.T) {
+	tempDir := t.TempDir()
+	fileTool := NewFileTool(tempDir)
+	writeTree(t, tempDir, map[string]string{
+		"old.txt":       "content",
+		"taken.txt":     "keep me",
+		"pkg/a/a.go":    "package a\n",
+		"pkg/a/a_test":  "test\n",
+		"other/note.md": "note\n",
+	})
+
+	// Missing parents of the destination are created
+	result, err := fileTool.Execute(context.Background(), `{"type": "move", "path": "old.txt", "destination": "docs/new.txt"}`)
+	require.NoError(t, err)
+	assert.Contains(t, result, "Moved")
+	assert.NoFileExists(t, filepath.Join(tempDir, "old.txt"))
+	assert.Equal(t, "content", readTestFile(t, filepath.Join(tempDir, "docs", "new.txt")))
+
+	// Existing files are only replaced with overwrite
+	_, err = fileTool.Execute(context.Background(), `{"type": "move", "path": "docs/new.txt", "destination": "taken.txt"}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "set overwrite to replace it")
+	assert.Equal(t, "keep me", readTestFile(t, filepath.Join(tempDir, "taken.txt")))
+
+	_, err = fileTool.Execute(context.Background(), `{"type": "move", "path": "docs/new.txt", "destination": "taken.txt", "overwrite": true}`)
+	require.NoError(t, err)
+	assert.Equal(t, "content", readTestFile(t, filepath.Join(tempDir, "taken.txt")))
+
+	// Directories move whole, but never onto or into a directory
+	result, err = fileTool.Execute(context.Background(), `{"type": "move", "path": "pkg/a", "destination": "pkg/b"}`)
+	require.NoError(t, err)
+	assert.Contains(t, result, "Moved directory")
+	assert.FileExists(t, filepath.Join(tempDir, "pkg", "b", "a.go"))
+	assert.NoDirExists(t, filepath.Join(tempDir, "pkg", "a"))
+
+	_, err = fileTool.Execute(context.Background(), `{"type": "move", "path": "pkg/b", "destination": "other"}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "give the full new path, such as")
+
+	_, err = fileTool.Execute(context.Background(), `{"type": "move", "path": "pkg", "destination": "pkg/inner"}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "inside itself")
+
+	_, err = fileTool.Execute(context.Background(), `{"type": "move", "path": "taken.txt"}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "move needs a destination")
+
+	_, err = fileTool.Execute(context.Background(), `{"type": "move", "path": "taken.txt", "destination": "../outside.txt"}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "outside project bounds")
+}
+
+func TestFileTool_Copy(t *testing.T) {
+	tempDir := t.TempDir()
+	fileTool := NewFileTool(tempDir)
+	writeTree(t, tempDir, map[string]string{
+		"src/main.go":     "package main\n",
+		"src/lib/lib.go":  "package lib\n",
+		"scripts/run.txt": "run\n",
+	})
+	require.NoError(t, os.Chmod(filepath.Join(tempDir, "scripts", "run.txt"), 0755))
+
+	result, err := fileTool.Execute(context.Background(), `{"type": "copy", "path": "scripts/run.txt", "destination": "scripts/run2.txt"}`)
+	require.NoError(t, err)
+	assert.Contains(t, result, "Copied")
+	info, err := os.Stat(filepath.Join(tempDir, "scripts", "run2.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+	assert.FileExists(t, filepath.Join(tempDir, "scripts", "run.txt"))
+
+	result, err = fileTool.Execute(context.Background(), `{"type": "copy", "path": "src", "destination": "backup/src"}`)
+	require.NoError(t, err)
+	assert.Contains(t, result, "Copied directory")
+	assert.Contains(t, result, "2 files")
+	assert.Equal(t, "package lib\n", readTestFile(t, filepath.Join(tempDir, "backup", "src", "lib", "lib.go")))
+	assert.FileExists(t, filepath.Join(tempDir, "src", "main.go"))
+
+	_, err = fileTool.Execute(context.Background(), `{"type": "copy", "path": "src/main.go", "destination": "src/main.go"}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the same")
+}
+
+func TestFileTool_Delete(t *testing.T) {
+	tempDir := t.TempDir()
+	fileTool := NewFileTool(tempDir)
+	writeTree(t, tempDir, map[string]string{
+		"stale.txt":     "old",
+		"build/out.bin": "bin",
+		"build/sub/x":   "x",
+	})
+	require.NoError(t, os.Mkdir(filepath.Join(tempDir, "empty"), 0755))
+
+	result, err := fileTool.Execute(context.Background(), `{"type": "delete", "path": "stale.txt"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "Deleted "+filepath.Join(tempDir, "stale.txt"), result)
+	assert.NoFileExists(t, filepath.Join(tempDir, "stale.txt"))
+
+	_, err = fileTool.Execute(context.Background(), `{"type": "delete", "path": "empty"}`)
+	require.NoError(t, err)
+	assert.NoDirExists(t, filepath.Join(tempDir, "empty"))
+
+	// Directories with contents need recursive
+	_, err = fileTool.Execute(context.Background(), `{"type": "delete", "path": "build"}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "set recursive")
+	assert.DirExists(t, filepath.Join(tempDir, "build"))
+
+	result, err = fileTool.Execute(context.Background(), `{"type": "delete", "path": "build", "recursive": true}`)
+	require.NoError(t, err)
+	assert.Contains(t, result, "(2 files)")
+	assert.NoDirExists(t, filepath.Join(tempDir, "build"))
+
+	for _, path := range []string{"", ".", "sub/.."} {
+		_, err = fileTool.Execute(context.Background(), `{"type": "delete", "path": "`+path+`", "recursive": true}`)
+		require.Error(t, err, path)
+		assert.Contains(t, err.Error(), "refusing to delete the project root")
+	}
+
+	_, err = fileTool.Execute(context.Background(), `{"type": "delete", "path": "missing.txt"}`)
+	assert.Error(t, err)
+}
+
+func TestFileTool_Mkdir(t *testing.T) {
+	tempDir := t.TempDir()
+	fileTool := NewFileTool(tempDir)
+
+	result, err := fileTool.Execute(context.Background(), `{"type": "mkdir", "path": "a/b/c"}`)
+	require.NoError(t, err)
+	assert.Contains(t, result, "Created directory")
+	assert.DirExists(t, filepath.Join(tempDir, "a", "b", "c"))
+
+	result, err = fileTool.Execute(context.Background(), `{"type": "mkdir", "path": "a/b"}`)
+	require.NoError(t, err)
+	assert.Contains(t, result, "already exists")
+
+	writeTree(t, tempDir, map[string]string{"file.txt": "x"})
+	_, err = fileTool.Execute(context.Background(), `{"type": "mkdir", "path": "file.txt"}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is a file")
+}
+
+func TestFileTool_ManageOperationsCanBeUndone(t *testing.T) {
+	tempDir := t.TempDir()
+	writeTree(t, tempDir, map[string]string{
+		"keep.txt":          "keep",
+		"old/name.txt":      "name",
+		"old/deep/file.txt": "deep",
+		"lib/util.go":       "package lib\n",
+		"victim.txt":        "victim",
+	})
+	fileTool := NewFileTool(tempDir)
+	checkpoints, err := checkpoint.Open(t.TempDir(), tempDir, 10)
+	require.NoError(t, err)
+	fileTool.SetSnapshotter(checkpoints)
+
+	require.NoError(t, checkpoints.Begin("reorganise"))
+	for _, args := range []string{
+		`{"type": "move", "path": "old", "destination": "new/place"}`,
+		`{"type": "copy", "path": "lib", "destination": "lib2"}`,
+		`{"type": "copy", "path": "keep.txt", "destination": "victim.txt", "overwrite": true}`,
+		`{"type": "delete", "path": "lib", "recursive": true}`,
+		`{"type": "mkdir", "path": "made/here"}`,
+	} {
+		_, err := fileTool.Execute(context.Background(), args)
+		require.NoError(t, err, args)
+	}
+	_, err = checkpoints.End()
+	require.NoError(t, err)
+
+	_, err = checkpoints.Undo()
+	require.NoError(t, err)
+
+	assert.Equal(t, "name", readTestFile(t, filepath.Join(tempDir, "old", "name.txt")))
+	assert.Equal(t, "deep", readTestFile(t, filepath.Join(tempDir, "old", "deep", "file.txt")))
+	assert.Equal(t, "package lib\n", readTestFile(t, filepath.Join(tempDir, "lib", "util.go")))
+	assert.Equal(t, "victim", readTestFile(t, filepath.Join(tempDir, "victim.txt")))
+	assert.NoDirExists(t, filepath.Join(tempDir, "new"))
+	assert.NoDirExists(t, filepath.Join(tempDir, "lib2"))
+	assert.NoDirExists(t, filepath.Join(tempDir, "made"))
+}
@@ -0,0 +1,252 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// defaultReadBytes is how much of a file a read returns unless
+	// max_bytes asks for more, about 25k tokens
+	defaultReadBytes = 100 * 1024
+	// maxReadBytes caps max_bytes
+	maxReadBytes = 1024 * 1024
+	// sniffBytes is how much of a file is checked for binary content
+	sniffBytes = 8 * 1024
+)
+
+// readFile returns a file's text. A whole-file read returns it as is; a
+// read with start_line or end_line numbers the lines. Output stops at
+// maxBytes with a notice saying where to continue, and files that aren't
+// UTF-8 text are summarised instead.
+func (f *FileTool) readFile(path string, startLine, endLine, maxBytes int) (string, error) {
+	log.Debug().
+		Str("path", path).
+		Int("start_line", startLine).
+		Int("end_line", endLine).
+		Msg("Reading file")
+
+	if startLine < 0 || endLine < 0 {
+		return "", fmt.Errorf("start_line and end_line count from 1")
+	}
+	if endLine > 0 && endLine < startLine {
+		return "", fmt.Errorf("end_line %d is before start_line %d", endLine, startLine)
+	}
+	if maxBytes <= 0 {
+		maxBytes = defaultReadBytes
+	}
+	if maxBytes > maxReadBytes {
+		maxBytes = maxReadBytes
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s is a directory; use list to see its contents", path)
+	}
+
+	reader := bufio.NewReaderSize(file, sniffBytes)
+	sniff, _ := reader.Peek(sniffBytes)
+	if kind := detectEncoding(sniff); kind != "" {
+		return binarySummary(path, info, kind, sniff), nil
+	}
+	if bytes.HasPrefix(sniff, []byte("\xef\xbb\xbf")) {
+		reader.Discard(3)
+	}
+
+	numbered := startLine > 0 || endLine > 0
+	if startLine == 0 {
+		startLine = 1
+	}
+
+	var out strings.Builder
+	lineNo, first, last := 0, 0, 0
+	truncated := false
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			lineNo++
+			inRange := lineNo >= startLine && (endLine == 0 || lineNo <= endLine)
+			if inRange && !truncated {
+				text := line
+				if numbered {
+					text = fmt.Sprintf("%6d\t%s", lineNo, line)
+				}
+				switch {
+				case out.Len()+len(text) <= maxBytes:
+					out.WriteString(text)
+					if first == 0 {
+						first = lineNo
+					}
+					last = lineNo
+				case out.Len() == 0:
+					// A single line over the budget is cut
+					out.WriteString(truncateUTF8(text, maxBytes))
+					first, last = lineNo, lineNo
+					truncated = true
+				default:
+					truncated = true
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read file: %w", err)
+		}
+	}
+
+	if startLine > 1 && startLine > lineNo {
+		return "", fmt.Errorf("start_line %d is past the end of the file, which has %d lines", startLine, lineNo)
+	}
+
+	result := out.String()
+	if numbered && !strings.HasSuffix(result, "\n") {
+		result += "\n"
+	}
+	if truncated {
+		if !strings.HasSuffix(result, "\n") {
+			result += "\n"
+		}
+		result += fmt.Sprintf("[Truncated at %d bytes: showed lines %d-%d of %d (file is %s). Read on with start_line=%d, or raise max_bytes up to %d]\n",
+			maxBytes, first, last, lineNo, formatFileSize(info.Size()), last+1, maxReadBytes)
+	} else if numbered && (first > 1 || last < lineNo) {
+		result += fmt.Sprintf("[Lines %d-%d of %d]\n", first, last, lineNo)
+	}
+	return result, nil
+}
+
+// statFile describes a file so the model can plan its reads
+func (f *FileTool) statFile(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	var out strings.Builder
+	out.WriteString(fmt.Sprintf("Path: %s\n", path))
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read directory: %w", err)
+		}
+		out.WriteString("Type: directory\n")
+		out.WriteString(fmt.Sprintf("Entries: %d\n", len(entries)))
+		out.WriteString(fmt.Sprintf("Modified: %s\n", info.ModTime().Format(time.RFC3339)))
+		return out.String(), nil
+	}
+
+	out.WriteString("Type: file\n")
+	out.WriteString(fmt.Sprintf("Size: %d bytes (%s)\n", info.Size(), formatFileSize(info.Size())))
+	out.WriteString(fmt.Sprintf("Modified: %s\n", info.ModTime().Format(time.RFC3339)))
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReaderSize(file, sniffBytes)
+	sniff, _ := reader.Peek(sniffBytes)
+	if kind := detectEncoding(sniff); kind != "" {
+		out.WriteString(fmt.Sprintf("Encoding: %s (%s)\n", kind, http.DetectContentType(sniff)))
+		return out.String(), nil
+	}
+
+	lines, err := countLines(reader)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	out.WriteString("Encoding: UTF-8 text\n")
+	out.WriteString(fmt.Sprintf("Lines: %d\n", lines))
+	return out.String(), nil
+}
+
+// detectEncoding returns a description of content that isn't UTF-8 text,
+// judged from the start of the file, or "" for text
+func detectEncoding(sniff []byte) string {
+	switch {
+	case bytes.HasPrefix(sniff, []byte{0xff, 0xfe, 0, 0}), bytes.HasPrefix(sniff, []byte{0, 0, 0xfe, 0xff}):
+		return "UTF-32 text"
+	case bytes.HasPrefix(sniff, []byte{0xff, 0xfe}), bytes.HasPrefix(sniff, []byte{0xfe, 0xff}):
+		return "UTF-16 text"
+	case bytes.IndexByte(sniff, 0) >= 0:
+		return "binary"
+	}
+
+	// A full sample may end partway through a multi-byte character
+	if len(sniff) == sniffBytes {
+		for i := len(sniff) - 1; i >= 0 && i >= len(sniff)-utf8.UTFMax; i-- {
+			if utf8.RuneStart(sniff[i]) {
+				if !utf8.FullRune(sniff[i:]) {
+					sniff = sniff[:i]
+				}
+				break
+			}
+		}
+	}
+	if !utf8.Valid(sniff) {
+		return "non-UTF-8 text or binary"
+	}
+	return ""
+}
+
+// binarySummary describes a file whose content isn't shown
+func binarySummary(path string, info os.FileInfo, kind string, sniff []byte) string {
+	return fmt.Sprintf("%s is %s, so its content is not shown.\nDetected type: %s\nSize: %d bytes (%s)\nModified: %s\n",
+		path, kind, http.DetectContentType(sniff), info.Size(), formatFileSize(info.Size()), info.ModTime().Format(time.RFC3339))
+}
+
+// countLines counts lines, including a last line without a newline
+func countLines(r io.Reader) (int, error) {
+	buf := make([]byte, 32*1024)
+	lines := 0
+	var last byte = '\n'
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			lines += bytes.Count(buf[:n], []byte{'\n'})
+			last = buf[n-1]
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	if last != '\n' {
+		lines++
+	}
+	return lines, nil
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a character
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,110 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeLines writes a file of n lines, "line 1" to "line n"
+func writeLines(t *testing.T, path string, n int) {
+	t.Helper()
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, "line %d\n", i)
+	}
+	require.NoError(t, os.WriteFile(path, []byte(b.String()), 0644))
+}
+
+func TestFileTool_ReadLineRange(t *testing.T) {
+	tempDir := t.TempDir()
+	fileTool := NewFileTool(tempDir)
+	writeLines(t, filepath.Join(tempDir, "lines.txt"), 100)
+
+	result, err := fileTool.Execute(context.Background(), `{"type": "read", "path": "lines.txt", "start_line": 10, "end_line": 12}`)
+	require.NoError(t, err)
+	assert.Equal(t, "    10\tline 10\n    11\tline 11\n    12\tline 12\n[Lines 10-12 of 100]\n", result)
+
+	// end_line past the end reads to the end
+	result, err = fileTool.Execute(context.Background(), `{"type": "read", "path": "lines.txt", "start_line": 99, "end_line": 500}`)
+	require.NoError(t, err)
+	assert.Equal(t, "    99\tline 99\n   100\tline 100\n[Lines 99-100 of 100]\n", result)
+
+	_, err = fileTool.Execute(context.Background(), `{"type": "read", "path": "lines.txt", "start_line": 101}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "past the end of the file, which has 100 lines")
+
+	_, err = fileTool.Execute(context.Background(), `{"type": "read", "path": "lines.txt", "start_line": 20, "end_line": 10}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "end_line 10 is before start_line 20")
+}
+
+func TestFileTool_ReadBudget(t *testing.T) {
+	tempDir := t.TempDir()
+	fileTool := NewFileTool(tempDir)
+	writeLines(t, filepath.Join(tempDir, "lines.txt"), 100)
+
+	// Whole lines are kept within the budget
+	result, err := fileTool.Execute(context.Background(), `{"type": "read", "path": "lines.txt", "max_bytes": 30}`)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(result, "line 1\nline 2\nline 3\nline 4\n["), result)
+	assert.Contains(t, result, "[Truncated at 30 bytes: showed lines 1-4 of 100")
+	assert.Contains(t, result, "Read on with start_line=5")
+}
+
+func TestFileTool_ReadBinary(t *testing.T) {
+	tempDir := t.TempDir()
+	fileTool := NewFileTool(tempDir)
+
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 64)...)
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "logo.png"), png, 0644))
+	result, err := fileTool.Execute(context.Background(), `{"type": "read", "path": "logo.png"}`)
+	require.NoError(t, err)
+	assert.Contains(t, result, "is binary, so its content is not shown")
+	assert.Contains(t, result, "Detected type: image/png")
+	assert.NotContains(t, result, "PNG")
+
+	utf16 := []byte{0xff, 0xfe, 'h', 0, 'i', 0}
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "notes.txt"), utf16, 0644))
+	result, err = fileTool.Execute(context.Background(), `{"type": "read", "path": "notes.txt"}`)
+	require.NoError(t, err)
+	assert.Contains(t, result, "is UTF-16 text")
+
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "latin1.txt"), []byte("caf\xe9\n"), 0644))
+	result, err = fileTool.Execute(context.Background(), `{"type": "read", "path": "latin1.txt"}`)
+	require.NoError(t, err)
+	assert.Contains(t, result, "is non-UTF-8 text or binary")
+}
+
+func TestDetectEncodingAllowsCutCharacter(t *testing.T) {
+	// The sample may end partway through a multi-byte character
+	sample := []byte(strings.Repeat("a", sniffBytes-1) + "é")
+	assert.Equal(t, "", detectEncoding(sample[:sniffBytes]))
+}
+
+func TestFileTool_Stat(t *testing.T) {
+	tempDir := t.TempDir()
+	fileTool := NewFileTool(tempDir)
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "notes.txt"), []byte("one\ntwo\nthree"), 0644))
+
+	result, err := fileTool.Execute(context.Background(), `{"type": "stat", "path": "notes.txt"}`)
+	require.NoError(t, err)
+	assert.Contains(t, result, "Type: file\n")
+	assert.Contains(t, result, "Size: 13 bytes")
+	assert.Contains(t, result, "Modified: ")
+	assert.Contains(t, result, "Encoding: UTF-8 text\n")
+	assert.Contains(t, result, "Lines: 3\n")
+
+	result, err = fileTool.Execute(context.Background(), `{"type": "stat", "path": "."}`)
+	require.NoError(t, err)
+	assert.Contains(t, result, "Type: directory\nEntries: 1\n")
+
+	_, err = fileTool.Execute(context.Background(), `{"type": "stat", "path": "missing.txt"}`)
+	assert.Error(t, err)
+}
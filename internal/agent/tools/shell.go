@@ -14,9 +14,17 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// CommandTracker notes the files a shell command creates, so they can be
+// removed when the change is undone
+type CommandTracker interface {
+	// Track runs a command, recording the paths that appear while it runs
+	Track(run func() error) error
+}
+
 // ShellTool provides shell command execution capabilities
 type ShellTool struct {
 	basePath       string
+	tracker        CommandTracker
 	allowedCmds    []string
 	blockedCmds    []string
 	timeout        time.Duration
@@ -115,7 +123,18 @@ func (s *ShellTool) Execute(ctx context.Context, args string) (string, error) {
 	defer cancel()
 
 	// Execute command
-	result, err := s.executeCommand(execCtx, params.Command, workDir)
+	var result string
+	run := func() error {
+		var err error
+		result, err = s.executeCommand(execCtx, params.Command, workDir)
+		return err
+	}
+	var err error
+	if s.tracker != nil {
+		err = s.tracker.Track(run)
+	} else {
+		err = run()
+	}
 	if err != nil {
 		return "", fmt.Errorf("command execution failed: %w", err)
 	}
@@ -324,6 +343,11 @@ func (s *ShellTool) createSandboxPolicy(workDir string) sandbox.Policy {
 	return policy
 }
 
+// SetTracker makes the tool record the files each command creates
+func (s *ShellTool) SetTracker(t CommandTracker) {
+	s.tracker = t
+}
+
 // SetAllowedCommands sets the list of allowed commands
 func (s *ShellTool) SetAllowedCommands(cmds []string) {
 	s.allowedCmds = cmds
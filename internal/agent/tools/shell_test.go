@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/hammie/rubrduck/internal/checkpoint"
 	"github.com/hammie/rubrduck/internal/sandbox"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -262,3 +263,31 @@ func TestShellTool_ContextCancellation(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "context canceled")
 }
+
+func TestShellTool_UndoRemovesCreatedFiles(t *testing.T) {
+	skipOnDarwin(t)
+	tempDir := t.TempDir()
+	policy := absTempPolicy(tempDir)
+	policy.AllowedCommands = append(policy.AllowedCommands, "touch")
+	shellTool := NewShellTool(tempDir, policy)
+	checkpoints, err := checkpoint.Open(t.TempDir(), tempDir, 10)
+	require.NoError(t, err)
+	shellTool.SetTracker(checkpoints)
+
+	require.NoError(t, checkpoints.Begin("generate"))
+	// The sandbox runs commands in the process's directory, so name the
+	// file in full
+	made := filepath.Join(tempDir, "made.txt")
+	_, err = shellTool.Execute(context.Background(), `{"command": "touch `+made+`"}`)
+	require.NoError(t, err)
+	require.FileExists(t, made)
+	// Created by the user between commands, not by the agent
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "mine.txt"), []byte("mine"), 0644))
+	_, err = checkpoints.End()
+	require.NoError(t, err)
+
+	_, err = checkpoints.Undo()
+	require.NoError(t, err)
+	assert.NoFileExists(t, made)
+	assert.FileExists(t, filepath.Join(tempDir, "mine.txt"))
+}
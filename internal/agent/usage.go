@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"github.com/hammie/rubrduck/internal/ai"
+	"github.com/hammie/rubrduck/internal/usage"
+	"github.com/rs/zerolog/log"
+)
+
+// logUsage records a response's token usage, including prompt cache hits,
+// and adds its cost to the usage ledger
+func (a *Agent) logUsage(tokens ai.Usage) {
+	if tokens.TotalTokens == 0 && tokens.PromptTokens == 0 && tokens.CompletionTokens == 0 {
+		return
+	}
+	cost := a.model.Cost(tokens)
+	log.Debug().
+		Str("model", a.model.ID).
+		Int("prompt_tokens", tokens.PromptTokens).
+		Int("completion_tokens", tokens.CompletionTokens).
+		Int("cache_read_tokens", tokens.CacheReadTokens).
+		Int("cache_write_tokens", tokens.CacheWriteTokens).
+		Float64("cost", cost).
+		Msg("Token usage")
+
+	if a.spend == nil {
+		return
+	}
+	status, err := a.spend.Add(usage.Record{
+		Provider:         a.config.Provider,
+		Model:            a.model.ID,
+		Mode:             a.mode,
+		PromptTokens:     tokens.PromptTokens,
+		CompletionTokens: tokens.CompletionTokens,
+		CacheReadTokens:  tokens.CacheReadTokens,
+		CacheWriteTokens: tokens.CacheWriteTokens,
+		Cost:             cost,
+	})
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to record usage")
+	}
+	if status.Warning != "" {
+		log.Warn().Str("spending", status.Warning).Msg("Spending limit")
+	}
+}
+
+// SetMode labels following usage records with a mode, e.g. "planning"
+func (a *Agent) SetMode(mode string) {
+	a.mode = mode
+}
+
+// Spending returns the session and daily cost so far and any limit
+// warning; ok is false when usage tracking is off
+func (a *Agent) Spending() (status usage.Status, ok bool) {
+	if a.spend == nil {
+		return usage.Status{}, false
+	}
+	return a.spend.Status(), true
+}
+
+// checkSpending refuses a new turn once a hard spending limit is reached
+func (a *Agent) checkSpending() error {
+	if a.spend == nil {
+		return nil
+	}
+	return a.spend.Check()
+}
@@ -0,0 +1,41 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hammie/rubrduck/internal/config"
+	"github.com/hammie/rubrduck/internal/usage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgentSpendingLimit(t *testing.T) {
+	price := 1000.0
+	ag, recorder := newRecordingAgent(t, "mock-spend", []config.ModelConfig{{
+		ID: "priced-model", Provider: "mock-spend", InputPrice: &price, OutputPrice: &price,
+	}}, "priced-model")
+	dir := t.TempDir()
+	spend, err := usage.NewTracker(dir, usage.Limits{SessionLimit: 0.5})
+	require.NoError(t, err)
+	ag.spend = spend
+	ag.SetMode("building")
+
+	// mockProvider reports one token; at $1000 per million that's $0.001
+	_, err = ag.Chat(context.Background(), "hi")
+	require.NoError(t, err)
+	status, ok := ag.Spending()
+	require.True(t, ok)
+	require.InDelta(t, 0.001, status.SessionCost, 1e-9)
+
+	_, err = spend.Add(usage.Record{Cost: 1})
+	require.NoError(t, err)
+	_, err = ag.Chat(context.Background(), "again")
+	require.ErrorIs(t, err, usage.ErrLimitReached)
+	require.Equal(t, 1, recorder.chatCalls, "no request is sent once the limit is reached")
+
+	records, err := usage.Load(dir, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, "building", records[0].Mode)
+	require.Equal(t, "priced-model", records[0].Model)
+}
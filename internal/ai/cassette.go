@@ -0,0 +1,177 @@
+package ai
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Cassette methods
+const (
+	CassetteChat   = "chat"
+	CassetteStream = "stream"
+)
+
+// CassetteEntry is one line of a cassette: a request and what the provider
+// returned for it. Streams are stored as the chunks the caller received.
+type CassetteEntry struct {
+	// Seq numbers requests in the order they were sent
+	Seq      int                `json:"seq"`
+	Method   string             `json:"method"`
+	Request  *ChatRequest       `json:"request"`
+	Response *ChatResponse      `json:"response,omitempty"`
+	Chunks   []*ChatStreamChunk `json:"chunks,omitempty"`
+	// Error is the error returned by the call, or that ended the stream
+	Error string `json:"error,omitempty"`
+}
+
+// ReadCassette loads a cassette in request order
+func ReadCassette(path string) ([]CassetteEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cassette: %w", err)
+	}
+	defer file.Close()
+
+	var entries []CassetteEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry CassetteEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("cassette %s line %d: %w", path, line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	// Streams are written when they finish, so entries may be out of order
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Seq < entries[j].Seq })
+	return entries, nil
+}
+
+// RecordingProvider wraps a provider and writes every request with its
+// response or streamed chunks to a JSONL cassette, which the replay
+// provider can play back offline
+type RecordingProvider struct {
+	inner Provider
+
+	mu   sync.Mutex
+	file *os.File
+	seq  int
+}
+
+// NewRecordingProvider starts a new cassette at path, replacing any
+// existing file
+func NewRecordingProvider(inner Provider, path string) (*RecordingProvider, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cassette: %w", err)
+	}
+	return &RecordingProvider{inner: inner, file: file}, nil
+}
+
+// Chat forwards the request and records the response
+func (r *RecordingProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	entry := CassetteEntry{Seq: r.nextSeq(), Method: CassetteChat, Request: req}
+	resp, err := r.inner.Chat(ctx, req)
+	entry.Response = resp
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	r.write(&entry)
+	return resp, err
+}
+
+// StreamChat forwards the request and records the chunks as they are
+// received; the entry is written when the stream ends or is closed
+func (r *RecordingProvider) StreamChat(ctx context.Context, req *ChatRequest) (ChatStream, error) {
+	entry := &CassetteEntry{Seq: r.nextSeq(), Method: CassetteStream, Request: req}
+	stream, err := r.inner.StreamChat(ctx, req)
+	if err != nil {
+		entry.Error = err.Error()
+		r.write(entry)
+		return nil, err
+	}
+	return &recordingStream{inner: stream, recorder: r, entry: entry}, nil
+}
+
+// GetName returns the wrapped provider's name
+func (r *RecordingProvider) GetName() string {
+	return r.inner.GetName()
+}
+
+// Unwrap returns the wrapped provider, for callers that need its optional
+// interfaces such as Embedder
+func (r *RecordingProvider) Unwrap() Provider {
+	return r.inner
+}
+
+// Close closes the cassette file
+func (r *RecordingProvider) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+func (r *RecordingProvider) nextSeq() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	return r.seq
+}
+
+// write appends an entry; recording failures never fail the request
+func (r *RecordingProvider) write(entry *CassetteEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.file.Write(append(data, '\n'))
+}
+
+// recordingStream records chunks as the caller receives them
+type recordingStream struct {
+	inner    ChatStream
+	recorder *RecordingProvider
+	entry    *CassetteEntry
+	written  bool
+}
+
+func (s *recordingStream) Recv() (*ChatStreamChunk, error) {
+	chunk, err := s.inner.Recv()
+	if err != nil {
+		if err != io.EOF {
+			s.entry.Error = err.Error()
+		}
+		s.finish()
+		return nil, err
+	}
+	s.entry.Chunks = append(s.entry.Chunks, chunk)
+	return chunk, nil
+}
+
+func (s *recordingStream) Close() error {
+	// A stream closed early is recorded as far as it was read
+	s.finish()
+	return s.inner.Close()
+}
+
+func (s *recordingStream) finish() {
+	if !s.written {
+		s.written = true
+		s.recorder.write(s.entry)
+	}
+}
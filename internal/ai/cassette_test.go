@@ -0,0 +1,68 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+// cassetteProvider streams a canned reply and fails Chat calls
+type cassetteProvider struct {
+	MockProvider
+}
+
+func (p *cassetteProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	return nil, errors.New("rate limited")
+}
+
+func (p *cassetteProvider) StreamChat(ctx context.Context, req *ChatRequest) (ChatStream, error) {
+	return NewResponseStream(&ChatResponse{Choices: []Choice{{Message: Message{Role: "assistant", Content: "Hello"}}}}), nil
+}
+
+func TestRecordingProviderCassette(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.jsonl")
+	recorder, err := NewRecordingProvider(&cassetteProvider{}, path)
+	if err != nil {
+		t.Fatalf("NewRecordingProvider() error = %v", err)
+	}
+
+	// The stream finishes after the chat call, so it is written second
+	streamReq := &ChatRequest{Model: "m", Messages: []Message{{Role: "user", Content: "hi"}}}
+	stream, err := recorder.StreamChat(context.Background(), streamReq)
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+	if _, err := recorder.Chat(context.Background(), &ChatRequest{Model: "m"}); err == nil {
+		t.Fatal("Expected the wrapped provider's error")
+	}
+	for {
+		if _, err := stream.Recv(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Recv() error = %v", err)
+		}
+	}
+	stream.Close()
+	recorder.Close()
+
+	entries, err := ReadCassette(path)
+	if err != nil {
+		t.Fatalf("ReadCassette() error = %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+
+	first, second := entries[0], entries[1]
+	if first.Seq != 1 || first.Method != CassetteStream || first.Request.Messages[0].Content != "hi" {
+		t.Errorf("Expected the stream request first, got %+v", first)
+	}
+	if len(first.Chunks) == 0 || first.Chunks[0].Choices[0].Delta.Content != "Hello" {
+		t.Errorf("Expected recorded chunks, got %+v", first.Chunks)
+	}
+	if second.Seq != 2 || second.Method != CassetteChat || second.Error != "rate limited" {
+		t.Errorf("Expected the failed chat call second, got %+v", second)
+	}
+}
@@ -0,0 +1,282 @@
+package ai
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ModelInfo describes a model's limits, capabilities and pricing
+type ModelInfo struct {
+	// ID is the canonical model name sent to the provider
+	ID string `json:"id"`
+	// Provider is the provider family serving the model ("openai", "anthropic", ...)
+	Provider string `json:"provider"`
+	// Aliases are alternative names that resolve to ID
+	Aliases []string `json:"aliases,omitempty"`
+
+	ContextWindow   int `json:"context_window"`
+	MaxOutputTokens int `json:"max_output_tokens"`
+
+	SupportsTools  bool `json:"supports_tools"`
+	SupportsVision bool `json:"supports_vision"`
+	// SupportsStreamingTools is false for models that can call tools but
+	// not while streaming; callers should fall back to a regular request
+	SupportsStreamingTools bool `json:"supports_streaming_tools"`
+	// SupportsReasoning marks models that accept a thinking budget
+	SupportsReasoning bool `json:"supports_reasoning,omitempty"`
+
+	// Pricing in USD per million tokens; zero for local or unknown models
+	InputPrice  float64 `json:"input_price,omitempty"`
+	OutputPrice float64 `json:"output_price,omitempty"`
+	// Prompt cache pricing; zero bills cached tokens at InputPrice
+	CacheReadPrice  float64 `json:"cache_read_price,omitempty"`
+	CacheWritePrice float64 `json:"cache_write_price,omitempty"`
+
+	// Known is false for placeholder entries returned for unregistered models
+	Known bool `json:"known"`
+}
+
+// CompletionBudget returns the number of completion tokens to request by
+// default: the model's output limit, capped at a quarter of the context
+// window so that the prompt keeps room to grow
+func (m ModelInfo) CompletionBudget() int {
+	budget := m.MaxOutputTokens
+	if quarter := m.ContextWindow / 4; quarter > 0 && quarter < budget {
+		budget = quarter
+	}
+	return budget
+}
+
+// Cost returns the price in USD of a response's token usage. Cache reads
+// and writes are part of PromptTokens and billed at the cache prices.
+func (m ModelInfo) Cost(usage Usage) float64 {
+	readPrice, writePrice := m.CacheReadPrice, m.CacheWritePrice
+	if readPrice == 0 {
+		readPrice = m.InputPrice
+	}
+	if writePrice == 0 {
+		writePrice = m.InputPrice
+	}
+
+	uncached := usage.PromptTokens - usage.CacheReadTokens - usage.CacheWriteTokens
+	if uncached < 0 {
+		uncached = 0
+	}
+	cost := float64(uncached)*m.InputPrice +
+		float64(usage.CacheReadTokens)*readPrice +
+		float64(usage.CacheWriteTokens)*writePrice +
+		float64(usage.CompletionTokens)*m.OutputPrice
+	return cost / 1e6
+}
+
+// Model registry, keyed by canonical ID
+var (
+	modelsMu      sync.RWMutex
+	models        = make(map[string]ModelInfo)
+	modelAliases  = make(map[string]string)
+	defaultModels = make(map[string]string)
+
+	defaultEmbeddingModels = make(map[string]string)
+)
+
+// fallbackLimits are conservative limits for models missing from the
+// registry, by provider family
+var fallbackLimits = map[string]ModelInfo{
+	"ollama": {ContextWindow: 4000, MaxOutputTokens: 1024},
+}
+
+// RegisterModel adds a model to the registry, replacing any existing entry
+// with the same ID
+func RegisterModel(info ModelInfo) {
+	modelsMu.Lock()
+	defer modelsMu.Unlock()
+
+	info.Known = true
+	models[info.ID] = info
+	for _, alias := range info.Aliases {
+		modelAliases[alias] = info.ID
+	}
+}
+
+// SetDefaultModel sets the model used for a provider family when none is configured
+func SetDefaultModel(provider, model string) {
+	modelsMu.Lock()
+	defer modelsMu.Unlock()
+	defaultModels[provider] = model
+}
+
+// DefaultModel returns the default model for a provider family, or "" if
+// the family has none
+func DefaultModel(provider string) string {
+	modelsMu.RLock()
+	defer modelsMu.RUnlock()
+	return defaultModels[provider]
+}
+
+// SetDefaultEmbeddingModel sets the embedding model used by a provider
+// family when an EmbeddingRequest doesn't name one
+func SetDefaultEmbeddingModel(provider, model string) {
+	modelsMu.Lock()
+	defer modelsMu.Unlock()
+	defaultEmbeddingModels[provider] = model
+}
+
+// DefaultEmbeddingModel returns the default embedding model for a provider
+// family, or "" if the family can't embed
+func DefaultEmbeddingModel(provider string) string {
+	modelsMu.RLock()
+	defer modelsMu.RUnlock()
+	return defaultEmbeddingModels[provider]
+}
+
+// LookupModel returns the registry entry for a model ID or alias. Ollama
+// style tags fall back to their base name, so "qwen2.5-coder:7b" matches a
+// "qwen2.5-coder" entry.
+func LookupModel(model string) (ModelInfo, bool) {
+	modelsMu.RLock()
+	defer modelsMu.RUnlock()
+
+	for _, name := range []string{model, strings.TrimSuffix(model, ":latest"), strings.SplitN(model, ":", 2)[0]} {
+		if info, ok := models[name]; ok {
+			return info, true
+		}
+		if info, ok := models[modelAliases[name]]; ok {
+			return info, true
+		}
+	}
+	return ModelInfo{}, false
+}
+
+// ResolveModel returns the name to send to a provider: the family default
+// when model is empty and model unchanged otherwise. Models are never
+// swapped for another vendor's, and aliases are left for the provider to
+// resolve since they may track newer snapshots than the registry.
+func ResolveModel(provider, model string) string {
+	if model == "" {
+		return DefaultModel(provider)
+	}
+	return model
+}
+
+// ModelInfoFor returns the registry entry for model, or a placeholder with
+// conservative limits when the model is unknown. Unknown models are assumed
+// to support tools so that self-hosted models keep working.
+func ModelInfoFor(provider, model string) ModelInfo {
+	model = ResolveModel(provider, model)
+	if info, ok := LookupModel(model); ok {
+		return info
+	}
+
+	info := ModelInfo{
+		ID:                     model,
+		Provider:               provider,
+		ContextWindow:          8000,
+		MaxOutputTokens:        2048,
+		SupportsTools:          true,
+		SupportsStreamingTools: true,
+	}
+	if limits, ok := fallbackLimits[provider]; ok {
+		info.ContextWindow = limits.ContextWindow
+		info.MaxOutputTokens = limits.MaxOutputTokens
+	}
+	return info
+}
+
+// ListRegisteredModels returns the registered models for a provider family,
+// or all models when provider is empty, sorted by provider and ID
+func ListRegisteredModels(provider string) []ModelInfo {
+	modelsMu.RLock()
+	defer modelsMu.RUnlock()
+
+	var result []ModelInfo
+	for _, info := range models {
+		if provider == "" || info.Provider == provider {
+			result = append(result, info)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Provider != result[j].Provider {
+			return result[i].Provider < result[j].Provider
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+// ProviderFamily maps a provider type to the family whose models it serves
+func ProviderFamily(providerType string) string {
+	switch providerType {
+	case "azure":
+		return "openai"
+	default:
+		return providerType
+	}
+}
+
+func init() {
+	SetDefaultModel("openai", "gpt-4")
+	SetDefaultModel("anthropic", "claude-3-5-sonnet-20241022")
+	SetDefaultModel("gemini", "gemini-1.5-pro")
+	SetDefaultModel("ollama", "llama3.2:3b")
+
+	SetDefaultEmbeddingModel("openai", "text-embedding-3-small")
+	SetDefaultEmbeddingModel("gemini", "text-embedding-004")
+	SetDefaultEmbeddingModel("ollama", "nomic-embed-text")
+
+	for _, info := range builtinModels {
+		// List discounts for prompt caching
+		switch info.Provider {
+		case "anthropic":
+			info.CacheReadPrice = info.InputPrice / 10
+			info.CacheWritePrice = info.InputPrice * 5 / 4
+		case "openai":
+			info.CacheReadPrice = info.InputPrice / 2
+		}
+		RegisterModel(info)
+	}
+}
+
+// builtinModels is the default registry. Prices are list prices in USD per
+// million tokens and can be overridden from config.
+var builtinModels = []ModelInfo{
+	// OpenAI
+	{ID: "gpt-4o", Provider: "openai", ContextWindow: 128000, MaxOutputTokens: 16384, SupportsTools: true, SupportsVision: true, SupportsStreamingTools: true, InputPrice: 2.50, OutputPrice: 10.00},
+	{ID: "gpt-4o-mini", Provider: "openai", ContextWindow: 128000, MaxOutputTokens: 16384, SupportsTools: true, SupportsVision: true, SupportsStreamingTools: true, InputPrice: 0.15, OutputPrice: 0.60},
+	{ID: "gpt-4.1", Provider: "openai", ContextWindow: 1047576, MaxOutputTokens: 32768, SupportsTools: true, SupportsVision: true, SupportsStreamingTools: true, InputPrice: 2.00, OutputPrice: 8.00},
+	{ID: "gpt-4.1-mini", Provider: "openai", ContextWindow: 1047576, MaxOutputTokens: 32768, SupportsTools: true, SupportsVision: true, SupportsStreamingTools: true, InputPrice: 0.40, OutputPrice: 1.60},
+	{ID: "gpt-4-turbo", Provider: "openai", ContextWindow: 128000, MaxOutputTokens: 4096, SupportsTools: true, SupportsVision: true, SupportsStreamingTools: true, InputPrice: 10.00, OutputPrice: 30.00},
+	{ID: "gpt-4", Provider: "openai", ContextWindow: 8192, MaxOutputTokens: 8192, SupportsTools: true, SupportsStreamingTools: true, InputPrice: 30.00, OutputPrice: 60.00},
+	{ID: "gpt-3.5-turbo", Provider: "openai", ContextWindow: 16385, MaxOutputTokens: 4096, SupportsTools: true, SupportsStreamingTools: true, InputPrice: 0.50, OutputPrice: 1.50},
+	{ID: "o1", Provider: "openai", ContextWindow: 200000, MaxOutputTokens: 100000, SupportsTools: true, SupportsVision: true, SupportsReasoning: true, InputPrice: 15.00, OutputPrice: 60.00},
+	{ID: "o3-mini", Provider: "openai", ContextWindow: 200000, MaxOutputTokens: 100000, SupportsTools: true, SupportsStreamingTools: true, SupportsReasoning: true, InputPrice: 1.10, OutputPrice: 4.40},
+
+	// Anthropic
+	{ID: "claude-opus-4-20250514", Provider: "anthropic", Aliases: []string{"claude-opus-4-0"}, ContextWindow: 200000, MaxOutputTokens: 32000, SupportsTools: true, SupportsVision: true, SupportsStreamingTools: true, SupportsReasoning: true, InputPrice: 15.00, OutputPrice: 75.00},
+	{ID: "claude-sonnet-4-20250514", Provider: "anthropic", Aliases: []string{"claude-sonnet-4-0"}, ContextWindow: 200000, MaxOutputTokens: 64000, SupportsTools: true, SupportsVision: true, SupportsStreamingTools: true, SupportsReasoning: true, InputPrice: 3.00, OutputPrice: 15.00},
+	{ID: "claude-3-7-sonnet-20250219", Provider: "anthropic", Aliases: []string{"claude-3-7-sonnet-latest"}, ContextWindow: 200000, MaxOutputTokens: 64000, SupportsTools: true, SupportsVision: true, SupportsStreamingTools: true, SupportsReasoning: true, InputPrice: 3.00, OutputPrice: 15.00},
+	{ID: "claude-3-5-sonnet-20241022", Provider: "anthropic", Aliases: []string{"claude-3-5-sonnet-latest"}, ContextWindow: 200000, MaxOutputTokens: 8192, SupportsTools: true, SupportsVision: true, SupportsStreamingTools: true, InputPrice: 3.00, OutputPrice: 15.00},
+	{ID: "claude-3-5-haiku-20241022", Provider: "anthropic", Aliases: []string{"claude-3-5-haiku-latest"}, ContextWindow: 200000, MaxOutputTokens: 8192, SupportsTools: true, SupportsVision: true, SupportsStreamingTools: true, InputPrice: 0.80, OutputPrice: 4.00},
+	{ID: "claude-3-opus-20240229", Provider: "anthropic", Aliases: []string{"claude-3-opus-latest"}, ContextWindow: 200000, MaxOutputTokens: 4096, SupportsTools: true, SupportsVision: true, SupportsStreamingTools: true, InputPrice: 15.00, OutputPrice: 75.00},
+	{ID: "claude-3-sonnet-20240229", Provider: "anthropic", ContextWindow: 200000, MaxOutputTokens: 4096, SupportsTools: true, SupportsVision: true, SupportsStreamingTools: true, InputPrice: 3.00, OutputPrice: 15.00},
+	{ID: "claude-3-haiku-20240307", Provider: "anthropic", ContextWindow: 200000, MaxOutputTokens: 4096, SupportsTools: true, SupportsVision: true, SupportsStreamingTools: true, InputPrice: 0.25, OutputPrice: 1.25},
+
+	// Gemini
+	{ID: "gemini-2.5-pro", Provider: "gemini", ContextWindow: 1048576, MaxOutputTokens: 65536, SupportsTools: true, SupportsVision: true, SupportsStreamingTools: true, SupportsReasoning: true, InputPrice: 1.25, OutputPrice: 10.00},
+	{ID: "gemini-2.5-flash", Provider: "gemini", ContextWindow: 1048576, MaxOutputTokens: 65536, SupportsTools: true, SupportsVision: true, SupportsStreamingTools: true, SupportsReasoning: true, InputPrice: 0.30, OutputPrice: 2.50},
+	{ID: "gemini-2.0-flash", Provider: "gemini", ContextWindow: 1048576, MaxOutputTokens: 8192, SupportsTools: true, SupportsVision: true, SupportsStreamingTools: true, InputPrice: 0.10, OutputPrice: 0.40},
+	{ID: "gemini-1.5-pro", Provider: "gemini", ContextWindow: 2097152, MaxOutputTokens: 8192, SupportsTools: true, SupportsVision: true, SupportsStreamingTools: true, InputPrice: 1.25, OutputPrice: 5.00},
+	{ID: "gemini-1.5-flash", Provider: "gemini", ContextWindow: 1048576, MaxOutputTokens: 8192, SupportsTools: true, SupportsVision: true, SupportsStreamingTools: true, InputPrice: 0.075, OutputPrice: 0.30},
+
+	// Ollama, keyed by base name so any tag matches; context windows are the
+	// model maximums, the server's num_ctx may be lower (the agent trims to
+	// the provider's configured num_ctx when it is set)
+	{ID: "llama3.2", Provider: "ollama", ContextWindow: 131072, MaxOutputTokens: 4096, SupportsTools: true, SupportsStreamingTools: true},
+	{ID: "llama3.1", Provider: "ollama", ContextWindow: 131072, MaxOutputTokens: 4096, SupportsTools: true, SupportsStreamingTools: true},
+	{ID: "qwen2.5-coder", Provider: "ollama", ContextWindow: 32768, MaxOutputTokens: 4096, SupportsTools: true, SupportsStreamingTools: true},
+	{ID: "mistral", Provider: "ollama", ContextWindow: 32768, MaxOutputTokens: 4096, SupportsTools: true, SupportsStreamingTools: true},
+	{ID: "qwen3", Provider: "ollama", ContextWindow: 40960, MaxOutputTokens: 8192, SupportsTools: true, SupportsStreamingTools: true, SupportsReasoning: true},
+	{ID: "deepseek-r1", Provider: "ollama", ContextWindow: 131072, MaxOutputTokens: 8192, SupportsReasoning: true},
+	{ID: "codellama", Provider: "ollama", ContextWindow: 16384, MaxOutputTokens: 4096},
+	{ID: "llava", Provider: "ollama", ContextWindow: 4096, MaxOutputTokens: 2048, SupportsVision: true},
+}
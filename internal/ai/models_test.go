@@ -0,0 +1,98 @@
+package ai
+
+import "testing"
+
+func TestLookupModel(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"gpt-4o", "gpt-4o"},
+		{"claude-3-5-sonnet-latest", "claude-3-5-sonnet-20241022"},
+		{"qwen2.5-coder:7b", "qwen2.5-coder"},
+		{"llama3.2:latest", "llama3.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			info, ok := LookupModel(tt.input)
+			if !ok {
+				t.Fatalf("Expected %s to be registered", tt.input)
+			}
+			if info.ID != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, info.ID)
+			}
+		})
+	}
+
+	if _, ok := LookupModel("no-such-model"); ok {
+		t.Error("Expected unknown model lookup to fail")
+	}
+}
+
+func TestResolveModel(t *testing.T) {
+	if got := ResolveModel("anthropic", ""); got != DefaultModel("anthropic") {
+		t.Errorf("Expected default model, got %s", got)
+	}
+
+	// Other vendors' models are passed through rather than swapped
+	if got := ResolveModel("anthropic", "gpt-4"); got != "gpt-4" {
+		t.Errorf("Expected gpt-4 to pass through, got %s", got)
+	}
+}
+
+func TestModelInfoFor(t *testing.T) {
+	info := ModelInfoFor("openai", "gpt-4")
+	if !info.Known || info.ContextWindow != 8192 {
+		t.Errorf("Expected registered gpt-4 limits, got %+v", info)
+	}
+	if info.CompletionBudget() != 2048 {
+		t.Errorf("Expected budget capped at a quarter of the context, got %d", info.CompletionBudget())
+	}
+
+	unknown := ModelInfoFor("ollama", "my-finetune")
+	if unknown.Known {
+		t.Error("Expected placeholder for unknown model")
+	}
+	if unknown.ContextWindow != 4000 || unknown.MaxOutputTokens != 1024 {
+		t.Errorf("Expected Ollama fallback limits, got %+v", unknown)
+	}
+	if !unknown.SupportsTools {
+		t.Error("Expected unknown models to assume tool support")
+	}
+}
+
+func TestRegisterModel(t *testing.T) {
+	RegisterModel(ModelInfo{ID: "test-model-x", Provider: "test", Aliases: []string{"test-x"}, ContextWindow: 1000, MaxOutputTokens: 100})
+
+	info, ok := LookupModel("test-x")
+	if !ok || info.ID != "test-model-x" {
+		t.Fatalf("Expected alias to resolve, got %+v", info)
+	}
+
+	models := ListRegisteredModels("test")
+	if len(models) != 1 || models[0].ID != "test-model-x" {
+		t.Errorf("Expected 1 test model, got %+v", models)
+	}
+}
+
+func TestModelInfoCost(t *testing.T) {
+	info := ModelInfo{InputPrice: 3, OutputPrice: 15, CacheReadPrice: 0.3, CacheWritePrice: 3.75}
+	usage := Usage{PromptTokens: 1000000, CompletionTokens: 100000, CacheReadTokens: 800000, CacheWriteTokens: 100000}
+
+	// 100k uncached at $3, 800k read at $0.30, 100k written at $3.75, 100k out at $15
+	want := 0.3 + 0.24 + 0.375 + 1.5
+	if got := info.Cost(usage); got < want-1e-9 || got > want+1e-9 {
+		t.Errorf("Expected cost %.4f, got %.4f", want, got)
+	}
+
+	// Without cache prices, cached tokens are billed as input
+	info.CacheReadPrice, info.CacheWritePrice = 0, 0
+	if got := info.Cost(usage); got < 4.5-1e-9 || got > 4.5+1e-9 {
+		t.Errorf("Expected cost 4.5, got %.4f", got)
+	}
+
+	if claude, _ := LookupModel("claude-sonnet-4-20250514"); claude.CacheReadPrice != 0.3 {
+		t.Errorf("Expected Anthropic cache reads at a tenth of the input price, got %v", claude.CacheReadPrice)
+	}
+}
@@ -2,7 +2,9 @@ package ai
 
 import (
 	"context"
+	"errors"
 	"io"
+	"time"
 )
 
 // Provider defines the interface for AI providers
@@ -17,6 +19,57 @@ type Provider interface {
 	GetName() string
 }
 
+// ErrModelNotFound is returned (wrapped) by ModelValidator implementations
+// when the requested model is not available
+var ErrModelNotFound = errors.New("model not found")
+
+// ModelLister is implemented by providers that can enumerate the models
+// available to the configured account or server
+type ModelLister interface {
+	// ListModels returns the models the provider currently offers
+	ListModels(ctx context.Context) ([]RemoteModel, error)
+}
+
+// ModelValidator is implemented by providers that can cheaply check whether
+// a model exists before the first request is made (e.g. local servers)
+type ModelValidator interface {
+	// ValidateModel returns an error if the model is not available
+	ValidateModel(ctx context.Context, model string) error
+}
+
+// Embedder is implemented by providers that can turn text into embedding
+// vectors, e.g. for semantic code search
+type Embedder interface {
+	// Embed returns one vector per input, in input order
+	Embed(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error)
+}
+
+// EmbeddingRequest represents an embedding request. An empty Model selects
+// the provider family's default embedding model (see DefaultEmbeddingModel).
+type EmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// EmbeddingResponse holds the vectors for an EmbeddingRequest
+type EmbeddingResponse struct {
+	Model      string      `json:"model"`
+	Embeddings [][]float32 `json:"embeddings"`
+	Usage      Usage       `json:"usage"`
+}
+
+// RemoteModel describes a model reported by a provider's list endpoint
+type RemoteModel struct {
+	ID         string    `json:"id"`
+	OwnedBy    string    `json:"owned_by,omitempty"`
+	Size       int64     `json:"size,omitempty"`
+	ModifiedAt time.Time `json:"modified_at,omitempty"`
+
+	// Limits, when the list endpoint reports them (Gemini)
+	ContextWindow   int `json:"context_window,omitempty"`
+	MaxOutputTokens int `json:"max_output_tokens,omitempty"`
+}
+
 // ChatRequest represents a chat completion request
 type ChatRequest struct {
 	Model       string    `json:"model"`
@@ -25,6 +78,42 @@ type ChatRequest struct {
 	MaxTokens   int       `json:"max_tokens,omitempty"`
 	Stream      bool      `json:"stream,omitempty"`
 	Tools       []Tool    `json:"tools,omitempty"`
+	// CacheTools marks the tool definitions as a cacheable prefix for
+	// providers with explicit prompt caching (Anthropic)
+	CacheTools bool `json:"cache_tools,omitempty"`
+	// ResponseFormat asks for a JSON reply; see ChatStructured for validation
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+	// ReasoningBudget lets reasoning-capable models think for up to this
+	// many tokens before answering; 0 leaves the provider default. The
+	// reasoning is reported separately from the answer.
+	ReasoningBudget int `json:"reasoning_budget,omitempty"`
+}
+
+// Response format types
+const (
+	// ResponseFormatJSONObject asks for any JSON object
+	ResponseFormatJSONObject = "json_object"
+	// ResponseFormatJSONSchema asks for JSON matching ResponseFormat.Schema
+	ResponseFormatJSONSchema = "json_schema"
+)
+
+// ResponseFormat requests structured output. Providers implement it
+// natively: OpenAI response_format, Gemini responseSchema, Ollama format and
+// Anthropic a forced tool call whose input becomes the message content.
+type ResponseFormat struct {
+	Type string `json:"type"`
+	// Name identifies the schema (OpenAI json_schema name, Anthropic tool
+	// name); defaults to "response"
+	Name   string                 `json:"name,omitempty"`
+	Schema map[string]interface{} `json:"schema,omitempty"`
+}
+
+// SchemaName returns the name to send with the schema
+func (f *ResponseFormat) SchemaName() string {
+	if f.Name == "" {
+		return "response"
+	}
+	return f.Name
 }
 
 // MessagePart represents either text or image content within a message.
@@ -43,6 +132,15 @@ type Message struct {
 	Name       string        `json:"name,omitempty"`
 	ToolCalls  []ToolCall    `json:"tool_calls,omitempty"`
 	ToolCallID string        `json:"tool_call_id,omitempty"`
+	// CacheBreakpoint marks the end of a stable prefix: providers with
+	// explicit prompt caching cache everything up to and including this
+	// message. Providers that cache automatically ignore it.
+	CacheBreakpoint bool `json:"cache_breakpoint,omitempty"`
+	// Reasoning is the model's thinking before an assistant reply. It is
+	// sent back only to providers that require it (Anthropic, with its
+	// ReasoningSignature, when thinking is combined with tool use).
+	Reasoning          string `json:"reasoning,omitempty"`
+	ReasoningSignature string `json:"reasoning_signature,omitempty"`
 }
 
 // Tool represents a function that can be called by the AI
@@ -82,11 +180,15 @@ type Choice struct {
 	FinishReason string  `json:"finish_reason"`
 }
 
-// Usage represents token usage information
+// Usage represents token usage information. PromptTokens counts the whole
+// prompt; the cache fields report how much of it was read from or written
+// to the provider's prompt cache.
 type Usage struct {
 	PromptTokens     int `json:"prompt_tokens"`
 	CompletionTokens int `json:"completion_tokens"`
 	TotalTokens      int `json:"total_tokens"`
+	CacheReadTokens  int `json:"cache_read_tokens,omitempty"`
+	CacheWriteTokens int `json:"cache_write_tokens,omitempty"`
 }
 
 // ChatStream represents a stream of chat responses
@@ -102,6 +204,8 @@ type ChatStream interface {
 type ChatStreamChunk struct {
 	ID      string             `json:"id"`
 	Choices []ChatStreamChoice `json:"choices"`
+	// Usage is set on chunks that report token usage, typically the last one
+	Usage *Usage `json:"usage,omitempty"`
 }
 
 // ChatStreamChoice represents a streamed choice
@@ -116,6 +220,49 @@ type ChatStreamDelta struct {
 	Role      string     `json:"role,omitempty"`
 	Content   string     `json:"content,omitempty"`
 	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// Reasoning carries thinking output, kept apart from Content
+	Reasoning          string `json:"reasoning,omitempty"`
+	ReasoningSignature string `json:"reasoning_signature,omitempty"`
+}
+
+// responseStream replays a complete ChatResponse as a single stream chunk
+type responseStream struct {
+	chunk *ChatStreamChunk
+}
+
+// NewResponseStream replays a non-streaming response as a ChatStream, for
+// backends or models that cannot stream
+func NewResponseStream(resp *ChatResponse) ChatStream {
+	usage := resp.Usage
+	chunk := &ChatStreamChunk{ID: resp.ID, Usage: &usage}
+	for _, choice := range resp.Choices {
+		finishReason := choice.FinishReason
+		chunk.Choices = append(chunk.Choices, ChatStreamChoice{
+			Index: choice.Index,
+			Delta: ChatStreamDelta{
+				Role:               choice.Message.Role,
+				Content:            choice.Message.Content,
+				ToolCalls:          choice.Message.ToolCalls,
+				Reasoning:          choice.Message.Reasoning,
+				ReasoningSignature: choice.Message.ReasoningSignature,
+			},
+			FinishReason: &finishReason,
+		})
+	}
+	return &responseStream{chunk: chunk}
+}
+
+func (s *responseStream) Recv() (*ChatStreamChunk, error) {
+	if s.chunk == nil {
+		return nil, io.EOF
+	}
+	chunk := s.chunk
+	s.chunk = nil
+	return chunk, nil
+}
+
+func (s *responseStream) Close() error {
+	return nil
 }
 
 // ProviderFactory creates a provider instance
@@ -39,8 +39,9 @@ func NewAnthropicProvider(config map[string]interface{}) (ai.Provider, error) {
 	}
 
 	// Create HTTP client with reasonable timeouts
-	httpClient := &http.Client{
-		Timeout: 60 * time.Second,
+	httpClient, err := ai.NewHTTPClient("Anthropic", config, 60*time.Second)
+	if err != nil {
+		return nil, err
 	}
 
 	return &AnthropicProvider{
@@ -97,7 +98,11 @@ func (p *AnthropicProvider) Chat(ctx context.Context, req *ai.ChatRequest) (*ai.
 	}
 
 	// Convert to our format
-	return p.convertResponse(&anthropicResp), nil
+	chatResp := p.convertResponse(&anthropicResp)
+	if req.ResponseFormat != nil {
+		applyResponseTool(chatResp, req.ResponseFormat.SchemaName())
+	}
+	return chatResp, nil
 }
 
 // StreamChat sends a streaming chat completion request
@@ -138,27 +143,92 @@ func (p *AnthropicProvider) StreamChat(ctx context.Context, req *ai.ChatRequest)
 	}
 
 	// Return stream
-	return &anthropicStream{
+	stream := &anthropicStream{
 		reader:  resp.Body,
 		scanner: bufio.NewScanner(resp.Body),
-	}, nil
+	}
+	if req.ResponseFormat != nil {
+		stream.responseTool = req.ResponseFormat.SchemaName()
+	}
+	return stream, nil
 }
 
-// convertRequest converts our request format to Anthropic format
+// convertRequest converts our request format to Anthropic format.
+//
+// Anthropic differs from the OpenAI shape in a few ways: system prompts go in
+// a top-level "system" field, assistant tool calls are "tool_use" content
+// blocks, and tool results are "tool_result" blocks sent in a user message.
 func (p *AnthropicProvider) convertRequest(req *ai.ChatRequest) map[string]interface{} {
-	// Convert messages to Anthropic format
-	messages := make([]map[string]interface{}, len(req.Messages))
+	var system []string
+	var messages []map[string]interface{}
+	var toolNames []string
+	cacheTools, cacheSystem, cacheMessages := anthropicCacheBreakpoints(req)
+	thinkingBudget := anthropicThinkingBudget(req)
+	thinking := thinkingBudget > 0
+
 	for i, msg := range req.Messages {
+		switch msg.Role {
+		case "system":
+			if text := messageText(msg); text != "" {
+				system = append(system, text)
+			}
+			continue
+		case "tool":
+			// Consecutive tool results must share a single user message
+			block := map[string]interface{}{
+				"type":        "tool_result",
+				"tool_use_id": msg.ToolCallID,
+				"content":     messageText(msg),
+			}
+			if cacheMessages[i] {
+				block["cache_control"] = anthropicCacheControl()
+			}
+			if n := len(messages); n > 0 && messages[n-1]["role"] == "user" && isToolResultMessage(messages[n-1]) {
+				messages[n-1]["content"] = append(messages[n-1]["content"].([]map[string]interface{}), block)
+			} else {
+				messages = append(messages, map[string]interface{}{
+					"role":    "user",
+					"content": []map[string]interface{}{block},
+				})
+			}
+			continue
+		}
+
 		m := map[string]interface{}{
 			"role": msg.Role,
 		}
-		if len(msg.Parts) > 0 {
+
+		if msg.Role == "assistant" && (len(msg.ToolCalls) > 0 || (thinking && msg.ReasoningSignature != "")) {
+			var blocks []map[string]interface{}
+			if thinking && msg.ReasoningSignature != "" {
+				// With thinking on, tool use turns must be replayed with
+				// their signed thinking block first
+				blocks = append(blocks, map[string]interface{}{
+					"type":      "thinking",
+					"thinking":  msg.Reasoning,
+					"signature": msg.ReasoningSignature,
+				})
+			}
+			if msg.Content != "" {
+				blocks = append(blocks, map[string]interface{}{"type": "text", "text": msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				blocks = append(blocks, map[string]interface{}{
+					"type":  "tool_use",
+					"id":    call.ID,
+					"name":  call.Function.Name,
+					"input": toolCallInput(call.Function.Arguments),
+				})
+				toolNames = append(toolNames, call.Function.Name)
+			}
+			m["content"] = blocks
+		} else if len(msg.Parts) > 0 {
 			parts := make([]map[string]interface{}, len(msg.Parts))
 			for j, part := range msg.Parts {
 				if part.Type == "image_url" {
 					parts[j] = map[string]interface{}{
 						"type":   "image",
-						"source": map[string]interface{}{"data": part.ImageURL, "media_type": "image/png"},
+						"source": anthropicImageSource(part.ImageURL),
 					}
 				} else {
 					parts[j] = map[string]interface{}{
@@ -171,56 +241,262 @@ func (p *AnthropicProvider) convertRequest(req *ai.ChatRequest) map[string]inter
 		} else {
 			m["content"] = msg.Content
 		}
-		messages[i] = m
+		if cacheMessages[i] {
+			m["content"] = withCacheControl(m["content"])
+		}
+		messages = append(messages, m)
 	}
 
 	anthropicReq := map[string]interface{}{
 		"model":      p.convertModel(req.Model),
 		"messages":   messages,
-		"max_tokens": 2048, // Reduced to leave more room for input context
+		"max_tokens": ai.ModelInfoFor("anthropic", req.Model).CompletionBudget(), // required by the API
 	}
 
-	if req.Temperature > 0 {
+	if cacheSystem && len(system) > 0 {
+		// Cached system prompts must be sent as content blocks
+		blocks := make([]map[string]interface{}, len(system))
+		for i, text := range system {
+			blocks[i] = map[string]interface{}{"type": "text", "text": text}
+		}
+		blocks[len(blocks)-1]["cache_control"] = anthropicCacheControl()
+		anthropicReq["system"] = blocks
+	} else if len(system) > 0 {
+		anthropicReq["system"] = strings.Join(system, "\n\n")
+	}
+	if req.Temperature > 0 && !thinking {
+		// Thinking only runs at the default temperature
 		anthropicReq["temperature"] = req.Temperature
 	}
 	if req.MaxTokens > 0 {
 		anthropicReq["max_tokens"] = req.MaxTokens
 	}
+	if thinking {
+		anthropicReq["thinking"] = map[string]interface{}{"type": "enabled", "budget_tokens": thinkingBudget}
+	}
 	if len(req.Tools) > 0 {
-		anthropicReq["tools"] = p.convertTools(req.Tools)
+		tools := p.convertTools(req.Tools)
+		if cacheTools {
+			tools[len(tools)-1]["cache_control"] = anthropicCacheControl()
+		}
+		anthropicReq["tools"] = tools
+	} else if len(toolNames) > 0 {
+		// Anthropic rejects histories containing tool_use blocks unless tools
+		// are declared, so follow-up requests sent without tools get stubs
+		anthropicReq["tools"] = stubTools(toolNames)
+	}
+
+	// Structured output is a forced call to a tool whose input schema is the
+	// requested one; the tool input is returned as the message content
+	if format := req.ResponseFormat; format != nil && format.Type != "text" {
+		tools, _ := anthropicReq["tools"].([]map[string]interface{})
+		anthropicReq["tools"] = append(tools, anthropicResponseTool(format))
+		anthropicReq["tool_choice"] = map[string]interface{}{"type": "tool", "name": format.SchemaName()}
 	}
 
 	return anthropicReq
 }
 
-// convertModel converts our model names to Anthropic model names
-func (p *AnthropicProvider) convertModel(model string) string {
-	switch model {
-	case "gpt-4":
-		return "claude-3-opus-20240229"
-	case "gpt-3.5-turbo":
-		return "claude-3-sonnet-20240229"
+// anthropicMinThinkingBudget is the smallest thinking budget the API accepts
+const anthropicMinThinkingBudget = 1024
+
+// anthropicThinkingBudget returns the budget_tokens to send, or 0 to leave
+// thinking off. The budget must fit below max_tokens with room for an
+// answer, and thinking can't be combined with a forced tool choice.
+func anthropicThinkingBudget(req *ai.ChatRequest) int {
+	if req.ReasoningBudget <= 0 || req.ResponseFormat != nil {
+		return 0
+	}
+	maxTokens := req.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = ai.ModelInfoFor("anthropic", req.Model).CompletionBudget()
+	}
+	budget := req.ReasoningBudget
+	if limit := maxTokens - anthropicMinThinkingBudget; budget > limit {
+		budget = limit
+	}
+	if budget < anthropicMinThinkingBudget {
+		return 0
+	}
+	return budget
+}
+
+// anthropicResponseTool declares the tool used to return structured output
+func anthropicResponseTool(format *ai.ResponseFormat) map[string]interface{} {
+	schema := format.Schema
+	if format.Type != ai.ResponseFormatJSONSchema || schema == nil {
+		schema = map[string]interface{}{"type": "object"}
+	}
+	return map[string]interface{}{
+		"name":         format.SchemaName(),
+		"description":  "Respond with your answer as structured data matching this schema",
+		"input_schema": schema,
+	}
+}
+
+// applyResponseTool turns the forced structured output tool call into the
+// message content
+func applyResponseTool(resp *ai.ChatResponse, name string) {
+	for i := range resp.Choices {
+		msg := &resp.Choices[i].Message
+		var calls []ai.ToolCall
+		for _, call := range msg.ToolCalls {
+			if call.Function.Name == name {
+				msg.Content = call.Function.Arguments
+				continue
+			}
+			calls = append(calls, call)
+		}
+		msg.ToolCalls = calls
+		if len(calls) == 0 && resp.Choices[i].FinishReason == "tool_calls" {
+			resp.Choices[i].FinishReason = "stop"
+		}
+	}
+}
+
+// maxAnthropicCacheBreakpoints is the number of cache_control blocks the
+// Messages API accepts per request
+const maxAnthropicCacheBreakpoints = 4
+
+// anthropicCacheBreakpoints decides where to place cache_control blocks.
+// Tools and the system prompt come first in the prompt, so they get theirs
+// first; the remaining breakpoints go to the latest marked messages, which
+// cover the longest prefixes.
+func anthropicCacheBreakpoints(req *ai.ChatRequest) (tools, system bool, messages map[int]bool) {
+	budget := maxAnthropicCacheBreakpoints
+	if req.CacheTools && len(req.Tools) > 0 {
+		tools = true
+		budget--
+	}
+	for _, msg := range req.Messages {
+		if msg.Role == "system" && msg.CacheBreakpoint {
+			system = true
+		}
+	}
+	if system {
+		budget--
+	}
+
+	messages = make(map[int]bool)
+	for i := len(req.Messages) - 1; i >= 0 && budget > 0; i-- {
+		if msg := req.Messages[i]; msg.CacheBreakpoint && msg.Role != "system" {
+			messages[i] = true
+			budget--
+		}
+	}
+	return tools, system, messages
+}
+
+// anthropicCacheControl returns the cache_control value for a breakpoint
+func anthropicCacheControl() map[string]interface{} {
+	return map[string]interface{}{"type": "ephemeral"}
+}
+
+// withCacheControl marks the last block of message content as a cache
+// breakpoint, converting plain string content to a text block
+func withCacheControl(content interface{}) interface{} {
+	switch c := content.(type) {
+	case string:
+		if c == "" {
+			return c
+		}
+		return []map[string]interface{}{{"type": "text", "text": c, "cache_control": anthropicCacheControl()}}
+	case []map[string]interface{}:
+		if len(c) > 0 {
+			c[len(c)-1]["cache_control"] = anthropicCacheControl()
+		}
+		return c
 	default:
-		// If it's already an Anthropic model name, return as is
-		if strings.HasPrefix(model, "claude-") {
-			return model
+		return content
+	}
+}
+
+// messageText returns the text of a message, joining text parts if needed
+func messageText(msg ai.Message) string {
+	if msg.Content != "" || len(msg.Parts) == 0 {
+		return msg.Content
+	}
+	var texts []string
+	for _, part := range msg.Parts {
+		if part.Type != "image_url" && part.Text != "" {
+			texts = append(texts, part.Text)
 		}
-		// Default to Claude 3 Sonnet
-		return "claude-3-sonnet-20240229"
 	}
+	return strings.Join(texts, "\n")
+}
+
+// isToolResultMessage reports whether an Anthropic message carries tool results
+func isToolResultMessage(m map[string]interface{}) bool {
+	blocks, ok := m["content"].([]map[string]interface{})
+	return ok && len(blocks) > 0 && blocks[0]["type"] == "tool_result"
+}
+
+// toolCallInput decodes streamed tool arguments into the object Anthropic expects
+func toolCallInput(arguments string) interface{} {
+	input := map[string]interface{}{}
+	if strings.TrimSpace(arguments) == "" {
+		return input
+	}
+	var raw json.RawMessage
+	if err := json.Unmarshal([]byte(arguments), &raw); err != nil {
+		return input
+	}
+	return raw
+}
+
+// anthropicImageSource builds an image source block with the right media type
+func anthropicImageSource(imageURL string) map[string]interface{} {
+	src := parseImageURL(imageURL)
+	if src.URL != "" {
+		return map[string]interface{}{"type": "url", "url": src.URL}
+	}
+	return map[string]interface{}{
+		"type":       "base64",
+		"media_type": src.MediaType,
+		"data":       src.Data,
+	}
+}
+
+// stubTools declares minimal tool definitions for tools referenced in history
+func stubTools(names []string) []map[string]interface{} {
+	seen := make(map[string]bool)
+	var tools []map[string]interface{}
+	for _, name := range names {
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		tools = append(tools, map[string]interface{}{
+			"name":         name,
+			"input_schema": map[string]interface{}{"type": "object"},
+		})
+	}
+	return tools
+}
+
+// convertModel fills in the registry default when no model is set; other
+// names are passed through unchanged
+func (p *AnthropicProvider) convertModel(model string) string {
+	return ai.ResolveModel("anthropic", model)
 }
 
 // convertTools converts our tool format to Anthropic format
 func (p *AnthropicProvider) convertTools(tools []ai.Tool) []map[string]interface{} {
 	anthropicTools := make([]map[string]interface{}, len(tools))
 	for i, tool := range tools {
-		anthropicTools[i] = map[string]interface{}{
-			"name":        tool.Function.Name,
-			"description": tool.Function.Description,
-			"input_schema": map[string]interface{}{
+		// Tool parameters are already a JSON schema object; only bare
+		// property maps need wrapping
+		schema := tool.Function.Parameters
+		if _, ok := schema["type"]; !ok {
+			schema = map[string]interface{}{
 				"type":       "object",
 				"properties": tool.Function.Parameters,
-			},
+			}
+		}
+		anthropicTools[i] = map[string]interface{}{
+			"name":         tool.Function.Name,
+			"description":  tool.Function.Description,
+			"input_schema": schema,
 		}
 	}
 	return anthropicTools
@@ -228,26 +504,49 @@ func (p *AnthropicProvider) convertTools(tools []ai.Tool) []map[string]interface
 
 // convertResponse converts Anthropic response to our format
 func (p *AnthropicProvider) convertResponse(resp *anthropicMessageResponse) *ai.ChatResponse {
-	choices := make([]ai.Choice, len(resp.Content))
-	for i, content := range resp.Content {
-		choices[i] = ai.Choice{
-			Index: i,
-			Message: ai.Message{
-				Role:    "assistant",
-				Content: content.Text,
-			},
-			FinishReason: resp.StopReason,
+	message := ai.Message{Role: "assistant"}
+	for _, content := range resp.Content {
+		switch content.Type {
+		case "text":
+			message.Content += content.Text
+		case "thinking":
+			message.Reasoning += content.Thinking
+			message.ReasoningSignature = content.Signature
+		case "tool_use":
+			call := ai.ToolCall{ID: content.ID, Type: "function"}
+			call.Function.Name = content.Name
+			call.Function.Arguments = "{}"
+			if len(content.Input) > 0 {
+				call.Function.Arguments = string(content.Input)
+			}
+			message.ToolCalls = append(message.ToolCalls, call)
 		}
 	}
 
 	return &ai.ChatResponse{
-		ID:      resp.ID,
-		Choices: choices,
-		Usage: ai.Usage{
-			PromptTokens:     resp.Usage.InputTokens,
-			CompletionTokens: resp.Usage.OutputTokens,
-			TotalTokens:      resp.Usage.InputTokens + resp.Usage.OutputTokens,
+		ID: resp.ID,
+		Choices: []ai.Choice{
+			{
+				Index:        0,
+				Message:      message,
+				FinishReason: convertAnthropicStopReason(resp.StopReason),
+			},
 		},
+		Usage: resp.Usage.toUsage(),
+	}
+}
+
+// convertAnthropicStopReason maps Anthropic stop reasons to OpenAI finish reasons
+func convertAnthropicStopReason(reason string) string {
+	switch reason {
+	case "end_turn", "stop_sequence":
+		return "stop"
+	case "tool_use":
+		return "tool_calls"
+	case "max_tokens":
+		return "length"
+	default:
+		return reason
 	}
 }
 
@@ -264,52 +563,117 @@ type anthropicMessageResponse struct {
 }
 
 type anthropicContent struct {
-	Type string `json:"type"`
-	Text string `json:"text"`
+	Type  string          `json:"type"`
+	Text  string          `json:"text,omitempty"`
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+	// Thinking and Signature are set on thinking blocks
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
 }
 
 type anthropicUsage struct {
-	InputTokens  int `json:"input_tokens"`
-	OutputTokens int `json:"output_tokens"`
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
 }
 
-// anthropicStream implements ai.ChatStream for Anthropic SSE responses
+// toUsage converts Anthropic usage to ai.Usage. Anthropic's input_tokens
+// excludes cached tokens, so they are added back into PromptTokens.
+func (u anthropicUsage) toUsage() ai.Usage {
+	prompt := u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+	return ai.Usage{
+		PromptTokens:     prompt,
+		CompletionTokens: u.OutputTokens,
+		TotalTokens:      prompt + u.OutputTokens,
+		CacheReadTokens:  u.CacheReadInputTokens,
+		CacheWriteTokens: u.CacheCreationInputTokens,
+	}
+}
+
+// anthropicStreamEvent is a single server-sent event from the Messages API
+type anthropicStreamEvent struct {
+	Type         string                    `json:"type"`
+	Index        int                       `json:"index"`
+	Message      *anthropicMessageResponse `json:"message,omitempty"`
+	ContentBlock *anthropicContent         `json:"content_block,omitempty"`
+	Delta        *struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		Thinking    string `json:"thinking"`
+		Signature   string `json:"signature"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta,omitempty"`
+	// Usage is sent on message_delta with the output token count
+	Usage *anthropicUsage `json:"usage,omitempty"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// anthropicStream implements ai.ChatStream for Anthropic SSE responses,
+// translating Messages API events into OpenAI-style stream chunks
 type anthropicStream struct {
 	reader  io.ReadCloser
 	scanner *bufio.Scanner
+
+	messageID string
+	// usage accumulates input tokens from message_start and output tokens
+	// from message_delta
+	usage anthropicUsage
+	// toolBlocks maps content block indexes to the tool_use block they carry
+	toolBlocks map[int]*anthropicToolBlock
+	// responseTool names the structured output tool, whose input is
+	// streamed as content rather than as a tool call
+	responseTool string
+	// toolCalls counts tool_use blocks other than the response tool
+	toolCalls int
+}
+
+// anthropicToolBlock tracks a streamed tool_use block
+type anthropicToolBlock struct {
+	id      string
+	hasArgs bool
+	// response marks the structured output tool
+	response bool
 }
 
 func (s *anthropicStream) Recv() (*ai.ChatStreamChunk, error) {
 	for s.scanner.Scan() {
 		line := strings.TrimSpace(s.scanner.Text())
 
-		// Skip empty lines
-		if line == "" {
+		// Skip empty lines and event names; the payload carries its type
+		if line == "" || !strings.HasPrefix(line, "data: ") {
 			continue
 		}
 
-		// Check for [DONE] marker
-		if line == "data: [DONE]" {
+		data := strings.TrimPrefix(line, "data: ")
+		if data == "" {
+			continue
+		}
+		if data == "[DONE]" {
 			return nil, io.EOF
 		}
 
-		// Parse SSE data line
-		if strings.HasPrefix(line, "data: ") {
-			data := strings.TrimPrefix(line, "data: ")
-
-			// Skip empty data
-			if data == "" {
-				continue
-			}
-
-			// Parse JSON chunk
-			var chunk ai.ChatStreamChunk
-			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
-				// Skip malformed JSON
-				continue
-			}
+		var event anthropicStreamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			// Skip malformed JSON
+			continue
+		}
 
-			return &chunk, nil
+		chunk, done, err := s.handleEvent(&event)
+		if err != nil {
+			return nil, err
+		}
+		if done {
+			return nil, io.EOF
+		}
+		if chunk != nil {
+			return chunk, nil
 		}
 	}
 
@@ -321,6 +685,121 @@ func (s *anthropicStream) Recv() (*ai.ChatStreamChunk, error) {
 	return nil, io.EOF
 }
 
+// handleEvent converts one stream event into a chunk. A nil chunk means the
+// event carried nothing for the caller (e.g. ping)
+func (s *anthropicStream) handleEvent(event *anthropicStreamEvent) (*ai.ChatStreamChunk, bool, error) {
+	switch event.Type {
+	case "message_start":
+		if event.Message != nil {
+			s.messageID = event.Message.ID
+			s.usage = event.Message.Usage
+		}
+		return nil, false, nil
+
+	case "content_block_start":
+		if event.ContentBlock == nil || event.ContentBlock.Type != "tool_use" {
+			return nil, false, nil
+		}
+		if s.toolBlocks == nil {
+			s.toolBlocks = make(map[int]*anthropicToolBlock)
+		}
+		if s.responseTool != "" && event.ContentBlock.Name == s.responseTool {
+			s.toolBlocks[event.Index] = &anthropicToolBlock{id: event.ContentBlock.ID, response: true}
+			return nil, false, nil
+		}
+		s.toolBlocks[event.Index] = &anthropicToolBlock{id: event.ContentBlock.ID}
+		s.toolCalls++
+
+		call := ai.ToolCall{ID: event.ContentBlock.ID, Type: "function"}
+		call.Function.Name = event.ContentBlock.Name
+		return s.chunk(ai.ChatStreamDelta{ToolCalls: []ai.ToolCall{call}}, nil), false, nil
+
+	case "content_block_delta":
+		if event.Delta == nil {
+			return nil, false, nil
+		}
+		switch event.Delta.Type {
+		case "text_delta":
+			if event.Delta.Text == "" {
+				return nil, false, nil
+			}
+			return s.chunk(ai.ChatStreamDelta{Content: event.Delta.Text}, nil), false, nil
+		case "thinking_delta":
+			if event.Delta.Thinking == "" {
+				return nil, false, nil
+			}
+			return s.chunk(ai.ChatStreamDelta{Reasoning: event.Delta.Thinking}, nil), false, nil
+		case "signature_delta":
+			return s.chunk(ai.ChatStreamDelta{ReasoningSignature: event.Delta.Signature}, nil), false, nil
+		case "input_json_delta":
+			block, ok := s.toolBlocks[event.Index]
+			if !ok || event.Delta.PartialJSON == "" {
+				return nil, false, nil
+			}
+			block.hasArgs = true
+			if block.response {
+				return s.chunk(ai.ChatStreamDelta{Content: event.Delta.PartialJSON}, nil), false, nil
+			}
+			call := ai.ToolCall{ID: block.id}
+			call.Function.Arguments = event.Delta.PartialJSON
+			return s.chunk(ai.ChatStreamDelta{ToolCalls: []ai.ToolCall{call}}, nil), false, nil
+		}
+		return nil, false, nil
+
+	case "content_block_stop":
+		// Tools called without arguments never receive an input_json_delta
+		block, ok := s.toolBlocks[event.Index]
+		if !ok || block.hasArgs {
+			return nil, false, nil
+		}
+		block.hasArgs = true
+		if block.response {
+			return s.chunk(ai.ChatStreamDelta{Content: "{}"}, nil), false, nil
+		}
+		call := ai.ToolCall{ID: block.id}
+		call.Function.Arguments = "{}"
+		return s.chunk(ai.ChatStreamDelta{ToolCalls: []ai.ToolCall{call}}, nil), false, nil
+
+	case "message_delta":
+		if event.Delta == nil || event.Delta.StopReason == "" {
+			return nil, false, nil
+		}
+		if event.Usage != nil {
+			s.usage.OutputTokens = event.Usage.OutputTokens
+		}
+		reason := convertAnthropicStopReason(event.Delta.StopReason)
+		if reason == "tool_calls" && s.responseTool != "" && s.toolCalls == 0 {
+			// Only the structured output tool was called
+			reason = "stop"
+		}
+		chunk := s.chunk(ai.ChatStreamDelta{}, &reason)
+		usage := s.usage.toUsage()
+		chunk.Usage = &usage
+		return chunk, false, nil
+
+	case "message_stop":
+		return nil, true, nil
+
+	case "error":
+		if event.Error != nil {
+			return nil, false, fmt.Errorf("Anthropic stream error (%s): %s", event.Error.Type, event.Error.Message)
+		}
+		return nil, false, fmt.Errorf("Anthropic stream error")
+	}
+
+	return nil, false, nil
+}
+
+// chunk wraps a delta in a single-choice stream chunk
+func (s *anthropicStream) chunk(delta ai.ChatStreamDelta, finishReason *string) *ai.ChatStreamChunk {
+	return &ai.ChatStreamChunk{
+		ID: s.messageID,
+		Choices: []ai.ChatStreamChoice{
+			{Index: 0, Delta: delta, FinishReason: finishReason},
+		},
+	}
+}
+
 func (s *anthropicStream) Close() error {
 	return s.reader.Close()
 }
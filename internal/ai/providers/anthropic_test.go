@@ -3,6 +3,8 @@ package providers
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -112,7 +114,7 @@ func TestAnthropicProvider_Chat(t *testing.T) {
 
 	// Test chat request
 	req := &ai.ChatRequest{
-		Model: "gpt-4",
+		Model: "claude-3-opus-20240229",
 		Messages: []ai.Message{
 			{Role: "user", Content: "Hello"},
 		},
@@ -152,10 +154,10 @@ func TestAnthropicProvider_ModelConversion(t *testing.T) {
 		input    string
 		expected string
 	}{
-		{"gpt-4", "claude-3-opus-20240229"},
-		{"gpt-3.5-turbo", "claude-3-sonnet-20240229"},
+		{"", "claude-3-5-sonnet-20241022"},
 		{"claude-3-opus-20240229", "claude-3-opus-20240229"},
-		{"unknown", "claude-3-sonnet-20240229"},
+		{"claude-3-5-sonnet-latest", "claude-3-5-sonnet-latest"},
+		{"gpt-4", "gpt-4"},
 	}
 
 	for _, tt := range tests {
@@ -172,7 +174,7 @@ func TestAnthropicProvider_ConvertRequest(t *testing.T) {
 	provider := &AnthropicProvider{}
 
 	req := &ai.ChatRequest{
-		Model: "gpt-4",
+		Model: "claude-3-opus-20240229",
 		Messages: []ai.Message{
 			{Role: "user", Content: "Hello"},
 		},
@@ -215,3 +217,486 @@ func TestAnthropicProvider_GetName(t *testing.T) {
 		t.Errorf("Expected name 'Anthropic', got '%s'", provider.GetName())
 	}
 }
+
+func TestAnthropicProvider_ConvertRequest_ToolRoundTrip(t *testing.T) {
+	provider := &AnthropicProvider{}
+
+	call := ai.ToolCall{ID: "toolu_1", Type: "function"}
+	call.Function.Name = "file_operations"
+	call.Function.Arguments = `{"type":"read","path":"main.go"}`
+
+	req := &ai.ChatRequest{
+		Model: "claude-3-opus-20240229",
+		Messages: []ai.Message{
+			{Role: "system", Content: "You are RubrDuck."},
+			{Role: "user", Content: "Read main.go"},
+			{Role: "assistant", Content: "Reading it now.", ToolCalls: []ai.ToolCall{call}},
+			{Role: "tool", Content: "package main", ToolCallID: "toolu_1"},
+		},
+	}
+
+	anthropicReq := provider.convertRequest(req)
+
+	if anthropicReq["system"] != "You are RubrDuck." {
+		t.Errorf("Expected top-level system prompt, got '%v'", anthropicReq["system"])
+	}
+
+	messages := anthropicReq["messages"].([]map[string]interface{})
+	if len(messages) != 3 {
+		t.Fatalf("Expected 3 messages without the system message, got %d", len(messages))
+	}
+
+	assistant := messages[1]["content"].([]map[string]interface{})
+	if len(assistant) != 2 || assistant[1]["type"] != "tool_use" {
+		t.Fatalf("Expected text and tool_use blocks, got %v", assistant)
+	}
+	if assistant[1]["id"] != "toolu_1" || assistant[1]["name"] != "file_operations" {
+		t.Errorf("Unexpected tool_use block: %v", assistant[1])
+	}
+	input, _ := json.Marshal(assistant[1]["input"])
+	if string(input) != `{"type":"read","path":"main.go"}` {
+		t.Errorf("Expected tool input object, got %s", input)
+	}
+
+	if messages[2]["role"] != "user" {
+		t.Errorf("Expected tool result in a user message, got role '%v'", messages[2]["role"])
+	}
+	result := messages[2]["content"].([]map[string]interface{})
+	if result[0]["type"] != "tool_result" || result[0]["tool_use_id"] != "toolu_1" {
+		t.Errorf("Unexpected tool_result block: %v", result[0])
+	}
+
+	// Histories with tool_use blocks must declare tools
+	tools, ok := anthropicReq["tools"].([]map[string]interface{})
+	if !ok || len(tools) != 1 || tools[0]["name"] != "file_operations" {
+		t.Errorf("Expected stub tool declaration, got %v", anthropicReq["tools"])
+	}
+}
+
+func TestAnthropicProvider_ConvertRequest_ImageMediaType(t *testing.T) {
+	provider := &AnthropicProvider{}
+
+	req := &ai.ChatRequest{
+		Model: "claude-3-opus-20240229",
+		Messages: []ai.Message{
+			{Role: "user", Parts: []ai.MessagePart{
+				{Type: "text", Text: "What is this?"},
+				{Type: "image_url", ImageURL: "data:image/jpeg;base64,/9j/4AAQSkZJRg=="},
+			}},
+		},
+	}
+
+	messages := provider.convertRequest(req)["messages"].([]map[string]interface{})
+	parts := messages[0]["content"].([]map[string]interface{})
+	source := parts[1]["source"].(map[string]interface{})
+
+	if source["type"] != "base64" {
+		t.Errorf("Expected base64 source, got '%v'", source["type"])
+	}
+	if source["media_type"] != "image/jpeg" {
+		t.Errorf("Expected media type image/jpeg, got '%v'", source["media_type"])
+	}
+	if source["data"] != "/9j/4AAQSkZJRg==" {
+		t.Errorf("Expected data without the data URI prefix, got '%v'", source["data"])
+	}
+}
+
+func TestAnthropicProvider_ConvertResponse_ToolUse(t *testing.T) {
+	provider := &AnthropicProvider{}
+
+	resp := provider.convertResponse(&anthropicMessageResponse{
+		ID: "msg_1",
+		Content: []anthropicContent{
+			{Type: "text", Text: "Let me look."},
+			{Type: "tool_use", ID: "toolu_1", Name: "file_operations", Input: json.RawMessage(`{"type":"list"}`)},
+		},
+		StopReason: "tool_use",
+		Usage:      anthropicUsage{InputTokens: 5, OutputTokens: 3},
+	})
+
+	if len(resp.Choices) != 1 {
+		t.Fatalf("Expected 1 choice, got %d", len(resp.Choices))
+	}
+
+	msg := resp.Choices[0].Message
+	if msg.Content != "Let me look." {
+		t.Errorf("Expected text content, got '%s'", msg.Content)
+	}
+	if len(msg.ToolCalls) != 1 || msg.ToolCalls[0].Function.Arguments != `{"type":"list"}` {
+		t.Errorf("Expected tool call with arguments, got %+v", msg.ToolCalls)
+	}
+	if resp.Choices[0].FinishReason != "tool_calls" {
+		t.Errorf("Expected finish reason 'tool_calls', got '%s'", resp.Choices[0].FinishReason)
+	}
+}
+
+func TestAnthropicProvider_StreamChat_ToolUse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		events := []string{
+			`{"type":"message_start","message":{"id":"msg_1","usage":{"input_tokens":10}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Checking"}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"ping"}`,
+			`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"file_operations","input":{}}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"type\":"}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"list\"}"}}`,
+			`{"type":"content_block_stop","index":1}`,
+			`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":12}}`,
+			`{"type":"message_stop"}`,
+		}
+		for _, event := range events {
+			fmt.Fprintf(w, "event: x\ndata: %s\n\n", event)
+		}
+	}))
+	defer server.Close()
+
+	provider, err := NewAnthropicProvider(map[string]interface{}{
+		"api_key":  "test-key",
+		"base_url": server.URL,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := provider.StreamChat(ctx, &ai.ChatRequest{
+		Model:    "claude-3-opus-20240229",
+		Messages: []ai.Message{{Role: "user", Content: "list files"}},
+	})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+	defer stream.Close()
+
+	var content, toolID, toolName, args, finish string
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv() error = %v", err)
+		}
+		choice := chunk.Choices[0]
+		content += choice.Delta.Content
+		for _, tc := range choice.Delta.ToolCalls {
+			if tc.ID != "" {
+				toolID = tc.ID
+			}
+			if tc.Function.Name != "" {
+				toolName = tc.Function.Name
+			}
+			args += tc.Function.Arguments
+		}
+		if choice.FinishReason != nil {
+			finish = *choice.FinishReason
+		}
+	}
+
+	if content != "Checking" {
+		t.Errorf("Expected content 'Checking', got '%s'", content)
+	}
+	if toolID != "toolu_1" || toolName != "file_operations" {
+		t.Errorf("Expected tool call toolu_1/file_operations, got %s/%s", toolID, toolName)
+	}
+	if args != `{"type":"list"}` {
+		t.Errorf("Expected assembled tool arguments, got '%s'", args)
+	}
+	if finish != "tool_calls" {
+		t.Errorf("Expected finish reason 'tool_calls', got '%s'", finish)
+	}
+}
+
+func TestAnthropicProvider_ConvertRequest_CacheControl(t *testing.T) {
+	provider := &AnthropicProvider{}
+
+	tool := ai.Tool{Type: "function", Function: ai.ToolFunction{Name: "file_operations", Parameters: map[string]interface{}{"type": "object"}}}
+	req := &ai.ChatRequest{
+		Model: "claude-3-5-sonnet-20241022",
+		Messages: []ai.Message{
+			{Role: "system", Content: "You are RubrDuck.", CacheBreakpoint: true},
+			{Role: "user", Content: "turn 1", CacheBreakpoint: true},
+			{Role: "assistant", Content: "reply 1", CacheBreakpoint: true},
+			{Role: "user", Content: "turn 2", CacheBreakpoint: true},
+			{Role: "tool", Content: "result", ToolCallID: "toolu_1", CacheBreakpoint: true},
+		},
+		Tools:      []ai.Tool{tool},
+		CacheTools: true,
+	}
+
+	anthropicReq := provider.convertRequest(req)
+
+	system, ok := anthropicReq["system"].([]map[string]interface{})
+	if !ok || system[0]["cache_control"] == nil {
+		t.Fatalf("Expected cached system block, got %v", anthropicReq["system"])
+	}
+	tools := anthropicReq["tools"].([]map[string]interface{})
+	if tools[0]["cache_control"] == nil {
+		t.Error("Expected cache_control on the last tool definition")
+	}
+
+	// Four breakpoints at most: tools and system leave two for the latest messages
+	messages := anthropicReq["messages"].([]map[string]interface{})
+	if _, ok := messages[0]["content"].(string); !ok {
+		t.Errorf("Expected oldest marked message to stay uncached, got %v", messages[0]["content"])
+	}
+	if _, ok := messages[1]["content"].(string); !ok {
+		t.Errorf("Expected second marked message to stay uncached, got %v", messages[1]["content"])
+	}
+	turn2 := messages[2]["content"].([]map[string]interface{})
+	if turn2[0]["text"] != "turn 2" || turn2[0]["cache_control"] == nil {
+		t.Errorf("Expected cached text block, got %v", turn2)
+	}
+	result := messages[3]["content"].([]map[string]interface{})
+	if result[0]["type"] != "tool_result" || result[0]["cache_control"] == nil {
+		t.Errorf("Expected cached tool_result block, got %v", result)
+	}
+
+	// Without breakpoints the system prompt stays a plain string
+	plain := provider.convertRequest(&ai.ChatRequest{Messages: []ai.Message{{Role: "system", Content: "sys"}, {Role: "user", Content: "hi"}}})
+	if plain["system"] != "sys" {
+		t.Errorf("Expected plain system prompt, got %v", plain["system"])
+	}
+}
+
+func TestAnthropicProvider_CacheUsage(t *testing.T) {
+	provider := &AnthropicProvider{}
+
+	resp := provider.convertResponse(&anthropicMessageResponse{
+		ID:         "msg_1",
+		StopReason: "end_turn",
+		Usage:      anthropicUsage{InputTokens: 10, OutputTokens: 5, CacheCreationInputTokens: 100, CacheReadInputTokens: 900},
+	})
+	if resp.Usage.PromptTokens != 1010 || resp.Usage.TotalTokens != 1015 {
+		t.Errorf("Expected cached tokens counted in the prompt, got %+v", resp.Usage)
+	}
+	if resp.Usage.CacheReadTokens != 900 || resp.Usage.CacheWriteTokens != 100 {
+		t.Errorf("Expected cache read/write tokens, got %+v", resp.Usage)
+	}
+
+	stream := &anthropicStream{}
+	_, _, _ = stream.handleEvent(&anthropicStreamEvent{
+		Type:    "message_start",
+		Message: &anthropicMessageResponse{ID: "msg_2", Usage: anthropicUsage{InputTokens: 4, CacheReadInputTokens: 2000}},
+	})
+	var event anthropicStreamEvent
+	_ = json.Unmarshal([]byte(`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":7}}`), &event)
+	chunk, _, err := stream.handleEvent(&event)
+	if err != nil {
+		t.Fatalf("handleEvent() error = %v", err)
+	}
+	if chunk.Usage == nil || chunk.Usage.PromptTokens != 2004 || chunk.Usage.CompletionTokens != 7 || chunk.Usage.CacheReadTokens != 2000 {
+		t.Errorf("Expected streamed usage with cache reads, got %+v", chunk.Usage)
+	}
+}
+
+func TestAnthropicProvider_StructuredOutput(t *testing.T) {
+	provider := &AnthropicProvider{}
+	format := &ai.ResponseFormat{
+		Type:   ai.ResponseFormatJSONSchema,
+		Name:   "plan",
+		Schema: map[string]interface{}{"type": "object", "required": []string{"steps"}},
+	}
+
+	anthropicReq := provider.convertRequest(&ai.ChatRequest{
+		Messages:       []ai.Message{{Role: "user", Content: "plan it"}},
+		ResponseFormat: format,
+	})
+
+	tools := anthropicReq["tools"].([]map[string]interface{})
+	if len(tools) != 1 || tools[0]["name"] != "plan" {
+		t.Fatalf("Expected response tool 'plan', got %v", tools)
+	}
+	choice := anthropicReq["tool_choice"].(map[string]interface{})
+	if choice["type"] != "tool" || choice["name"] != "plan" {
+		t.Errorf("Expected forced tool choice, got %v", choice)
+	}
+
+	resp := provider.convertResponse(&anthropicMessageResponse{
+		ID: "msg_1",
+		Content: []anthropicContent{
+			{Type: "tool_use", ID: "toolu_1", Name: "plan", Input: json.RawMessage(`{"steps":["a"]}`)},
+		},
+		StopReason: "tool_use",
+	})
+	applyResponseTool(resp, "plan")
+
+	msg := resp.Choices[0].Message
+	if msg.Content != `{"steps":["a"]}` || len(msg.ToolCalls) != 0 {
+		t.Errorf("Expected tool input as content, got %+v", msg)
+	}
+	if resp.Choices[0].FinishReason != "stop" {
+		t.Errorf("Expected finish reason 'stop', got '%s'", resp.Choices[0].FinishReason)
+	}
+}
+
+func TestAnthropicProvider_StreamChat_StructuredOutput(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		events := []string{
+			`{"type":"message_start","message":{"id":"msg_1","usage":{"input_tokens":10}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"response","input":{}}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"ok\":"}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"true}"}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":5}}`,
+			`{"type":"message_stop"}`,
+		}
+		for _, event := range events {
+			fmt.Fprintf(w, "event: x\ndata: %s\n\n", event)
+		}
+	}))
+	defer server.Close()
+
+	provider, err := NewAnthropicProvider(map[string]interface{}{
+		"api_key":  "test-key",
+		"base_url": server.URL,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	stream, err := provider.StreamChat(context.Background(), &ai.ChatRequest{
+		Model:          "claude-3-opus-20240229",
+		Messages:       []ai.Message{{Role: "user", Content: "ok?"}},
+		ResponseFormat: &ai.ResponseFormat{Type: ai.ResponseFormatJSONObject},
+	})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+	defer stream.Close()
+
+	var content, finish string
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv() error = %v", err)
+		}
+		choice := chunk.Choices[0]
+		content += choice.Delta.Content
+		if len(choice.Delta.ToolCalls) > 0 {
+			t.Errorf("Expected no tool call deltas, got %+v", choice.Delta.ToolCalls)
+		}
+		if choice.FinishReason != nil {
+			finish = *choice.FinishReason
+		}
+	}
+
+	if content != `{"ok":true}` {
+		t.Errorf("Expected streamed JSON content, got '%s'", content)
+	}
+	if finish != "stop" {
+		t.Errorf("Expected finish reason 'stop', got '%s'", finish)
+	}
+}
+
+func TestAnthropicProvider_ConvertRequest_Thinking(t *testing.T) {
+	provider := &AnthropicProvider{}
+
+	req := &ai.ChatRequest{
+		Model:       "claude-sonnet-4-20250514",
+		Temperature: 0.5,
+		MaxTokens:   8192,
+		Messages: []ai.Message{
+			{Role: "user", Content: "list files"},
+			{Role: "assistant", Reasoning: "I should list", ReasoningSignature: "sig", ToolCalls: []ai.ToolCall{{ID: "toolu_1", Type: "function"}}},
+			{Role: "tool", Content: "a.go", ToolCallID: "toolu_1"},
+		},
+		ReasoningBudget: 4096,
+	}
+	req.Messages[1].ToolCalls[0].Function.Name = "file_operations"
+
+	anthropicReq := provider.convertRequest(req)
+
+	thinking, ok := anthropicReq["thinking"].(map[string]interface{})
+	if !ok || thinking["budget_tokens"] != 4096 {
+		t.Errorf("Expected thinking with budget 4096, got %v", anthropicReq["thinking"])
+	}
+	if _, ok := anthropicReq["temperature"]; ok {
+		t.Error("Expected temperature to be dropped when thinking")
+	}
+	blocks := anthropicReq["messages"].([]map[string]interface{})[1]["content"].([]map[string]interface{})
+	if blocks[0]["type"] != "thinking" || blocks[0]["signature"] != "sig" || blocks[1]["type"] != "tool_use" {
+		t.Errorf("Expected signed thinking block before tool_use, got %v", blocks)
+	}
+
+	// The budget leaves room for the answer
+	req.MaxTokens = 4096
+	req.ReasoningBudget = 10000
+	if got := anthropicThinkingBudget(req); got != 3072 {
+		t.Errorf("Expected budget capped at 3072, got %d", got)
+	}
+	req.MaxTokens = 1500
+	if got := anthropicThinkingBudget(req); got != 0 {
+		t.Errorf("Expected thinking off below the minimum budget, got %d", got)
+	}
+}
+
+func TestAnthropicProvider_StreamChat_Thinking(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		events := []string{
+			`{"type":"message_start","message":{"id":"msg_1","usage":{"input_tokens":10}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Consider "}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"the options"}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"EqQB"}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"content_block_start","index":1,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"Use B"}}`,
+			`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":30}}`,
+			`{"type":"message_stop"}`,
+		}
+		for _, event := range events {
+			fmt.Fprintf(w, "event: x\ndata: %s\n\n", event)
+		}
+	}))
+	defer server.Close()
+
+	provider, err := NewAnthropicProvider(map[string]interface{}{
+		"api_key":  "test-key",
+		"base_url": server.URL,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	stream, err := provider.StreamChat(context.Background(), &ai.ChatRequest{
+		Model:           "claude-sonnet-4-20250514",
+		Messages:        []ai.Message{{Role: "user", Content: "A or B?"}},
+		ReasoningBudget: 2048,
+	})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+	defer stream.Close()
+
+	var content, reasoning, signature string
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv() error = %v", err)
+		}
+		delta := chunk.Choices[0].Delta
+		content += delta.Content
+		reasoning += delta.Reasoning
+		signature += delta.ReasoningSignature
+	}
+
+	if reasoning != "Consider the options" || signature != "EqQB" {
+		t.Errorf("Expected streamed reasoning and signature, got '%s' / '%s'", reasoning, signature)
+	}
+	if content != "Use B" {
+		t.Errorf("Expected answer kept apart from reasoning, got '%s'", content)
+	}
+}
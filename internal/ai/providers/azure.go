@@ -0,0 +1,180 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hammie/rubrduck/internal/ai"
+)
+
+func init() {
+	// Register the Azure OpenAI provider
+	ai.RegisterProvider("azure", NewAzureProvider)
+}
+
+// defaultAzureAPIVersion is used when no api_version is configured
+const defaultAzureAPIVersion = "2024-02-15-preview"
+
+// AzureProvider implements the ai.Provider interface for Azure OpenAI.
+// Azure serves the OpenAI chat completions API behind per-deployment URLs,
+// so request and response bodies are shared with the OpenAI provider.
+type AzureProvider struct {
+	apiKey     string
+	baseURL    string
+	apiVersion string
+	deployment string
+	httpClient *http.Client
+	openai     *OpenAIProvider
+}
+
+// NewAzureProvider creates a new Azure OpenAI provider instance
+func NewAzureProvider(config map[string]interface{}) (ai.Provider, error) {
+	apiKey, _ := config["api_key"].(string)
+	if apiKey == "" {
+		return nil, fmt.Errorf("Azure OpenAI API key is required")
+	}
+
+	baseURL, _ := config["base_url"].(string)
+	if baseURL == "" {
+		return nil, fmt.Errorf("Azure OpenAI base URL is required (e.g. https://your-resource.openai.azure.com)")
+	}
+
+	apiVersion, _ := config["api_version"].(string)
+	if apiVersion == "" {
+		apiVersion = defaultAzureAPIVersion
+	}
+
+	// The deployment is optional; when unset the request model name is used
+	deployment, _ := config["deployment"].(string)
+
+	// Create HTTP client with reasonable timeouts
+	httpClient, err := ai.NewHTTPClient("Azure OpenAI", config, 60*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AzureProvider{
+		apiKey:     apiKey,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiVersion: apiVersion,
+		deployment: deployment,
+		httpClient: httpClient,
+		openai:     &OpenAIProvider{},
+	}, nil
+}
+
+// GetName returns the provider name
+func (p *AzureProvider) GetName() string {
+	return "Azure OpenAI"
+}
+
+// Chat sends a chat completion request
+func (p *AzureProvider) Chat(ctx context.Context, req *ai.ChatRequest) (*ai.ChatResponse, error) {
+	// Azure accepts the OpenAI request format
+	azureReq := p.openai.convertRequest(req)
+
+	// Marshal request
+	body, err := json.Marshal(azureReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	// Create HTTP request
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.chatURL(req.Model), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Set headers
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("api-key", p.apiKey)
+
+	// Send request
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Check status
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Azure OpenAI API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	// Parse response
+	var azureResp openAIChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&azureResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	// Convert to our format
+	return p.openai.convertResponse(&azureResp), nil
+}
+
+// StreamChat sends a streaming chat completion request
+func (p *AzureProvider) StreamChat(ctx context.Context, req *ai.ChatRequest) (ai.ChatStream, error) {
+	// Set streaming flag
+	req.Stream = true
+
+	// Azure accepts the OpenAI request format
+	azureReq := p.openai.convertRequest(req)
+
+	// Marshal request
+	body, err := json.Marshal(azureReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	// Create HTTP request
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.chatURL(req.Model), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Set headers
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("api-key", p.apiKey)
+	httpReq.Header.Set("Accept", "text/event-stream")
+
+	// Send request
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	// Check status
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Azure OpenAI API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	// Azure streams the same SSE frames as OpenAI
+	return &openAIStream{
+		reader:  resp.Body,
+		scanner: bufio.NewScanner(resp.Body),
+	}, nil
+}
+
+// chatURL builds the deployment-scoped chat completions URL
+func (p *AzureProvider) chatURL(model string) string {
+	deployment := p.deployment
+	if deployment == "" {
+		deployment = model
+	}
+
+	query := url.Values{}
+	query.Set("api-version", p.apiVersion)
+
+	return fmt.Sprintf("%s/openai/deployments/%s/chat/completions?%s",
+		p.baseURL, url.PathEscape(deployment), query.Encode())
+}
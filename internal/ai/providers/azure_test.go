@@ -0,0 +1,273 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hammie/rubrduck/internal/ai"
+)
+
+func TestNewAzureProvider(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name: "valid config",
+			config: map[string]interface{}{
+				"api_key":  "test-key",
+				"base_url": "https://example.openai.azure.com",
+			},
+			wantErr: false,
+		},
+		{
+			name: "missing api key",
+			config: map[string]interface{}{
+				"base_url": "https://example.openai.azure.com",
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing base url",
+			config: map[string]interface{}{
+				"api_key": "test-key",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := NewAzureProvider(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewAzureProvider() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && provider == nil {
+				t.Error("NewAzureProvider() returned nil provider")
+			}
+		})
+	}
+}
+
+func TestAzureProvider_Chat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Check deployment URL and api-version
+		if r.URL.Path != "/openai/deployments/my-gpt4/chat/completions" {
+			t.Errorf("Expected deployment path, got %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("api-version") != "2024-06-01" {
+			t.Errorf("Expected api-version 2024-06-01, got %s", r.URL.Query().Get("api-version"))
+		}
+
+		// Check headers
+		if r.Header.Get("api-key") != "test-key" {
+			t.Errorf("Expected api-key header, got %s", r.Header.Get("api-key"))
+		}
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("Expected no Authorization header, got %s", r.Header.Get("Authorization"))
+		}
+
+		// Check tools are forwarded
+		body, _ := io.ReadAll(r.Body)
+		var req map[string]interface{}
+		_ = json.Unmarshal(body, &req)
+		if tools, ok := req["tools"].([]interface{}); !ok || len(tools) != 1 {
+			t.Errorf("Expected 1 tool in request, got %v", req["tools"])
+		}
+
+		response := map[string]interface{}{
+			"id": "chatcmpl-azure",
+			"choices": []map[string]interface{}{
+				{
+					"index": 0,
+					"message": map[string]interface{}{
+						"role":    "assistant",
+						"content": "",
+						"tool_calls": []map[string]interface{}{
+							{
+								"id":   "call_1",
+								"type": "function",
+								"function": map[string]interface{}{
+									"name":      "file_operations",
+									"arguments": `{"type":"read","path":"main.go"}`,
+								},
+							},
+						},
+					},
+					"finish_reason": "tool_calls",
+				},
+			},
+			"usage": map[string]interface{}{
+				"prompt_tokens":     12,
+				"completion_tokens": 6,
+				"total_tokens":      18,
+			},
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	provider, err := NewAzureProvider(map[string]interface{}{
+		"api_key":     "test-key",
+		"base_url":    server.URL,
+		"api_version": "2024-06-01",
+		"deployment":  "my-gpt4",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	req := &ai.ChatRequest{
+		Model: "gpt-4",
+		Messages: []ai.Message{
+			{Role: "user", Content: "Read main.go"},
+		},
+		Tools: []ai.Tool{
+			{
+				Type: "function",
+				Function: ai.ToolFunction{
+					Name:        "file_operations",
+					Description: "File operations",
+					Parameters:  map[string]interface{}{"type": "object"},
+				},
+			},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := provider.Chat(ctx, req)
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+
+	if resp.ID != "chatcmpl-azure" {
+		t.Errorf("Expected ID 'chatcmpl-azure', got '%s'", resp.ID)
+	}
+
+	if len(resp.Choices) != 1 || len(resp.Choices[0].Message.ToolCalls) != 1 {
+		t.Fatalf("Expected 1 choice with 1 tool call, got %+v", resp.Choices)
+	}
+
+	if resp.Choices[0].Message.ToolCalls[0].Function.Name != "file_operations" {
+		t.Errorf("Expected tool call 'file_operations', got '%s'", resp.Choices[0].Message.ToolCalls[0].Function.Name)
+	}
+
+	if resp.Usage.TotalTokens != 18 {
+		t.Errorf("Expected total tokens 18, got %d", resp.Usage.TotalTokens)
+	}
+}
+
+func TestAzureProvider_Chat_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":{"code":"DeploymentNotFound"}}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewAzureProvider(map[string]interface{}{
+		"api_key":  "test-key",
+		"base_url": server.URL,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = provider.Chat(ctx, &ai.ChatRequest{
+		Model:    "missing",
+		Messages: []ai.Message{{Role: "user", Content: "Hello"}},
+	})
+	if err == nil {
+		t.Fatal("Expected error for missing deployment")
+	}
+
+	if !strings.Contains(err.Error(), "404") || !strings.Contains(err.Error(), "DeploymentNotFound") {
+		t.Errorf("Expected error to contain status and body, got: %v", err)
+	}
+}
+
+func TestAzureProvider_StreamChat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Without a configured deployment the model name is used
+		if r.URL.Path != "/openai/deployments/gpt-4o/chat/completions" {
+			t.Errorf("Expected model-named deployment path, got %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("api-version") != defaultAzureAPIVersion {
+			t.Errorf("Expected default api-version, got %s", r.URL.Query().Get("api-version"))
+		}
+		if r.Header.Get("Accept") != "text/event-stream" {
+			t.Errorf("Expected Accept text/event-stream, got %s", r.Header.Get("Accept"))
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+
+		fmt.Fprintf(w, "data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"Reading\"},\"finish_reason\":null}]}\n\n")
+		fmt.Fprintf(w, "data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{\"tool_calls\":[{\"id\":\"call_1\",\"type\":\"function\",\"function\":{\"name\":\"file_operations\",\"arguments\":\"{\\\"type\\\":\"}}]},\"finish_reason\":null}]}\n\n")
+		fmt.Fprintf(w, "data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{\"tool_calls\":[{\"function\":{\"arguments\":\"\\\"read\\\"}\"}}]},\"finish_reason\":\"tool_calls\"}]}\n\n")
+		fmt.Fprintf(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	provider, err := NewAzureProvider(map[string]interface{}{
+		"api_key":  "test-key",
+		"base_url": server.URL,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := provider.StreamChat(ctx, &ai.ChatRequest{
+		Model:    "gpt-4o",
+		Messages: []ai.Message{{Role: "user", Content: "Hello"}},
+	})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+	defer stream.Close()
+
+	var content, args string
+	for {
+		chunk, err := stream.Recv()
+		if err != nil {
+			break
+		}
+		for _, choice := range chunk.Choices {
+			content += choice.Delta.Content
+			for _, tc := range choice.Delta.ToolCalls {
+				args += tc.Function.Arguments
+			}
+		}
+	}
+
+	if content != "Reading" {
+		t.Errorf("Expected content 'Reading', got '%s'", content)
+	}
+	if args != `{"type":"read"}` {
+		t.Errorf("Expected streamed tool arguments, got '%s'", args)
+	}
+}
+
+func TestAzureProvider_GetName(t *testing.T) {
+	provider := &AzureProvider{}
+	if provider.GetName() != "Azure OpenAI" {
+		t.Errorf("Expected name 'Azure OpenAI', got '%s'", provider.GetName())
+	}
+}
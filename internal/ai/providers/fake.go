@@ -0,0 +1,321 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hammie/rubrduck/internal/ai"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	ai.RegisterProvider("fake", NewFakeProvider)
+}
+
+// FakeScript is the YAML script played by the fake provider. Each request
+// is answered by the first turn that matches its last message.
+type FakeScript struct {
+	Turns []FakeTurn `yaml:"turns"`
+}
+
+// FakeTurn is one scripted reply
+type FakeTurn struct {
+	// Match is a regular expression tested against the content of the last
+	// message; empty matches anything
+	Match string `yaml:"match"`
+	// Role restricts the turn to a last message with this role, e.g. "user"
+	// for prompts or "tool" for replies to tool results
+	Role string `yaml:"role"`
+
+	// Text is streamed word by word unless Chunks is set
+	Text   string   `yaml:"text"`
+	Chunks []string `yaml:"chunks"`
+	// Delay is the pause before each streamed chunk
+	Delay     time.Duration  `yaml:"delay"`
+	Reasoning string         `yaml:"reasoning"`
+	ToolCalls []FakeToolCall `yaml:"tool_calls"`
+	// Error fails the request; when streaming, after the text is sent
+	Error string    `yaml:"error"`
+	Usage FakeUsage `yaml:"usage"`
+
+	pattern *regexp.Regexp
+}
+
+// FakeToolCall is a scripted tool call; Arguments is sent as JSON
+type FakeToolCall struct {
+	Name      string                 `yaml:"name"`
+	Arguments map[string]interface{} `yaml:"arguments"`
+}
+
+// FakeUsage is the token usage reported for a turn; the total defaults to
+// prompt plus completion tokens
+type FakeUsage struct {
+	PromptTokens     int `yaml:"prompt_tokens"`
+	CompletionTokens int `yaml:"completion_tokens"`
+	TotalTokens      int `yaml:"total_tokens"`
+}
+
+// FakeProvider answers requests from a script instead of a model, so the
+// TUI, approval flows and API server can be exercised without a network
+type FakeProvider struct {
+	turns []FakeTurn
+
+	mu    sync.Mutex
+	calls int
+}
+
+// NewFakeProvider creates a fake provider.
+//
+// Recognised config keys: script (required), the path of the YAML script.
+func NewFakeProvider(config map[string]interface{}) (ai.Provider, error) {
+	path, _ := config["script"].(string)
+	if path == "" {
+		return nil, fmt.Errorf("fake provider requires a script")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fake script: %w", err)
+	}
+	return ParseFakeScript(data)
+}
+
+// ParseFakeScript creates a fake provider from script YAML
+func ParseFakeScript(data []byte) (*FakeProvider, error) {
+	var script FakeScript
+	if err := yaml.Unmarshal(data, &script); err != nil {
+		return nil, fmt.Errorf("invalid fake script: %w", err)
+	}
+	if len(script.Turns) == 0 {
+		return nil, fmt.Errorf("fake script has no turns")
+	}
+
+	for i := range script.Turns {
+		turn := &script.Turns[i]
+		pattern, err := regexp.Compile(turn.Match)
+		if err != nil {
+			return nil, fmt.Errorf("fake script turn %d: invalid match: %w", i+1, err)
+		}
+		turn.pattern = pattern
+	}
+	return &FakeProvider{turns: script.Turns}, nil
+}
+
+// Chat returns the matching turn as a single response
+func (p *FakeProvider) Chat(ctx context.Context, req *ai.ChatRequest) (*ai.ChatResponse, error) {
+	turn, err := p.match(req)
+	if err != nil {
+		return nil, err
+	}
+	if turn.Error != "" {
+		return nil, errors.New(turn.Error)
+	}
+
+	toolCalls, err := p.toolCalls(turn)
+	if err != nil {
+		return nil, err
+	}
+	return &ai.ChatResponse{
+		ID: p.responseID(),
+		Choices: []ai.Choice{{
+			Message: ai.Message{
+				Role:      "assistant",
+				Content:   strings.Join(turn.textChunks(), ""),
+				ToolCalls: toolCalls,
+				Reasoning: turn.Reasoning,
+			},
+			FinishReason: finishReason(toolCalls),
+		}},
+		Usage: turn.usage(),
+	}, nil
+}
+
+// StreamChat streams the matching turn: reasoning, text chunks with the
+// turn's delay, tool calls, then a final chunk with usage
+func (p *FakeProvider) StreamChat(ctx context.Context, req *ai.ChatRequest) (ai.ChatStream, error) {
+	turn, err := p.match(req)
+	if err != nil {
+		return nil, err
+	}
+	if turn.Error != "" && turn.Text == "" && len(turn.Chunks) == 0 {
+		return nil, errors.New(turn.Error)
+	}
+
+	toolCalls, err := p.toolCalls(turn)
+	if err != nil {
+		return nil, err
+	}
+
+	id := p.responseID()
+	delta := func(d ai.ChatStreamDelta) *ai.ChatStreamChunk {
+		return &ai.ChatStreamChunk{ID: id, Choices: []ai.ChatStreamChoice{{Delta: d}}}
+	}
+
+	var chunks []*ai.ChatStreamChunk
+	if turn.Reasoning != "" {
+		chunks = append(chunks, delta(ai.ChatStreamDelta{Role: "assistant", Reasoning: turn.Reasoning}))
+	}
+	for _, text := range turn.textChunks() {
+		chunks = append(chunks, delta(ai.ChatStreamDelta{Content: text}))
+	}
+	if len(toolCalls) > 0 {
+		chunks = append(chunks, delta(ai.ChatStreamDelta{ToolCalls: toolCalls}))
+	}
+
+	stream := &fakeStream{ctx: ctx, delay: turn.Delay, chunks: chunks}
+	if turn.Error != "" {
+		stream.err = errors.New(turn.Error)
+	} else {
+		reason := finishReason(toolCalls)
+		usage := turn.usage()
+		stream.chunks = append(stream.chunks, &ai.ChatStreamChunk{
+			ID:      id,
+			Choices: []ai.ChatStreamChoice{{FinishReason: &reason}},
+			Usage:   &usage,
+		})
+	}
+	return stream, nil
+}
+
+// GetName returns the provider name
+func (p *FakeProvider) GetName() string {
+	return "Fake"
+}
+
+// match returns the first turn matching the request's last message
+func (p *FakeProvider) match(req *ai.ChatRequest) (*FakeTurn, error) {
+	var last ai.Message
+	if len(req.Messages) > 0 {
+		last = req.Messages[len(req.Messages)-1]
+	}
+
+	for i := range p.turns {
+		turn := &p.turns[i]
+		if turn.Role != "" && turn.Role != last.Role {
+			continue
+		}
+		if turn.pattern.MatchString(last.Content) {
+			return turn, nil
+		}
+	}
+
+	content := last.Content
+	if len(content) > 80 {
+		content = content[:80] + "..."
+	}
+	return nil, fmt.Errorf("fake script has no turn matching %s message %q", last.Role, content)
+}
+
+// toolCalls builds the turn's tool calls with fresh IDs
+func (p *FakeProvider) toolCalls(turn *FakeTurn) ([]ai.ToolCall, error) {
+	var calls []ai.ToolCall
+	for _, call := range turn.ToolCalls {
+		args, err := json.Marshal(call.Arguments)
+		if err != nil {
+			return nil, fmt.Errorf("fake tool call %s: %w", call.Name, err)
+		}
+		if call.Arguments == nil {
+			args = []byte("{}")
+		}
+
+		toolCall := ai.ToolCall{ID: p.nextID("call_fake"), Type: "function"}
+		toolCall.Function.Name = call.Name
+		toolCall.Function.Arguments = string(args)
+		calls = append(calls, toolCall)
+	}
+	return calls, nil
+}
+
+func (p *FakeProvider) responseID() string {
+	return p.nextID("fake")
+}
+
+func (p *FakeProvider) nextID(prefix string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	return fmt.Sprintf("%s_%d", prefix, p.calls)
+}
+
+// textChunks splits Text into words, keeping the spaces, unless explicit
+// chunks are scripted
+func (t *FakeTurn) textChunks() []string {
+	if len(t.Chunks) > 0 {
+		return t.Chunks
+	}
+	var chunks []string
+	rest := t.Text
+	for rest != "" {
+		i := strings.IndexByte(rest[1:], ' ')
+		if i < 0 {
+			chunks = append(chunks, rest)
+			break
+		}
+		chunks = append(chunks, rest[:i+1])
+		rest = rest[i+1:]
+	}
+	return chunks
+}
+
+// usage converts the scripted usage, filling in the total
+func (t *FakeTurn) usage() ai.Usage {
+	usage := ai.Usage{
+		PromptTokens:     t.Usage.PromptTokens,
+		CompletionTokens: t.Usage.CompletionTokens,
+		TotalTokens:      t.Usage.TotalTokens,
+	}
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+	return usage
+}
+
+func finishReason(toolCalls []ai.ToolCall) string {
+	if len(toolCalls) > 0 {
+		return "tool_calls"
+	}
+	return "stop"
+}
+
+// fakeStream sends scripted chunks, pausing before each one
+type fakeStream struct {
+	ctx    context.Context
+	delay  time.Duration
+	chunks []*ai.ChatStreamChunk
+	err    error
+}
+
+func (s *fakeStream) Recv() (*ai.ChatStreamChunk, error) {
+	if len(s.chunks) == 0 {
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, io.EOF
+	}
+
+	if s.delay > 0 {
+		timer := time.NewTimer(s.delay)
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return nil, s.ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	chunk := s.chunks[0]
+	s.chunks = s.chunks[1:]
+	return chunk, nil
+}
+
+func (s *fakeStream) Close() error {
+	return nil
+}
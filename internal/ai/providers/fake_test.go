@@ -0,0 +1,178 @@
+package providers
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hammie/rubrduck/internal/ai"
+)
+
+const testFakeScript = `
+turns:
+  - match: (?i)list files
+    role: user
+    text: Listing now.
+    tool_calls:
+      - name: file_operations
+        arguments: {type: list, path: "."}
+    usage: {prompt_tokens: 10, completion_tokens: 4}
+  - match: overload
+    chunks: ["Partial"]
+    error: "fake API error (status 529): overloaded"
+  - role: tool
+    delay: 1ms
+    reasoning: The listing looks fine.
+    text: All done here
+`
+
+func TestNewFakeProvider(t *testing.T) {
+	if _, err := NewFakeProvider(map[string]interface{}{}); err == nil {
+		t.Error("Expected error without a script")
+	}
+	if _, err := ParseFakeScript([]byte("turns: []")); err == nil {
+		t.Error("Expected error for a script without turns")
+	}
+	if _, err := ParseFakeScript([]byte("turns:\n  - match: '('")); err == nil {
+		t.Error("Expected error for an invalid match")
+	}
+
+	// The example script shipped in the repository must stay valid
+	if _, err := NewFakeProvider(map[string]interface{}{"script": "../../../examples/fake/demo.yaml"}); err != nil {
+		t.Errorf("Example script failed to load: %v", err)
+	}
+}
+
+func TestFakeProvider_Chat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.yaml")
+	if err := os.WriteFile(path, []byte(testFakeScript), 0644); err != nil {
+		t.Fatal(err)
+	}
+	provider, err := NewFakeProvider(map[string]interface{}{"script": path})
+	if err != nil {
+		t.Fatalf("NewFakeProvider() error = %v", err)
+	}
+
+	resp, err := provider.Chat(context.Background(), &ai.ChatRequest{
+		Messages: []ai.Message{{Role: "user", Content: "Please LIST FILES"}},
+	})
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	choice := resp.Choices[0]
+	if choice.Message.Content != "Listing now." || choice.FinishReason != "tool_calls" {
+		t.Errorf("Unexpected reply: %+v", choice)
+	}
+	if len(choice.Message.ToolCalls) != 1 || choice.Message.ToolCalls[0].Function.Arguments != `{"path":".","type":"list"}` {
+		t.Errorf("Expected scripted tool call, got %+v", choice.Message.ToolCalls)
+	}
+	if resp.Usage.TotalTokens != 14 {
+		t.Errorf("Expected total of 14 tokens, got %d", resp.Usage.TotalTokens)
+	}
+
+	_, err = provider.Chat(context.Background(), &ai.ChatRequest{
+		Messages: []ai.Message{{Role: "user", Content: "hello"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), `no turn matching user message "hello"`) {
+		t.Errorf("Expected no-match error, got %v", err)
+	}
+
+	if _, err := provider.Chat(context.Background(), &ai.ChatRequest{
+		Messages: []ai.Message{{Role: "user", Content: "overload"}},
+	}); err == nil || !strings.Contains(err.Error(), "529") {
+		t.Errorf("Expected scripted error, got %v", err)
+	}
+}
+
+func TestFakeProvider_StreamChat(t *testing.T) {
+	provider, err := ParseFakeScript([]byte(testFakeScript))
+	if err != nil {
+		t.Fatalf("ParseFakeScript() error = %v", err)
+	}
+
+	stream, err := provider.StreamChat(context.Background(), &ai.ChatRequest{
+		Messages: []ai.Message{
+			{Role: "user", Content: "list files"},
+			{Role: "tool", Content: "main.go", ToolCallID: "call_fake_2"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+
+	var content, reasoning []string
+	var usage *ai.Usage
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv() error = %v", err)
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		delta := chunk.Choices[0].Delta
+		if delta.Content != "" {
+			content = append(content, delta.Content)
+		}
+		if delta.Reasoning != "" {
+			reasoning = append(reasoning, delta.Reasoning)
+		}
+	}
+
+	if strings.Join(content, "|") != "All| done| here" {
+		t.Errorf("Expected word chunks, got %q", content)
+	}
+	if len(reasoning) != 1 {
+		t.Errorf("Expected reasoning chunk, got %q", reasoning)
+	}
+	if usage == nil {
+		t.Error("Expected a final usage chunk")
+	}
+}
+
+func TestFakeProvider_StreamError(t *testing.T) {
+	provider, err := ParseFakeScript([]byte(testFakeScript))
+	if err != nil {
+		t.Fatalf("ParseFakeScript() error = %v", err)
+	}
+
+	stream, err := provider.StreamChat(context.Background(), &ai.ChatRequest{
+		Messages: []ai.Message{{Role: "user", Content: "overload"}},
+	})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+	if chunk, err := stream.Recv(); err != nil || chunk.Choices[0].Delta.Content != "Partial" {
+		t.Fatalf("Expected partial text first, got %v, %v", chunk, err)
+	}
+	if _, err := stream.Recv(); err == nil || !strings.Contains(err.Error(), "overloaded") {
+		t.Errorf("Expected scripted error mid-stream, got %v", err)
+	}
+}
+
+func TestFakeProvider_StreamCancel(t *testing.T) {
+	provider, err := ParseFakeScript([]byte("turns:\n  - delay: 1h\n    text: never"))
+	if err != nil {
+		t.Fatalf("ParseFakeScript() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	stream, err := provider.StreamChat(ctx, &ai.ChatRequest{})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+	if _, err := stream.Recv(); err != context.DeadlineExceeded {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+}
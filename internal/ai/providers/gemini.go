@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
@@ -39,8 +40,9 @@ func NewGeminiProvider(config map[string]interface{}) (ai.Provider, error) {
 	}
 
 	// Create HTTP client with reasonable timeouts
-	httpClient := &http.Client{
-		Timeout: 60 * time.Second,
+	httpClient, err := ai.NewHTTPClient("Gemini", config, 60*time.Second)
+	if err != nil {
+		return nil, err
 	}
 
 	return &GeminiProvider{
@@ -55,6 +57,138 @@ func (p *GeminiProvider) GetName() string {
 	return "Gemini"
 }
 
+// ListModels returns the models that support generateContent (models.list)
+func (p *GeminiProvider) ListModels(ctx context.Context) ([]ai.RemoteModel, error) {
+	var models []ai.RemoteModel
+	pageToken := ""
+	for {
+		query := url.Values{}
+		query.Set("key", p.apiKey)
+		query.Set("pageSize", "1000")
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+
+		httpReq, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/models?"+query.Encode(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		resp, err := p.httpClient.Do(httpReq)
+		if err != nil {
+			return nil, fmt.Errorf("failed to send request: %w", err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return nil, fmt.Errorf("Gemini API error (status %d): %s", resp.StatusCode, string(body))
+		}
+
+		var page struct {
+			Models []struct {
+				Name                       string   `json:"name"`
+				InputTokenLimit            int      `json:"inputTokenLimit"`
+				OutputTokenLimit           int      `json:"outputTokenLimit"`
+				SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
+			} `json:"models"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+
+		for _, m := range page.Models {
+			// Skip embedding-only and other models the chat API can't use
+			if !containsMethod(m.SupportedGenerationMethods, "generateContent") {
+				continue
+			}
+			models = append(models, ai.RemoteModel{
+				ID:              strings.TrimPrefix(m.Name, "models/"),
+				OwnedBy:         "google",
+				ContextWindow:   m.InputTokenLimit,
+				MaxOutputTokens: m.OutputTokenLimit,
+			})
+		}
+
+		if page.NextPageToken == "" {
+			return models, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// containsMethod reports whether methods contains method
+func containsMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// Embed returns embeddings for the request inputs (batchEmbedContents)
+func (p *GeminiProvider) Embed(ctx context.Context, req *ai.EmbeddingRequest) (*ai.EmbeddingResponse, error) {
+	model := strings.TrimPrefix(req.Model, "models/")
+	if model == "" {
+		model = ai.DefaultEmbeddingModel("gemini")
+	}
+
+	requests := make([]map[string]interface{}, len(req.Input))
+	for i, text := range req.Input {
+		requests[i] = map[string]interface{}{
+			"model": "models/" + model,
+			"content": map[string]interface{}{
+				"parts": []map[string]interface{}{{"text": text}},
+			},
+		}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"requests": requests})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/models/%s:batchEmbedContents?key=%s", p.baseURL, model, p.apiKey)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Gemini API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Embeddings []struct {
+			Values []float32 `json:"values"`
+		} `json:"embeddings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(result.Embeddings) != len(req.Input) {
+		return nil, fmt.Errorf("Gemini returned %d embeddings for %d inputs", len(result.Embeddings), len(req.Input))
+	}
+
+	embeddings := make([][]float32, len(result.Embeddings))
+	for i, e := range result.Embeddings {
+		embeddings[i] = e.Values
+	}
+	return &ai.EmbeddingResponse{Model: model, Embeddings: embeddings}, nil
+}
+
 // Chat sends a chat completion request
 func (p *GeminiProvider) Chat(ctx context.Context, req *ai.ChatRequest) (*ai.ChatResponse, error) {
 	// Convert our request to Gemini format
@@ -111,7 +245,8 @@ func (p *GeminiProvider) StreamChat(ctx context.Context, req *ai.ChatRequest) (a
 	}
 
 	// Create HTTP request
-	url := fmt.Sprintf("%s/models/%s:streamGenerateContent?key=%s", p.baseURL, p.convertModel(req.Model), p.apiKey)
+	// alt=sse makes Gemini emit one JSON response per SSE data line
+	url := fmt.Sprintf("%s/models/%s:streamGenerateContent?alt=sse&key=%s", p.baseURL, p.convertModel(req.Model), p.apiKey)
 	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -119,6 +254,7 @@ func (p *GeminiProvider) StreamChat(ctx context.Context, req *ai.ChatRequest) (a
 
 	// Set headers
 	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
 
 	// Send request
 	resp, err := p.httpClient.Do(httpReq)
@@ -140,41 +276,92 @@ func (p *GeminiProvider) StreamChat(ctx context.Context, req *ai.ChatRequest) (a
 	}, nil
 }
 
-// convertRequest converts our request format to Gemini format
+// convertRequest converts our request format to Gemini format.
+//
+// System messages become systemInstruction, assistant turns use the "model"
+// role, tool calls become functionCall parts and tool results are sent back
+// as functionResponse parts.
 func (p *GeminiProvider) convertRequest(req *ai.ChatRequest) map[string]interface{} {
-	// Convert messages to Gemini format
-	contents := make([]map[string]interface{}, len(req.Messages))
-	for i, msg := range req.Messages {
-		m := map[string]interface{}{
-			"role": msg.Role,
+	var systemParts []map[string]interface{}
+	contents := []map[string]interface{}{}
+
+	// Gemini matches function responses by name rather than call ID
+	toolNames := make(map[string]string)
+
+	for _, msg := range req.Messages {
+		switch msg.Role {
+		case "system":
+			if text := messageText(msg); text != "" {
+				systemParts = append(systemParts, map[string]interface{}{"text": text})
+			}
+			continue
+		case "tool":
+			name := msg.Name
+			if name == "" {
+				name = toolNames[msg.ToolCallID]
+			}
+			part := map[string]interface{}{
+				"functionResponse": map[string]interface{}{
+					"name":     name,
+					"response": map[string]interface{}{"content": messageText(msg)},
+				},
+			}
+			// Consecutive tool results are grouped into one turn
+			if n := len(contents); n > 0 && isFunctionResponseContent(contents[n-1]) {
+				contents[n-1]["parts"] = append(contents[n-1]["parts"].([]map[string]interface{}), part)
+			} else {
+				contents = append(contents, map[string]interface{}{
+					"role":  "user",
+					"parts": []map[string]interface{}{part},
+				})
+			}
+			continue
+		}
+
+		role := "user"
+		if msg.Role == "assistant" {
+			role = "model"
 		}
+
 		parts := []map[string]interface{}{}
 		if len(msg.Parts) > 0 {
 			for _, part := range msg.Parts {
 				if part.Type == "image_url" {
-					parts = append(parts, map[string]interface{}{
-						"inline_data": map[string]interface{}{
-							"mime_type": "image/png",
-							"data":      part.ImageURL,
-						},
-					})
+					parts = append(parts, geminiImagePart(part.ImageURL))
 				} else {
 					parts = append(parts, map[string]interface{}{
 						"text": part.Text,
 					})
 				}
 			}
-		} else {
+		} else if msg.Content != "" || len(msg.ToolCalls) == 0 {
 			parts = append(parts, map[string]interface{}{"text": msg.Content})
 		}
-		m["parts"] = parts
-		contents[i] = m
+
+		for _, call := range msg.ToolCalls {
+			toolNames[call.ID] = call.Function.Name
+			parts = append(parts, map[string]interface{}{
+				"functionCall": map[string]interface{}{
+					"name": call.Function.Name,
+					"args": toolCallInput(call.Function.Arguments),
+				},
+			})
+		}
+
+		contents = append(contents, map[string]interface{}{
+			"role":  role,
+			"parts": parts,
+		})
 	}
 
 	geminiReq := map[string]interface{}{
 		"contents": contents,
 	}
 
+	if len(systemParts) > 0 {
+		geminiReq["systemInstruction"] = map[string]interface{}{"parts": systemParts}
+	}
+
 	// Add generation config
 	generationConfig := map[string]interface{}{}
 	if req.Temperature > 0 {
@@ -183,6 +370,19 @@ func (p *GeminiProvider) convertRequest(req *ai.ChatRequest) map[string]interfac
 	if req.MaxTokens > 0 {
 		generationConfig["maxOutputTokens"] = req.MaxTokens
 	}
+	if format := req.ResponseFormat; format != nil && format.Type != "text" {
+		generationConfig["responseMimeType"] = "application/json"
+		if format.Type == ai.ResponseFormatJSONSchema && format.Schema != nil {
+			generationConfig["responseSchema"] = geminiSchema(format.Schema)
+		}
+	}
+	if req.ReasoningBudget > 0 {
+		// includeThoughts returns thought summaries as parts marked "thought"
+		generationConfig["thinkingConfig"] = map[string]interface{}{
+			"thinkingBudget":  req.ReasoningBudget,
+			"includeThoughts": true,
+		}
+	}
 	if len(generationConfig) > 0 {
 		geminiReq["generationConfig"] = generationConfig
 	}
@@ -195,67 +395,191 @@ func (p *GeminiProvider) convertRequest(req *ai.ChatRequest) map[string]interfac
 	return geminiReq
 }
 
-// convertModel converts our model names to Gemini model names
-func (p *GeminiProvider) convertModel(model string) string {
-	switch model {
-	case "gpt-4":
-		return "gemini-1.5-pro"
-	case "gpt-3.5-turbo":
-		return "gemini-1.5-flash"
-	default:
-		// If it's already a Gemini model name, return as is
-		if strings.HasPrefix(model, "gemini-") {
-			return model
+// geminiSchemaKeys are the JSON Schema keywords Gemini's responseSchema
+// (an OpenAPI subset) accepts
+var geminiSchemaKeys = map[string]bool{
+	"type": true, "format": true, "description": true, "nullable": true, "enum": true,
+	"properties": true, "required": true, "items": true, "minItems": true, "maxItems": true,
+	"anyOf": true, "propertyOrdering": true,
+}
+
+// geminiSchema converts a JSON schema to Gemini's responseSchema: unsupported
+// keywords such as additionalProperties are dropped, and type lists with
+// "null" become nullable
+func geminiSchema(schema map[string]interface{}) map[string]interface{} {
+	converted := make(map[string]interface{})
+	for key, value := range schema {
+		if !geminiSchemaKeys[key] {
+			continue
+		}
+		switch key {
+		case "type":
+			types, isList := value.([]interface{})
+			if list, ok := value.([]string); ok {
+				for _, t := range list {
+					types = append(types, t)
+				}
+				isList = true
+			}
+			if !isList {
+				converted["type"] = value
+				continue
+			}
+			for _, t := range types {
+				if t == "null" {
+					converted["nullable"] = true
+				} else {
+					converted["type"] = t
+				}
+			}
+		case "properties":
+			if props, ok := value.(map[string]interface{}); ok {
+				convertedProps := make(map[string]interface{}, len(props))
+				for name, prop := range props {
+					if sub, ok := prop.(map[string]interface{}); ok {
+						convertedProps[name] = geminiSchema(sub)
+					}
+				}
+				converted["properties"] = convertedProps
+			}
+		case "items":
+			if sub, ok := value.(map[string]interface{}); ok {
+				converted["items"] = geminiSchema(sub)
+			}
+		case "anyOf":
+			if options, ok := value.([]interface{}); ok {
+				var convertedOptions []interface{}
+				for _, option := range options {
+					if sub, ok := option.(map[string]interface{}); ok {
+						convertedOptions = append(convertedOptions, geminiSchema(sub))
+					}
+				}
+				converted["anyOf"] = convertedOptions
+			}
+		default:
+			converted[key] = value
 		}
-		// Default to Gemini 1.5 Pro
-		return "gemini-1.5-pro"
 	}
+	return converted
 }
 
-// convertTools converts our tool format to Gemini format
+// isFunctionResponseContent reports whether a Gemini content holds tool results
+func isFunctionResponseContent(content map[string]interface{}) bool {
+	parts, ok := content["parts"].([]map[string]interface{})
+	if !ok || len(parts) == 0 {
+		return false
+	}
+	_, ok = parts[0]["functionResponse"]
+	return ok
+}
+
+// geminiImagePart builds an inline or file image part with the right MIME type
+func geminiImagePart(imageURL string) map[string]interface{} {
+	src := parseImageURL(imageURL)
+	if src.URL != "" {
+		return map[string]interface{}{
+			"fileData": map[string]interface{}{
+				"mimeType": src.MediaType,
+				"fileUri":  src.URL,
+			},
+		}
+	}
+	return map[string]interface{}{
+		"inline_data": map[string]interface{}{
+			"mime_type": src.MediaType,
+			"data":      src.Data,
+		},
+	}
+}
+
+// convertModel fills in the registry default when no model is set; other
+// names are passed through unchanged
+func (p *GeminiProvider) convertModel(model string) string {
+	return ai.ResolveModel("gemini", model)
+}
+
+// convertTools converts our tool format to Gemini format. All functions are
+// declared on a single tool, which is what Gemini expects.
 func (p *GeminiProvider) convertTools(tools []ai.Tool) []map[string]interface{} {
-	geminiTools := make([]map[string]interface{}, len(tools))
+	declarations := make([]map[string]interface{}, len(tools))
 	for i, tool := range tools {
-		geminiTools[i] = map[string]interface{}{
-			"functionDeclarations": []map[string]interface{}{
-				{
-					"name":        tool.Function.Name,
-					"description": tool.Function.Description,
-					"parameters":  tool.Function.Parameters,
-				},
-			},
+		declarations[i] = map[string]interface{}{
+			"name":        tool.Function.Name,
+			"description": tool.Function.Description,
+			"parameters":  tool.Function.Parameters,
 		}
 	}
-	return geminiTools
+	return []map[string]interface{}{
+		{"functionDeclarations": declarations},
+	}
 }
 
 // convertResponse converts Gemini response to our format
 func (p *GeminiProvider) convertResponse(resp *geminiGenerateContentResponse) *ai.ChatResponse {
 	choices := make([]ai.Choice, len(resp.Candidates))
 	for i, candidate := range resp.Candidates {
-		content := ""
-		if len(candidate.Content.Parts) > 0 {
-			content = candidate.Content.Parts[0].Text
+		message := ai.Message{Role: "assistant"}
+		for j, part := range candidate.Content.Parts {
+			if part.FunctionCall != nil {
+				message.ToolCalls = append(message.ToolCalls, geminiToolCall(part.FunctionCall, fmt.Sprintf("%d_%d", i, j)))
+				continue
+			}
+			if part.Thought {
+				message.Reasoning += part.Text
+				continue
+			}
+			message.Content += part.Text
 		}
 
 		choices[i] = ai.Choice{
-			Index: i,
-			Message: ai.Message{
-				Role:    "assistant",
-				Content: content,
-			},
-			FinishReason: candidate.FinishReason,
+			Index:        i,
+			Message:      message,
+			FinishReason: convertGeminiFinishReason(candidate.FinishReason, len(message.ToolCalls) > 0),
 		}
 	}
 
+	id := resp.ResponseID
+	if id == "" && len(resp.Candidates) > 0 {
+		id = fmt.Sprintf("%d", resp.Candidates[0].Index) // Convert index to string
+	}
+
 	return &ai.ChatResponse{
-		ID:      fmt.Sprintf("%d", resp.Candidates[0].Index), // Convert index to string
+		ID:      id,
 		Choices: choices,
-		Usage: ai.Usage{
-			PromptTokens:     resp.UsageMetadata.PromptTokenCount,
-			CompletionTokens: resp.UsageMetadata.CandidatesTokenCount,
-			TotalTokens:      resp.UsageMetadata.TotalTokenCount,
-		},
+		Usage:   resp.UsageMetadata.toUsage(),
+	}
+}
+
+// geminiToolCall converts a Gemini functionCall into an ai.ToolCall. Gemini
+// does not always assign call IDs, so one is derived when missing.
+func geminiToolCall(fc *geminiFunctionCall, suffix string) ai.ToolCall {
+	id := fc.ID
+	if id == "" {
+		id = fmt.Sprintf("call_%s_%s", fc.Name, suffix)
+	}
+	call := ai.ToolCall{ID: id, Type: "function"}
+	call.Function.Name = fc.Name
+	call.Function.Arguments = "{}"
+	if len(fc.Args) > 0 && string(fc.Args) != "null" {
+		call.Function.Arguments = string(fc.Args)
+	}
+	return call
+}
+
+// convertGeminiFinishReason maps Gemini finish reasons to OpenAI finish reasons
+func convertGeminiFinishReason(reason string, hasToolCalls bool) string {
+	if hasToolCalls {
+		return "tool_calls"
+	}
+	switch reason {
+	case "STOP":
+		return "stop"
+	case "MAX_TOKENS":
+		return "length"
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII":
+		return "content_filter"
+	default:
+		return strings.ToLower(reason)
 	}
 }
 
@@ -264,6 +588,7 @@ type geminiGenerateContentResponse struct {
 	Candidates     []geminiCandidate     `json:"candidates"`
 	PromptFeedback *geminiPromptFeedback `json:"promptFeedback,omitempty"`
 	UsageMetadata  geminiUsageMetadata   `json:"usageMetadata"`
+	ResponseID     string                `json:"responseId,omitempty"`
 }
 
 type geminiCandidate struct {
@@ -279,7 +604,16 @@ type geminiContent struct {
 }
 
 type geminiPart struct {
-	Text string `json:"text"`
+	Text         string              `json:"text,omitempty"`
+	FunctionCall *geminiFunctionCall `json:"functionCall,omitempty"`
+	// Thought marks a thought summary part
+	Thought bool `json:"thought,omitempty"`
+}
+
+type geminiFunctionCall struct {
+	ID   string          `json:"id,omitempty"`
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
 }
 
 type geminiPromptFeedback struct {
@@ -295,12 +629,27 @@ type geminiUsageMetadata struct {
 	PromptTokenCount     int `json:"promptTokenCount"`
 	CandidatesTokenCount int `json:"candidatesTokenCount"`
 	TotalTokenCount      int `json:"totalTokenCount"`
+	// CachedContentTokenCount is the part of the prompt served from the cache
+	CachedContentTokenCount int `json:"cachedContentTokenCount"`
 }
 
-// geminiStream implements ai.ChatStream for Gemini streaming responses
+// toUsage converts Gemini usage metadata to ai.Usage
+func (u geminiUsageMetadata) toUsage() ai.Usage {
+	return ai.Usage{
+		PromptTokens:     u.PromptTokenCount,
+		CompletionTokens: u.CandidatesTokenCount,
+		TotalTokens:      u.TotalTokenCount,
+		CacheReadTokens:  u.CachedContentTokenCount,
+	}
+}
+
+// geminiStream implements ai.ChatStream for Gemini SSE responses
 type geminiStream struct {
 	reader  io.ReadCloser
 	scanner *bufio.Scanner
+
+	// calls counts streamed function calls so each gets a unique ID
+	calls int
 }
 
 func (s *geminiStream) Recv() (*ai.ChatStreamChunk, error) {
@@ -312,14 +661,16 @@ func (s *geminiStream) Recv() (*ai.ChatStreamChunk, error) {
 			continue
 		}
 
-		// Parse JSON chunk
-		var chunk ai.ChatStreamChunk
-		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+		// Accept both SSE data lines and bare JSON lines
+		data := strings.TrimPrefix(line, "data: ")
+
+		var resp geminiGenerateContentResponse
+		if err := json.Unmarshal([]byte(data), &resp); err != nil {
 			// Skip malformed JSON
 			continue
 		}
 
-		return &chunk, nil
+		return s.convertChunk(&resp), nil
 	}
 
 	// Check for scanner errors
@@ -330,6 +681,41 @@ func (s *geminiStream) Recv() (*ai.ChatStreamChunk, error) {
 	return nil, io.EOF
 }
 
+// convertChunk converts one streamed Gemini response into a stream chunk
+func (s *geminiStream) convertChunk(resp *geminiGenerateContentResponse) *ai.ChatStreamChunk {
+	chunk := &ai.ChatStreamChunk{ID: resp.ResponseID}
+
+	for i, candidate := range resp.Candidates {
+		var delta ai.ChatStreamDelta
+		for _, part := range candidate.Content.Parts {
+			if part.FunctionCall != nil {
+				s.calls++
+				delta.ToolCalls = append(delta.ToolCalls, geminiToolCall(part.FunctionCall, fmt.Sprintf("%d", s.calls)))
+				continue
+			}
+			if part.Thought {
+				delta.Reasoning += part.Text
+				continue
+			}
+			delta.Content += part.Text
+		}
+
+		choice := ai.ChatStreamChoice{Index: i, Delta: delta}
+		if candidate.FinishReason != "" {
+			reason := convertGeminiFinishReason(candidate.FinishReason, s.calls > 0)
+			choice.FinishReason = &reason
+		}
+		chunk.Choices = append(chunk.Choices, choice)
+	}
+
+	if resp.UsageMetadata.TotalTokenCount > 0 {
+		usage := resp.UsageMetadata.toUsage()
+		chunk.Usage = &usage
+	}
+
+	return chunk
+}
+
 func (s *geminiStream) Close() error {
 	return s.reader.Close()
 }
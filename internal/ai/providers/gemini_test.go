@@ -3,6 +3,8 @@ package providers
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -113,7 +115,7 @@ func TestGeminiProvider_Chat(t *testing.T) {
 
 	// Test chat request
 	req := &ai.ChatRequest{
-		Model: "gpt-4",
+		Model: "gemini-1.5-pro",
 		Messages: []ai.Message{
 			{Role: "user", Content: "Hello"},
 		},
@@ -153,10 +155,9 @@ func TestGeminiProvider_ModelConversion(t *testing.T) {
 		input    string
 		expected string
 	}{
-		{"gpt-4", "gemini-1.5-pro"},
-		{"gpt-3.5-turbo", "gemini-1.5-flash"},
-		{"gemini-1.5-pro", "gemini-1.5-pro"},
-		{"unknown", "gemini-1.5-pro"},
+		{"", "gemini-1.5-pro"},
+		{"gemini-1.5-flash", "gemini-1.5-flash"},
+		{"gpt-4", "gpt-4"},
 	}
 
 	for _, tt := range tests {
@@ -217,3 +218,305 @@ func TestGeminiProvider_GetName(t *testing.T) {
 		t.Errorf("Expected name 'Gemini', got '%s'", provider.GetName())
 	}
 }
+
+func TestGeminiProvider_ConvertRequest_FunctionCalling(t *testing.T) {
+	provider := &GeminiProvider{}
+
+	call := ai.ToolCall{ID: "call_1", Type: "function"}
+	call.Function.Name = "file_operations"
+	call.Function.Arguments = `{"type":"read","path":"main.go"}`
+
+	req := &ai.ChatRequest{
+		Model: "gemini-1.5-pro",
+		Messages: []ai.Message{
+			{Role: "system", Content: "You are RubrDuck."},
+			{Role: "user", Content: "Read main.go"},
+			{Role: "assistant", ToolCalls: []ai.ToolCall{call}},
+			{Role: "tool", Content: "package main", ToolCallID: "call_1"},
+		},
+	}
+
+	geminiReq := provider.convertRequest(req)
+
+	system := geminiReq["systemInstruction"].(map[string]interface{})
+	systemParts := system["parts"].([]map[string]interface{})
+	if systemParts[0]["text"] != "You are RubrDuck." {
+		t.Errorf("Expected system instruction, got %v", system)
+	}
+
+	contents := geminiReq["contents"].([]map[string]interface{})
+	if len(contents) != 3 {
+		t.Fatalf("Expected 3 contents without the system message, got %d", len(contents))
+	}
+
+	if contents[1]["role"] != "model" {
+		t.Errorf("Expected assistant role mapped to 'model', got '%v'", contents[1]["role"])
+	}
+	modelParts := contents[1]["parts"].([]map[string]interface{})
+	if len(modelParts) != 1 {
+		t.Fatalf("Expected only a functionCall part, got %v", modelParts)
+	}
+	fc := modelParts[0]["functionCall"].(map[string]interface{})
+	args, _ := json.Marshal(fc["args"])
+	if fc["name"] != "file_operations" || string(args) != `{"type":"read","path":"main.go"}` {
+		t.Errorf("Unexpected functionCall part: %v", fc)
+	}
+
+	toolParts := contents[2]["parts"].([]map[string]interface{})
+	fr := toolParts[0]["functionResponse"].(map[string]interface{})
+	if fr["name"] != "file_operations" {
+		t.Errorf("Expected functionResponse name resolved from call ID, got '%v'", fr["name"])
+	}
+	response := fr["response"].(map[string]interface{})
+	if response["content"] != "package main" {
+		t.Errorf("Expected tool result content, got %v", response)
+	}
+}
+
+func TestGeminiProvider_ConvertResponse_FunctionCall(t *testing.T) {
+	provider := &GeminiProvider{}
+
+	resp := provider.convertResponse(&geminiGenerateContentResponse{
+		Candidates: []geminiCandidate{
+			{
+				Content: geminiContent{
+					Role: "model",
+					Parts: []geminiPart{
+						{Text: "Listing files."},
+						{FunctionCall: &geminiFunctionCall{Name: "file_operations", Args: json.RawMessage(`{"type":"list"}`)}},
+					},
+				},
+				FinishReason: "STOP",
+			},
+		},
+		UsageMetadata: geminiUsageMetadata{PromptTokenCount: 4, CandidatesTokenCount: 2, TotalTokenCount: 6},
+	})
+
+	msg := resp.Choices[0].Message
+	if msg.Content != "Listing files." {
+		t.Errorf("Expected text content, got '%s'", msg.Content)
+	}
+	if len(msg.ToolCalls) != 1 || msg.ToolCalls[0].Function.Name != "file_operations" {
+		t.Fatalf("Expected one function call, got %+v", msg.ToolCalls)
+	}
+	if msg.ToolCalls[0].ID == "" || msg.ToolCalls[0].Function.Arguments != `{"type":"list"}` {
+		t.Errorf("Expected ID and arguments on tool call, got %+v", msg.ToolCalls[0])
+	}
+	if resp.Choices[0].FinishReason != "tool_calls" {
+		t.Errorf("Expected finish reason 'tool_calls', got '%s'", resp.Choices[0].FinishReason)
+	}
+	if resp.Usage.TotalTokens != 6 {
+		t.Errorf("Expected total tokens 6, got %d", resp.Usage.TotalTokens)
+	}
+}
+
+func TestGeminiProvider_StreamChat_FunctionCall(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("alt") != "sse" {
+			t.Errorf("Expected alt=sse, got %s", r.URL.RawQuery)
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":\"Sure\"}]}}]}\n\n")
+		fmt.Fprintf(w, "data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"functionCall\":{\"name\":\"file_operations\",\"args\":{\"type\":\"list\"}}}]},\"finishReason\":\"STOP\"}],\"usageMetadata\":{\"promptTokenCount\":7,\"candidatesTokenCount\":3,\"totalTokenCount\":10}}\n\n")
+	}))
+	defer server.Close()
+
+	provider, err := NewGeminiProvider(map[string]interface{}{
+		"api_key":  "test-key",
+		"base_url": server.URL,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := provider.StreamChat(ctx, &ai.ChatRequest{
+		Model:    "gemini-1.5-pro",
+		Messages: []ai.Message{{Role: "user", Content: "list files"}},
+	})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+	defer stream.Close()
+
+	var content string
+	var calls []ai.ToolCall
+	var usage *ai.Usage
+	var finish string
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv() error = %v", err)
+		}
+		for _, choice := range chunk.Choices {
+			content += choice.Delta.Content
+			calls = append(calls, choice.Delta.ToolCalls...)
+			if choice.FinishReason != nil {
+				finish = *choice.FinishReason
+			}
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+	}
+
+	if content != "Sure" {
+		t.Errorf("Expected content 'Sure', got '%s'", content)
+	}
+	if len(calls) != 1 || calls[0].Function.Arguments != `{"type":"list"}` {
+		t.Errorf("Expected streamed function call, got %+v", calls)
+	}
+	if finish != "tool_calls" {
+		t.Errorf("Expected finish reason 'tool_calls', got '%s'", finish)
+	}
+	if usage == nil || usage.TotalTokens != 10 {
+		t.Errorf("Expected usage with 10 total tokens, got %+v", usage)
+	}
+}
+
+func TestGeminiProvider_ListModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models" {
+			t.Errorf("Expected path /models, got %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("key") != "test-key" {
+			t.Errorf("Expected API key in query, got %s", r.URL.RawQuery)
+		}
+
+		// Serve two pages to exercise pagination
+		if r.URL.Query().Get("pageToken") == "" {
+			fmt.Fprint(w, `{"models":[
+				{"name":"models/gemini-1.5-pro","inputTokenLimit":2000000,"outputTokenLimit":8192,"supportedGenerationMethods":["generateContent","countTokens"]},
+				{"name":"models/text-embedding-004","inputTokenLimit":2048,"supportedGenerationMethods":["embedContent"]}
+			],"nextPageToken":"page2"}`)
+			return
+		}
+		fmt.Fprint(w, `{"models":[{"name":"models/gemini-exp-1206","inputTokenLimit":2097152,"outputTokenLimit":8192,"supportedGenerationMethods":["generateContent"]}]}`)
+	}))
+	defer server.Close()
+
+	p, err := NewGeminiProvider(map[string]interface{}{"api_key": "test-key", "base_url": server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	models, err := p.(ai.ModelLister).ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels() error = %v", err)
+	}
+
+	if len(models) != 2 {
+		t.Fatalf("Expected 2 chat models across pages, got %+v", models)
+	}
+	if models[0].ID != "gemini-1.5-pro" {
+		t.Errorf("Expected 'models/' prefix stripped, got '%s'", models[0].ID)
+	}
+	if models[1].ID != "gemini-exp-1206" || models[1].ContextWindow != 2097152 || models[1].MaxOutputTokens != 8192 {
+		t.Errorf("Unexpected second model: %+v", models[1])
+	}
+}
+
+func TestGeminiProvider_Embed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/text-embedding-004:batchEmbedContents" {
+			t.Errorf("Expected batchEmbedContents path, got %s", r.URL.Path)
+		}
+
+		var req struct {
+			Requests []struct {
+				Model   string `json:"model"`
+				Content struct {
+					Parts []struct {
+						Text string `json:"text"`
+					} `json:"parts"`
+				} `json:"content"`
+			} `json:"requests"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if len(req.Requests) != 2 || req.Requests[1].Model != "models/text-embedding-004" || req.Requests[1].Content.Parts[0].Text != "second" {
+			t.Errorf("Unexpected request: %+v", req)
+		}
+
+		fmt.Fprint(w, `{"embeddings":[{"values":[1,0]},{"values":[0,1]}]}`)
+	}))
+	defer server.Close()
+
+	p, err := NewGeminiProvider(map[string]interface{}{"api_key": "test-key", "base_url": server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	resp, err := p.(ai.Embedder).Embed(context.Background(), &ai.EmbeddingRequest{Input: []string{"first", "second"}})
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	if len(resp.Embeddings) != 2 || resp.Embeddings[1][1] != 1 {
+		t.Errorf("Unexpected embeddings: %v", resp.Embeddings)
+	}
+}
+
+func TestGeminiProvider_ConvertRequest_ResponseSchema(t *testing.T) {
+	provider := &GeminiProvider{}
+
+	geminiReq := provider.convertRequest(&ai.ChatRequest{
+		Model:    "gemini-1.5-pro",
+		Messages: []ai.Message{{Role: "user", Content: "plan it"}},
+		ResponseFormat: &ai.ResponseFormat{
+			Type: ai.ResponseFormatJSONSchema,
+			Schema: map[string]interface{}{
+				"type":                 "object",
+				"additionalProperties": false,
+				"properties": map[string]interface{}{
+					"owner": map[string]interface{}{"type": []string{"string", "null"}},
+				},
+			},
+		},
+	})
+
+	config := geminiReq["generationConfig"].(map[string]interface{})
+	if config["responseMimeType"] != "application/json" {
+		t.Errorf("Expected JSON mime type, got %v", config["responseMimeType"])
+	}
+	schema := config["responseSchema"].(map[string]interface{})
+	if _, ok := schema["additionalProperties"]; ok {
+		t.Error("Expected additionalProperties to be dropped")
+	}
+	owner := schema["properties"].(map[string]interface{})["owner"].(map[string]interface{})
+	if owner["type"] != "string" || owner["nullable"] != true {
+		t.Errorf("Expected nullable string, got %v", owner)
+	}
+}
+
+func TestGeminiProvider_Thinking(t *testing.T) {
+	provider := &GeminiProvider{}
+
+	geminiReq := provider.convertRequest(&ai.ChatRequest{
+		Model:           "gemini-2.5-pro",
+		Messages:        []ai.Message{{Role: "user", Content: "why?"}},
+		ReasoningBudget: 2048,
+	})
+	config := geminiReq["generationConfig"].(map[string]interface{})
+	thinking := config["thinkingConfig"].(map[string]interface{})
+	if thinking["thinkingBudget"] != 2048 || thinking["includeThoughts"] != true {
+		t.Errorf("Expected thinking config, got %v", thinking)
+	}
+
+	resp := provider.convertResponse(&geminiGenerateContentResponse{
+		Candidates: []geminiCandidate{{
+			Content: geminiContent{Parts: []geminiPart{
+				{Text: "Weighing it up", Thought: true},
+				{Text: "Because."},
+			}},
+			FinishReason: "STOP",
+		}},
+	})
+	if msg := resp.Choices[0].Message; msg.Reasoning != "Weighing it up" || msg.Content != "Because." {
+		t.Errorf("Expected thought part as reasoning, got %+v", msg)
+	}
+}
@@ -0,0 +1,76 @@
+package providers
+
+import (
+	"encoding/base64"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// imageSource describes an image referenced by an ai.MessagePart
+type imageSource struct {
+	// URL is set for remote images that the provider should fetch itself
+	URL string
+	// MediaType is the MIME type of inline image data
+	MediaType string
+	// Data is the base64 encoded image payload for inline images
+	Data string
+}
+
+// parseImageURL splits a MessagePart image reference into its media type and
+// payload. It accepts data URIs ("data:image/jpeg;base64,..."), http(s) URLs
+// and bare base64 payloads, sniffing the media type when it isn't declared.
+func parseImageURL(imageURL string) imageSource {
+	if strings.HasPrefix(imageURL, "http://") || strings.HasPrefix(imageURL, "https://") {
+		return imageSource{URL: imageURL, MediaType: mediaTypeFromExt(imageURL)}
+	}
+
+	if strings.HasPrefix(imageURL, "data:") {
+		header, data, found := strings.Cut(strings.TrimPrefix(imageURL, "data:"), ",")
+		if found {
+			mediaType, _, _ := strings.Cut(header, ";")
+			if mediaType == "" {
+				mediaType = sniffImageType(data)
+			}
+			return imageSource{MediaType: mediaType, Data: data}
+		}
+	}
+
+	return imageSource{MediaType: sniffImageType(imageURL), Data: imageURL}
+}
+
+// sniffImageType detects the media type of a base64 encoded image
+func sniffImageType(data string) string {
+	// DetectContentType only needs the first 512 bytes
+	prefix := data
+	if len(prefix) > 700 {
+		prefix = prefix[:700]
+	}
+	prefix = prefix[:len(prefix)/4*4]
+
+	decoded, err := base64.StdEncoding.DecodeString(prefix)
+	if err != nil || len(decoded) == 0 {
+		return "image/png"
+	}
+
+	mediaType := http.DetectContentType(decoded)
+	if !strings.HasPrefix(mediaType, "image/") {
+		return "image/png"
+	}
+	return mediaType
+}
+
+// mediaTypeFromExt guesses an image media type from a URL's file extension
+func mediaTypeFromExt(imageURL string) string {
+	ext := strings.ToLower(path.Ext(strings.SplitN(imageURL, "?", 2)[0]))
+	switch ext {
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".gif":
+		return "image/gif"
+	case ".webp":
+		return "image/webp"
+	default:
+		return "image/png"
+	}
+}
@@ -23,6 +23,9 @@ func init() {
 type OllamaProvider struct {
 	baseURL    string
 	httpClient *http.Client
+	// numCtx is the context size sent with each request; 0 leaves the
+	// server's default
+	numCtx int
 }
 
 // NewOllamaProvider creates a new Ollama provider instance
@@ -31,15 +34,19 @@ func NewOllamaProvider(config map[string]interface{}) (ai.Provider, error) {
 	if baseURL == "" {
 		baseURL = "http://localhost:11434"
 	}
+	// The native API lives at the server root, not under the OpenAI-compatible /v1
+	baseURL = strings.TrimSuffix(strings.TrimRight(baseURL, "/"), "/v1")
 
 	// Create HTTP client with reasonable timeouts
-	httpClient := &http.Client{
-		Timeout: 120 * time.Second, // Longer timeout for local models
+	httpClient, err := ai.NewHTTPClient("Ollama", config, 120*time.Second) // Longer timeout for local models
+	if err != nil {
+		return nil, err
 	}
 
 	return &OllamaProvider{
 		baseURL:    strings.TrimRight(baseURL, "/"),
 		httpClient: httpClient,
+		numCtx:     intFromConfig(config["num_ctx"]),
 	}, nil
 }
 
@@ -134,18 +141,57 @@ func (p *OllamaProvider) StreamChat(ctx context.Context, req *ai.ChatRequest) (a
 
 // convertRequest converts our request format to Ollama format
 func (p *OllamaProvider) convertRequest(req *ai.ChatRequest) map[string]interface{} {
+	// Ollama tool results carry the tool name rather than a call ID
+	toolNames := make(map[string]string)
+
 	// Convert messages to Ollama format
 	messages := make([]map[string]interface{}, len(req.Messages))
 	for i, msg := range req.Messages {
-		messages[i] = map[string]interface{}{
+		m := map[string]interface{}{
 			"role":    msg.Role,
-			"content": msg.Content,
+			"content": messageText(msg),
+		}
+
+		// Ollama only accepts inline base64 images
+		var images []string
+		for _, part := range msg.Parts {
+			if part.Type == "image_url" {
+				if src := parseImageURL(part.ImageURL); src.Data != "" {
+					images = append(images, src.Data)
+				}
+			}
+		}
+		if len(images) > 0 {
+			m["images"] = images
+		}
+
+		if len(msg.ToolCalls) > 0 {
+			calls := make([]map[string]interface{}, len(msg.ToolCalls))
+			for j, call := range msg.ToolCalls {
+				toolNames[call.ID] = call.Function.Name
+				calls[j] = map[string]interface{}{
+					"function": map[string]interface{}{
+						"name":      call.Function.Name,
+						"arguments": toolCallInput(call.Function.Arguments),
+					},
+				}
+			}
+			m["tool_calls"] = calls
+		}
+
+		if msg.Role == "tool" {
+			if name := toolNames[msg.ToolCallID]; name != "" {
+				m["tool_name"] = name
+			}
 		}
+
+		messages[i] = m
 	}
 
 	ollamaReq := map[string]interface{}{
 		"model":    p.convertModel(req.Model),
 		"messages": messages,
+		"stream":   req.Stream,
 	}
 
 	if req.Temperature > 0 {
@@ -155,6 +201,25 @@ func (p *OllamaProvider) convertRequest(req *ai.ChatRequest) map[string]interfac
 		ollamaReq["num_predict"] = req.MaxTokens
 	}
 
+	// Ollama accepts OpenAI-style function tool definitions
+	if len(req.Tools) > 0 {
+		ollamaReq["tools"] = req.Tools
+	}
+
+	// Ollama takes no thinking budget, only whether to think
+	if req.ReasoningBudget > 0 {
+		ollamaReq["think"] = true
+	}
+
+	// format is "json" or a JSON schema
+	if format := req.ResponseFormat; format != nil {
+		if format.Type == ai.ResponseFormatJSONSchema && format.Schema != nil {
+			ollamaReq["format"] = format.Schema
+		} else if format.Type == ai.ResponseFormatJSONObject {
+			ollamaReq["format"] = "json"
+		}
+	}
+
 	// Add options for better control
 	options := map[string]interface{}{}
 	if req.Temperature > 0 {
@@ -163,6 +228,9 @@ func (p *OllamaProvider) convertRequest(req *ai.ChatRequest) map[string]interfac
 	if req.MaxTokens > 0 {
 		options["num_predict"] = req.MaxTokens
 	}
+	if p.numCtx > 0 {
+		options["num_ctx"] = p.numCtx
+	}
 	if len(options) > 0 {
 		ollamaReq["options"] = options
 	}
@@ -170,51 +238,233 @@ func (p *OllamaProvider) convertRequest(req *ai.ChatRequest) map[string]interfac
 	return ollamaReq
 }
 
-// convertModel converts our model names to Ollama model names
+// convertModel fills in the registry default when no model is set; other
+// names are passed through unchanged
 func (p *OllamaProvider) convertModel(model string) string {
-	switch model {
-	case "gpt-4":
-		return "llama3.2:3b" // Default to a reasonable local model
-	case "gpt-3.5-turbo":
-		return "llama3.2:3b"
-	case "claude-3-opus-20240229":
-		return "llama3.2:3b"
-	case "claude-3-sonnet-20240229":
-		return "llama3.2:3b"
-	case "gemini-1.5-pro":
-		return "llama3.2:3b"
-	default:
-		// If it's already an Ollama model name, return as is
-		if strings.Contains(model, ":") || strings.HasPrefix(model, "llama") || strings.HasPrefix(model, "mistral") {
-			return model
-		}
-		// Default to Llama 3.2 3B
-		return "llama3.2:3b"
-	}
+	return ai.ResolveModel("ollama", model)
 }
 
 // convertResponse converts Ollama response to our format
 func (p *OllamaProvider) convertResponse(resp *ollamaChatResponse) *ai.ChatResponse {
+	message := ai.Message{
+		Role:      "assistant",
+		Content:   resp.Message.Content,
+		Reasoning: resp.Message.Thinking,
+	}
+	for i, call := range resp.Message.ToolCalls {
+		message.ToolCalls = append(message.ToolCalls, call.toToolCall(i+1))
+	}
+
 	choices := []ai.Choice{
 		{
-			Index: 0,
-			Message: ai.Message{
-				Role:    "assistant",
-				Content: resp.Message.Content,
-			},
-			FinishReason: "stop",
+			Index:        0,
+			Message:      message,
+			FinishReason: convertOllamaDoneReason(resp.DoneReason, len(message.ToolCalls) > 0),
 		},
 	}
 
 	return &ai.ChatResponse{
 		ID:      resp.Model,
 		Choices: choices,
-		Usage: ai.Usage{
-			PromptTokens:     resp.PromptEvalCount,
-			CompletionTokens: resp.EvalCount,
-			TotalTokens:      resp.PromptEvalCount + resp.EvalCount,
-		},
+		Usage:   resp.usage(),
+	}
+}
+
+// convertOllamaDoneReason maps Ollama done reasons to OpenAI finish reasons
+func convertOllamaDoneReason(reason string, hasToolCalls bool) string {
+	if hasToolCalls {
+		return "tool_calls"
+	}
+	if reason == "" {
+		return "stop"
+	}
+	return reason
+}
+
+// ListModels returns the models installed on the Ollama server (/api/tags)
+func (p *OllamaProvider) ListModels(ctx context.Context) ([]ai.RemoteModel, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/api/tags", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach Ollama at %s: %w", p.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Ollama API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var tags struct {
+		Models []struct {
+			Name       string    `json:"name"`
+			Model      string    `json:"model"`
+			Size       int64     `json:"size"`
+			ModifiedAt time.Time `json:"modified_at"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	models := make([]ai.RemoteModel, len(tags.Models))
+	for i, m := range tags.Models {
+		name := m.Name
+		if name == "" {
+			name = m.Model
+		}
+		models[i] = ai.RemoteModel{
+			ID:         name,
+			OwnedBy:    "local",
+			Size:       m.Size,
+			ModifiedAt: m.ModifiedAt,
+		}
+	}
+
+	return models, nil
+}
+
+// ValidateModel checks that the model is installed locally
+func (p *OllamaProvider) ValidateModel(ctx context.Context, model string) error {
+	models, err := p.ListModels(ctx)
+	if err != nil {
+		return err
+	}
+
+	name := p.convertModel(model)
+	var available []string
+	for _, m := range models {
+		// "llama3.2" refers to "llama3.2:latest"
+		if m.ID == name || m.ID == name+":latest" {
+			return nil
+		}
+		available = append(available, m.ID)
+	}
+
+	return fmt.Errorf("%w: Ollama model %q is not installed (available: %s); run `rubrduck models pull %s`",
+		ai.ErrModelNotFound, name, strings.Join(available, ", "), name)
+}
+
+// Embed returns embeddings for the request inputs (/api/embed)
+func (p *OllamaProvider) Embed(ctx context.Context, req *ai.EmbeddingRequest) (*ai.EmbeddingResponse, error) {
+	model := req.Model
+	if model == "" {
+		model = ai.DefaultEmbeddingModel("ollama")
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"model": model, "input": req.Input})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/api/embed", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach Ollama at %s: %w", p.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: Ollama embedding model %q is not installed; run `rubrduck models pull %s`",
+			ai.ErrModelNotFound, model, model)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Ollama API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Embeddings      [][]float32 `json:"embeddings"`
+		PromptEvalCount int         `json:"prompt_eval_count"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(result.Embeddings) != len(req.Input) {
+		return nil, fmt.Errorf("Ollama returned %d embeddings for %d inputs", len(result.Embeddings), len(req.Input))
+	}
+
+	return &ai.EmbeddingResponse{
+		Model:      model,
+		Embeddings: result.Embeddings,
+		Usage:      ai.Usage{PromptTokens: result.PromptEvalCount, TotalTokens: result.PromptEvalCount},
+	}, nil
+}
+
+// PullProgress reports the status of an in-progress model pull
+type PullProgress struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+}
+
+// PullModel downloads a model into the local Ollama server, reporting each
+// progress update to the callback as it streams in
+func (p *OllamaProvider) PullModel(ctx context.Context, model string, progress func(PullProgress)) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"model":  model,
+		"stream": true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/api/pull", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	// Pulls can take far longer than a chat request, so rely on ctx instead
+	client := &http.Client{Transport: p.httpClient.Transport}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to reach Ollama at %s: %w", p.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Ollama API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var update struct {
+			PullProgress
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(line), &update); err != nil {
+			continue
+		}
+		if update.Error != "" {
+			return fmt.Errorf("pull failed: %s", update.Error)
+		}
+		if progress != nil {
+			progress(update.PullProgress)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("pull stream error: %w", err)
+	}
+
+	return nil
 }
 
 // Ollama response types
@@ -222,21 +472,58 @@ type ollamaChatResponse struct {
 	Model     string `json:"model"`
 	CreatedAt string `json:"created_at"`
 	Message   struct {
-		Role    string `json:"role"`
-		Content string `json:"content"`
+		Role      string           `json:"role"`
+		Content   string           `json:"content"`
+		Thinking  string           `json:"thinking,omitempty"`
+		ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
 	} `json:"message"`
-	Done            bool  `json:"done"`
-	TotalDuration   int64 `json:"total_duration"`
-	LoadDuration    int64 `json:"load_duration"`
-	PromptEvalCount int   `json:"prompt_eval_count"`
-	EvalCount       int   `json:"eval_count"`
-	EvalDuration    int64 `json:"eval_duration"`
+	Done            bool   `json:"done"`
+	DoneReason      string `json:"done_reason,omitempty"`
+	TotalDuration   int64  `json:"total_duration"`
+	LoadDuration    int64  `json:"load_duration"`
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
+	EvalDuration    int64  `json:"eval_duration"`
+}
+
+// usage converts Ollama eval counts to ai.Usage
+func (r *ollamaChatResponse) usage() ai.Usage {
+	return ai.Usage{
+		PromptTokens:     r.PromptEvalCount,
+		CompletionTokens: r.EvalCount,
+		TotalTokens:      r.PromptEvalCount + r.EvalCount,
+	}
+}
+
+// ollamaToolCall is a native Ollama tool call; arguments are a JSON object
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+// toToolCall converts an Ollama tool call, deriving an ID since Ollama has none
+func (c ollamaToolCall) toToolCall(n int) ai.ToolCall {
+	call := ai.ToolCall{
+		ID:   fmt.Sprintf("call_%s_%d", c.Function.Name, n),
+		Type: "function",
+	}
+	call.Function.Name = c.Function.Name
+	call.Function.Arguments = "{}"
+	if len(c.Function.Arguments) > 0 && string(c.Function.Arguments) != "null" {
+		call.Function.Arguments = string(c.Function.Arguments)
+	}
+	return call
 }
 
 // ollamaStream implements ai.ChatStream for Ollama streaming responses
 type ollamaStream struct {
 	reader  io.ReadCloser
 	scanner *bufio.Scanner
+
+	// calls counts streamed tool calls so each gets a unique ID
+	calls int
 }
 
 func (s *ollamaStream) Recv() (*ai.ChatStreamChunk, error) {
@@ -248,14 +535,14 @@ func (s *ollamaStream) Recv() (*ai.ChatStreamChunk, error) {
 			continue
 		}
 
-		// Parse JSON chunk
-		var chunk ai.ChatStreamChunk
-		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+		// Parse JSON line
+		var resp ollamaChatResponse
+		if err := json.Unmarshal([]byte(line), &resp); err != nil {
 			// Skip malformed JSON
 			continue
 		}
 
-		return &chunk, nil
+		return s.convertChunk(&resp), nil
 	}
 
 	// Check for scanner errors
@@ -266,6 +553,33 @@ func (s *ollamaStream) Recv() (*ai.ChatStreamChunk, error) {
 	return nil, io.EOF
 }
 
+// convertChunk converts one streamed Ollama line into a stream chunk
+func (s *ollamaStream) convertChunk(resp *ollamaChatResponse) *ai.ChatStreamChunk {
+	delta := ai.ChatStreamDelta{
+		Role:      resp.Message.Role,
+		Content:   resp.Message.Content,
+		Reasoning: resp.Message.Thinking,
+	}
+	for _, call := range resp.Message.ToolCalls {
+		s.calls++
+		delta.ToolCalls = append(delta.ToolCalls, call.toToolCall(s.calls))
+	}
+
+	chunk := &ai.ChatStreamChunk{
+		ID:      resp.Model,
+		Choices: []ai.ChatStreamChoice{{Index: 0, Delta: delta}},
+	}
+
+	if resp.Done {
+		reason := convertOllamaDoneReason(resp.DoneReason, s.calls > 0)
+		chunk.Choices[0].FinishReason = &reason
+		usage := resp.usage()
+		chunk.Usage = &usage
+	}
+
+	return chunk
+}
+
 func (s *ollamaStream) Close() error {
 	return s.reader.Close()
 }
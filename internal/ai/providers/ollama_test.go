@@ -3,8 +3,12 @@ package providers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -139,14 +143,10 @@ func TestOllamaProvider_ModelConversion(t *testing.T) {
 		input    string
 		expected string
 	}{
-		{"gpt-4", "llama3.2:3b"},
-		{"gpt-3.5-turbo", "llama3.2:3b"},
-		{"claude-3-opus-20240229", "llama3.2:3b"},
-		{"claude-3-sonnet-20240229", "llama3.2:3b"},
-		{"gemini-1.5-pro", "llama3.2:3b"},
-		{"llama3.2:3b", "llama3.2:3b"},
+		{"", "llama3.2:3b"},
+		{"llama3.2", "llama3.2"},
 		{"mistral:7b", "mistral:7b"},
-		{"unknown", "llama3.2:3b"},
+		{"gpt-4", "gpt-4"},
 	}
 
 	for _, tt := range tests {
@@ -163,7 +163,6 @@ func TestOllamaProvider_ConvertRequest(t *testing.T) {
 	provider := &OllamaProvider{}
 
 	req := &ai.ChatRequest{
-		Model: "gpt-4",
 		Messages: []ai.Message{
 			{Role: "user", Content: "Hello"},
 		},
@@ -189,6 +188,285 @@ func TestOllamaProvider_ConvertRequest(t *testing.T) {
 	if options["num_predict"] != 100 {
 		t.Errorf("Expected num_predict 100, got '%v'", options["num_predict"])
 	}
+
+	// The server's context size is left alone unless configured
+	if _, ok := options["num_ctx"]; ok {
+		t.Errorf("Expected no num_ctx by default, got '%v'", options["num_ctx"])
+	}
+}
+
+func TestOllamaProvider_NumCtx(t *testing.T) {
+	provider, err := NewOllamaProvider(map[string]interface{}{"num_ctx": 16384})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	ollamaReq := provider.(*OllamaProvider).convertRequest(&ai.ChatRequest{
+		Messages: []ai.Message{{Role: "user", Content: "Hello"}},
+	})
+	options, _ := ollamaReq["options"].(map[string]interface{})
+	if options["num_ctx"] != 16384 {
+		t.Errorf("Expected num_ctx 16384, got '%v'", options["num_ctx"])
+	}
+}
+
+func TestOllamaProvider_ToolRoundTrip(t *testing.T) {
+	provider := &OllamaProvider{}
+
+	req := &ai.ChatRequest{
+		Model: "qwen2.5-coder",
+		Messages: []ai.Message{
+			{Role: "user", Content: "Read main.go"},
+			{
+				Role: "assistant",
+				ToolCalls: []ai.ToolCall{
+					{
+						ID:   "call_1",
+						Type: "function",
+						Function: struct {
+							Name      string `json:"name"`
+							Arguments string `json:"arguments"`
+						}{Name: "file_operations", Arguments: `{"type":"read","path":"main.go"}`},
+					},
+				},
+			},
+			{Role: "tool", ToolCallID: "call_1", Content: "package main"},
+		},
+		Tools: []ai.Tool{
+			{Type: "function", Function: ai.ToolFunction{Name: "file_operations"}},
+		},
+	}
+
+	body, err := json.Marshal(provider.convertRequest(req))
+	if err != nil {
+		t.Fatalf("Failed to marshal request: %v", err)
+	}
+
+	var ollamaReq struct {
+		Messages []struct {
+			Role      string `json:"role"`
+			ToolName  string `json:"tool_name"`
+			ToolCalls []struct {
+				Function struct {
+					Name      string                 `json:"name"`
+					Arguments map[string]interface{} `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"messages"`
+		Tools []interface{} `json:"tools"`
+	}
+	if err := json.Unmarshal(body, &ollamaReq); err != nil {
+		t.Fatalf("Failed to unmarshal request: %v", err)
+	}
+
+	if len(ollamaReq.Tools) != 1 {
+		t.Errorf("Expected 1 tool, got %d", len(ollamaReq.Tools))
+	}
+
+	calls := ollamaReq.Messages[1].ToolCalls
+	if len(calls) != 1 || calls[0].Function.Arguments["path"] != "main.go" {
+		t.Errorf("Expected tool call arguments as an object, got %+v", calls)
+	}
+
+	if ollamaReq.Messages[2].ToolName != "file_operations" {
+		t.Errorf("Expected tool_name 'file_operations', got '%s'", ollamaReq.Messages[2].ToolName)
+	}
+}
+
+func TestOllamaProvider_ChatToolCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req map[string]interface{}
+		_ = json.Unmarshal(body, &req)
+		if req["stream"] != false {
+			t.Errorf("Expected stream=false, got %v", req["stream"])
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"model":"qwen2.5-coder","message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"file_operations","arguments":{"type":"list"}}}]},"done":true,"done_reason":"stop","prompt_eval_count":20,"eval_count":5}`)
+	}))
+	defer server.Close()
+
+	provider, err := NewOllamaProvider(map[string]interface{}{"base_url": server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	resp, err := provider.Chat(context.Background(), &ai.ChatRequest{
+		Model:    "qwen2.5-coder",
+		Messages: []ai.Message{{Role: "user", Content: "List files"}},
+	})
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+
+	choice := resp.Choices[0]
+	if choice.FinishReason != "tool_calls" {
+		t.Errorf("Expected finish reason 'tool_calls', got '%s'", choice.FinishReason)
+	}
+	if len(choice.Message.ToolCalls) != 1 {
+		t.Fatalf("Expected 1 tool call, got %d", len(choice.Message.ToolCalls))
+	}
+	call := choice.Message.ToolCalls[0]
+	if call.ID == "" || call.Function.Name != "file_operations" || call.Function.Arguments != `{"type":"list"}` {
+		t.Errorf("Unexpected tool call: %+v", call)
+	}
+	if resp.Usage.TotalTokens != 25 {
+		t.Errorf("Expected total tokens 25, got %d", resp.Usage.TotalTokens)
+	}
+}
+
+func TestOllamaProvider_StreamChat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		fmt.Fprintln(w, `{"model":"llama3.2","message":{"role":"assistant","content":"Let me look"},"done":false}`)
+		fmt.Fprintln(w, `{"model":"llama3.2","message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"file_operations","arguments":{"type":"read"}}},{"function":{"name":"file_operations","arguments":{"type":"list"}}}]},"done":false}`)
+		fmt.Fprintln(w, `{"model":"llama3.2","message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":10,"eval_count":7}`)
+	}))
+	defer server.Close()
+
+	provider, err := NewOllamaProvider(map[string]interface{}{"base_url": server.URL + "/v1"})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	stream, err := provider.StreamChat(context.Background(), &ai.ChatRequest{
+		Model:    "llama3.2",
+		Messages: []ai.Message{{Role: "user", Content: "Hello"}},
+	})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+	defer stream.Close()
+
+	var content, finishReason string
+	var usage *ai.Usage
+	ids := map[string]bool{}
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv() error = %v", err)
+		}
+		content += chunk.Choices[0].Delta.Content
+		for _, tc := range chunk.Choices[0].Delta.ToolCalls {
+			ids[tc.ID] = true
+		}
+		if chunk.Choices[0].FinishReason != nil {
+			finishReason = *chunk.Choices[0].FinishReason
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+	}
+
+	if content != "Let me look" {
+		t.Errorf("Expected content 'Let me look', got '%s'", content)
+	}
+	if len(ids) != 2 {
+		t.Errorf("Expected 2 distinct tool call IDs, got %v", ids)
+	}
+	if finishReason != "tool_calls" {
+		t.Errorf("Expected finish reason 'tool_calls', got '%s'", finishReason)
+	}
+	if usage == nil || usage.TotalTokens != 17 {
+		t.Errorf("Expected usage with 17 total tokens, got %+v", usage)
+	}
+}
+
+func TestOllamaProvider_ListAndValidateModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/tags" {
+			t.Errorf("Expected path /api/tags, got %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"models":[{"name":"llama3.2:latest","size":2019393189,"modified_at":"2024-10-01T12:00:00Z"},{"name":"qwen2.5-coder:7b","size":4683087332}]}`)
+	}))
+	defer server.Close()
+
+	p, err := NewOllamaProvider(map[string]interface{}{"base_url": server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	provider := p.(*OllamaProvider)
+
+	models, err := provider.ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels() error = %v", err)
+	}
+	if len(models) != 2 || models[0].ID != "llama3.2:latest" || models[1].Size != 4683087332 {
+		t.Errorf("Unexpected models: %+v", models)
+	}
+
+	if err := provider.ValidateModel(context.Background(), "llama3.2"); err != nil {
+		t.Errorf("Expected llama3.2 to resolve to :latest, got %v", err)
+	}
+	if err := provider.ValidateModel(context.Background(), "qwen2.5-coder:7b"); err != nil {
+		t.Errorf("Expected qwen2.5-coder:7b to be installed, got %v", err)
+	}
+
+	err = provider.ValidateModel(context.Background(), "mistral")
+	if !errors.Is(err, ai.ErrModelNotFound) {
+		t.Fatalf("Expected ErrModelNotFound, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "rubrduck models pull mistral") {
+		t.Errorf("Expected pull hint in error, got %v", err)
+	}
+}
+
+func TestOllamaProvider_PullModel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/pull" {
+			t.Errorf("Expected path /api/pull, got %s", r.URL.Path)
+		}
+		body, _ := io.ReadAll(r.Body)
+		var req map[string]interface{}
+		_ = json.Unmarshal(body, &req)
+		if req["model"] != "llama3.2" {
+			t.Errorf("Expected model 'llama3.2', got %v", req["model"])
+		}
+
+		fmt.Fprintln(w, `{"status":"pulling manifest"}`)
+		fmt.Fprintln(w, `{"status":"downloading","digest":"sha256:abc","total":100,"completed":50}`)
+		fmt.Fprintln(w, `{"status":"success"}`)
+	}))
+	defer server.Close()
+
+	p, err := NewOllamaProvider(map[string]interface{}{"base_url": server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	var updates []PullProgress
+	err = p.(*OllamaProvider).PullModel(context.Background(), "llama3.2", func(progress PullProgress) {
+		updates = append(updates, progress)
+	})
+	if err != nil {
+		t.Fatalf("PullModel() error = %v", err)
+	}
+
+	if len(updates) != 3 || updates[1].Completed != 50 || updates[2].Status != "success" {
+		t.Errorf("Unexpected progress updates: %+v", updates)
+	}
+}
+
+func TestOllamaProvider_PullModel_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"status":"pulling manifest"}`)
+		fmt.Fprintln(w, `{"error":"pull model manifest: file does not exist"}`)
+	}))
+	defer server.Close()
+
+	p, err := NewOllamaProvider(map[string]interface{}{"base_url": server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	err = p.(*OllamaProvider).PullModel(context.Background(), "nope", nil)
+	if err == nil || !strings.Contains(err.Error(), "file does not exist") {
+		t.Errorf("Expected pull error, got %v", err)
+	}
 }
 
 func TestOllamaProvider_GetName(t *testing.T) {
@@ -197,3 +475,114 @@ func TestOllamaProvider_GetName(t *testing.T) {
 		t.Errorf("Expected name 'Ollama', got '%s'", provider.GetName())
 	}
 }
+
+func TestOllamaProvider_Embed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embed" {
+			t.Errorf("Expected path /api/embed, got %s", r.URL.Path)
+		}
+
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.Model == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":"model \"missing\" not found"}`)
+			return
+		}
+		if req.Model != "nomic-embed-text" || len(req.Input) != 1 {
+			t.Errorf("Unexpected request: %+v", req)
+		}
+
+		fmt.Fprint(w, `{"model":"nomic-embed-text","embeddings":[[0.5,0.5]],"prompt_eval_count":3}`)
+	}))
+	defer server.Close()
+
+	p, err := NewOllamaProvider(map[string]interface{}{"base_url": server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	embedder := p.(ai.Embedder)
+
+	resp, err := embedder.Embed(context.Background(), &ai.EmbeddingRequest{Input: []string{"hello"}})
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	if len(resp.Embeddings) != 1 || resp.Usage.PromptTokens != 3 {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+
+	_, err = embedder.Embed(context.Background(), &ai.EmbeddingRequest{Model: "missing", Input: []string{"hello"}})
+	if !errors.Is(err, ai.ErrModelNotFound) {
+		t.Errorf("Expected ErrModelNotFound, got %v", err)
+	}
+}
+
+func TestOllamaProvider_ConvertRequest_ResponseFormat(t *testing.T) {
+	provider := &OllamaProvider{}
+	schema := map[string]interface{}{"type": "object"}
+
+	ollamaReq := provider.convertRequest(&ai.ChatRequest{
+		Model:          "llama3",
+		ResponseFormat: &ai.ResponseFormat{Type: ai.ResponseFormatJSONSchema, Schema: schema},
+	})
+	if format, ok := ollamaReq["format"].(map[string]interface{}); !ok || format["type"] != "object" {
+		t.Errorf("Expected schema as format, got %v", ollamaReq["format"])
+	}
+
+	ollamaReq = provider.convertRequest(&ai.ChatRequest{
+		Model:          "llama3",
+		ResponseFormat: &ai.ResponseFormat{Type: ai.ResponseFormatJSONObject},
+	})
+	if ollamaReq["format"] != "json" {
+		t.Errorf("Expected format 'json', got %v", ollamaReq["format"])
+	}
+}
+
+func TestOllamaProvider_Thinking(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["think"] != true {
+			t.Errorf("Expected think=true, got %v", body["think"])
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		fmt.Fprintln(w, `{"model":"qwen3","message":{"role":"assistant","content":"","thinking":"Counting"},"done":false}`)
+		fmt.Fprintln(w, `{"model":"qwen3","message":{"role":"assistant","content":"3"},"done":false}`)
+		fmt.Fprintln(w, `{"model":"qwen3","message":{"role":"assistant","content":""},"done":true,"done_reason":"stop"}`)
+	}))
+	defer server.Close()
+
+	provider, err := NewOllamaProvider(map[string]interface{}{"base_url": server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	stream, err := provider.StreamChat(context.Background(), &ai.ChatRequest{
+		Model:           "qwen3",
+		Messages:        []ai.Message{{Role: "user", Content: "How many r's in strawberry?"}},
+		ReasoningBudget: 1024,
+	})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+	defer stream.Close()
+
+	var content, reasoning string
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv() error = %v", err)
+		}
+		content += chunk.Choices[0].Delta.Content
+		reasoning += chunk.Choices[0].Delta.Reasoning
+	}
+	if reasoning != "Counting" || content != "3" {
+		t.Errorf("Expected thinking as reasoning, got '%s' / '%s'", reasoning, content)
+	}
+}
@@ -39,8 +39,9 @@ func NewOpenAIProvider(config map[string]interface{}) (ai.Provider, error) {
 	}
 
 	// Create HTTP client with reasonable timeouts
-	httpClient := &http.Client{
-		Timeout: 60 * time.Second,
+	httpClient, err := ai.NewHTTPClient("OpenAI", config, 60*time.Second)
+	if err != nil {
+		return nil, err
 	}
 
 	return &OpenAIProvider{
@@ -55,6 +56,117 @@ func (p *OpenAIProvider) GetName() string {
 	return "OpenAI"
 }
 
+// ListModels returns the models available to the API key (/models)
+func (p *OpenAIProvider) ListModels(ctx context.Context) ([]ai.RemoteModel, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+
+	return listOpenAIModels(p.httpClient, httpReq, "OpenAI")
+}
+
+// listOpenAIModels sends a prepared /models request and parses the
+// OpenAI-style model list; name is used in error messages
+func listOpenAIModels(client *http.Client, httpReq *http.Request, name string) ([]ai.RemoteModel, error) {
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%s API error (status %d): %s", name, resp.StatusCode, string(body))
+	}
+
+	var list struct {
+		Data []struct {
+			ID      string `json:"id"`
+			OwnedBy string `json:"owned_by"`
+			Created int64  `json:"created"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	models := make([]ai.RemoteModel, len(list.Data))
+	for i, m := range list.Data {
+		models[i] = ai.RemoteModel{ID: m.ID, OwnedBy: m.OwnedBy}
+		if m.Created > 0 {
+			models[i].ModifiedAt = time.Unix(m.Created, 0).UTC()
+		}
+	}
+	return models, nil
+}
+
+// Embed returns embeddings for the request inputs (/embeddings)
+func (p *OpenAIProvider) Embed(ctx context.Context, req *ai.EmbeddingRequest) (*ai.EmbeddingResponse, error) {
+	model := req.Model
+	if model == "" {
+		model = ai.DefaultEmbeddingModel("openai")
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"model": model, "input": req.Input})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+
+	return embedOpenAI(p.httpClient, httpReq, model, len(req.Input), "OpenAI")
+}
+
+// embedOpenAI sends a prepared /embeddings request and parses the
+// OpenAI-style response; name is used in error messages
+func embedOpenAI(client *http.Client, httpReq *http.Request, model string, inputs int, name string) (*ai.EmbeddingResponse, error) {
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%s API error (status %d): %s", name, resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Model string `json:"model"`
+		Data  []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		Usage ai.Usage `json:"usage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	// Results carry their input index and aren't guaranteed to be in order
+	embeddings := make([][]float32, inputs)
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= inputs {
+			return nil, fmt.Errorf("%s returned embedding for unknown input %d", name, d.Index)
+		}
+		embeddings[d.Index] = d.Embedding
+	}
+	for i, e := range embeddings {
+		if e == nil {
+			return nil, fmt.Errorf("%s returned no embedding for input %d", name, i)
+		}
+	}
+
+	return &ai.EmbeddingResponse{Model: model, Embeddings: embeddings, Usage: result.Usage}, nil
+}
+
 // Chat sends a chat completion request
 func (p *OpenAIProvider) Chat(ctx context.Context, req *ai.ChatRequest) (*ai.ChatResponse, error) {
 	// Convert our request to OpenAI format
@@ -106,6 +218,8 @@ func (p *OpenAIProvider) StreamChat(ctx context.Context, req *ai.ChatRequest) (a
 
 	// Convert our request to OpenAI format
 	openAIReq := p.convertRequest(req)
+	// Report usage, including cached prompt tokens, in a final chunk
+	openAIReq["stream_options"] = map[string]interface{}{"include_usage": true}
 
 	// Marshal request
 	body, err := json.Marshal(openAIReq)
@@ -183,26 +297,68 @@ func (p *OpenAIProvider) convertRequest(req *ai.ChatRequest) map[string]interfac
 		messages[i] = m
 	}
 
+	info := ai.ModelInfoFor("openai", req.Model)
+	maxTokens := info.CompletionBudget()
+	if req.MaxTokens > 0 {
+		maxTokens = req.MaxTokens
+	}
+	// Reasoning models reject max_tokens, since their limit also covers
+	// the hidden reasoning tokens
+	maxTokensKey := "max_tokens"
+	if info.SupportsReasoning {
+		maxTokensKey = "max_completion_tokens"
+	}
+
 	openAIReq := map[string]interface{}{
 		"model":      req.Model,
 		"messages":   messages,
 		"stream":     req.Stream,
-		"max_tokens": 2048, // Reduced to leave more room for input context
+		maxTokensKey: maxTokens,
 	}
 
 	if req.Temperature > 0 {
 		openAIReq["temperature"] = req.Temperature
 	}
-	if req.MaxTokens > 0 {
-		openAIReq["max_tokens"] = req.MaxTokens
-	}
 	if len(req.Tools) > 0 {
 		openAIReq["tools"] = req.Tools
 	}
+	if req.ResponseFormat != nil {
+		openAIReq["response_format"] = openAIResponseFormat(req.ResponseFormat)
+	}
+	if req.ReasoningBudget > 0 {
+		openAIReq["reasoning_effort"] = openAIReasoningEffort(req.ReasoningBudget)
+	}
 
 	return openAIReq
 }
 
+// openAIReasoningEffort maps a thinking budget to a reasoning_effort level;
+// OpenAI models take an effort rather than a token count
+func openAIReasoningEffort(budget int) string {
+	switch {
+	case budget <= 2048:
+		return "low"
+	case budget <= 8192:
+		return "medium"
+	default:
+		return "high"
+	}
+}
+
+// openAIResponseFormat converts a response format to OpenAI's response_format
+func openAIResponseFormat(format *ai.ResponseFormat) map[string]interface{} {
+	if format.Type == ai.ResponseFormatJSONSchema && format.Schema != nil {
+		return map[string]interface{}{
+			"type": "json_schema",
+			"json_schema": map[string]interface{}{
+				"name":   format.SchemaName(),
+				"schema": format.Schema,
+			},
+		}
+	}
+	return map[string]interface{}{"type": format.Type}
+}
+
 // convertResponse converts OpenAI response to our format
 func (p *OpenAIProvider) convertResponse(resp *openAIChatResponse) *ai.ChatResponse {
 	choices := make([]ai.Choice, len(resp.Choices))
@@ -217,7 +373,7 @@ func (p *OpenAIProvider) convertResponse(resp *openAIChatResponse) *ai.ChatRespo
 	return &ai.ChatResponse{
 		ID:      resp.ID,
 		Choices: choices,
-		Usage:   resp.Usage,
+		Usage:   resp.Usage.toUsage(),
 	}
 }
 
@@ -225,7 +381,56 @@ func (p *OpenAIProvider) convertResponse(resp *openAIChatResponse) *ai.ChatRespo
 type openAIChatResponse struct {
 	ID      string      `json:"id"`
 	Choices []ai.Choice `json:"choices"`
-	Usage   ai.Usage    `json:"usage"`
+	Usage   openAIUsage `json:"usage"`
+}
+
+// UnmarshalJSON decodes the response and also accepts reasoning_content,
+// which DeepSeek and vLLM use in place of reasoning
+func (r *openAIChatResponse) UnmarshalJSON(data []byte) error {
+	type plain openAIChatResponse
+	if err := json.Unmarshal(data, (*plain)(r)); err != nil {
+		return err
+	}
+	if !bytes.Contains(data, []byte(`"reasoning_content"`)) {
+		return nil
+	}
+	var extra struct {
+		Choices []struct {
+			Message struct {
+				ReasoningContent string `json:"reasoning_content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(data, &extra); err != nil {
+		return err
+	}
+	for i, c := range extra.Choices {
+		if i < len(r.Choices) && r.Choices[i].Message.Reasoning == "" {
+			r.Choices[i].Message.Reasoning = c.Message.ReasoningContent
+		}
+	}
+	return nil
+}
+
+// openAIUsage is OpenAI's usage object. Prompt caching is automatic; the
+// cached part of the prompt is reported in prompt_tokens_details.
+type openAIUsage struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	TotalTokens         int `json:"total_tokens"`
+	PromptTokensDetails struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+}
+
+// toUsage converts OpenAI usage to ai.Usage
+func (u openAIUsage) toUsage() ai.Usage {
+	return ai.Usage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens,
+		CacheReadTokens:  u.PromptTokensDetails.CachedTokens,
+	}
 }
 
 // openAIStream implements ai.ChatStream for OpenAI SSE responses
@@ -264,6 +469,35 @@ func (s *openAIStream) Recv() (*ai.ChatStreamChunk, error) {
 				continue
 			}
 
+			// Some servers stream reasoning as reasoning_content
+			if strings.Contains(data, `"reasoning_content"`) {
+				var extra struct {
+					Choices []struct {
+						Delta struct {
+							ReasoningContent string `json:"reasoning_content"`
+						} `json:"delta"`
+					} `json:"choices"`
+				}
+				if json.Unmarshal([]byte(data), &extra) == nil {
+					for i, c := range extra.Choices {
+						if i < len(chunk.Choices) && chunk.Choices[i].Delta.Reasoning == "" {
+							chunk.Choices[i].Delta.Reasoning = c.Delta.ReasoningContent
+						}
+					}
+				}
+			}
+
+			// Decode usage again for the cached token details
+			if chunk.Usage != nil {
+				var extra struct {
+					Usage openAIUsage `json:"usage"`
+				}
+				if json.Unmarshal([]byte(data), &extra) == nil {
+					usage := extra.Usage.toUsage()
+					chunk.Usage = &usage
+				}
+			}
+
 			return &chunk, nil
 		}
 	}
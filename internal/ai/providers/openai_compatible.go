@@ -0,0 +1,292 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hammie/rubrduck/internal/ai"
+)
+
+func init() {
+	// Register the generic OpenAI-compatible provider type
+	ai.RegisterProvider("openai_compatible", NewOpenAICompatibleProvider)
+}
+
+// OpenAICompatibleProvider implements the ai.Provider interface for any
+// endpoint that speaks the OpenAI chat completions API, such as vLLM,
+// llama.cpp server, LM Studio or an internal gateway. Unlike OpenAIProvider
+// it can be configured many times under different names.
+type OpenAICompatibleProvider struct {
+	name             string
+	apiKey           string
+	baseURL          string
+	authHeader       string
+	authScheme       string
+	headers          map[string]string
+	queryParams      map[string]string
+	disableTools     bool
+	disableStreaming bool
+	httpClient       *http.Client
+	openai           *OpenAIProvider
+}
+
+// NewOpenAICompatibleProvider creates a new OpenAI-compatible provider instance.
+//
+// Recognised config keys: name, base_url (required), api_key, auth_header,
+// auth_scheme, headers, query_params, timeout (seconds), disable_tools and
+// disable_streaming.
+func NewOpenAICompatibleProvider(config map[string]interface{}) (ai.Provider, error) {
+	baseURL, _ := config["base_url"].(string)
+	if baseURL == "" {
+		return nil, fmt.Errorf("OpenAI-compatible provider requires a base_url")
+	}
+
+	name, _ := config["name"].(string)
+	if name == "" {
+		name = "OpenAI-compatible"
+	}
+
+	// Local servers frequently run without auth, so the key is optional
+	apiKey, _ := config["api_key"].(string)
+
+	authHeader, _ := config["auth_header"].(string)
+	authScheme, _ := config["auth_scheme"].(string)
+	if authHeader == "" {
+		authHeader = "Authorization"
+		if authScheme == "" {
+			authScheme = "Bearer"
+		}
+	}
+
+	timeout := 60 * time.Second
+	if seconds := intFromConfig(config["timeout"]); seconds > 0 {
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	disableTools, _ := config["disable_tools"].(bool)
+	disableStreaming, _ := config["disable_streaming"].(bool)
+
+	// Create HTTP client with the per-instance timeout
+	httpClient, err := ai.NewHTTPClient(name, config, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OpenAICompatibleProvider{
+		name:             name,
+		apiKey:           apiKey,
+		baseURL:          strings.TrimRight(baseURL, "/"),
+		authHeader:       authHeader,
+		authScheme:       authScheme,
+		headers:          stringMapFromConfig(config["headers"]),
+		queryParams:      stringMapFromConfig(config["query_params"]),
+		disableTools:     disableTools,
+		disableStreaming: disableStreaming,
+		httpClient:       httpClient,
+		openai:           &OpenAIProvider{},
+	}, nil
+}
+
+// GetName returns the configured provider name
+func (p *OpenAICompatibleProvider) GetName() string {
+	return p.name
+}
+
+// Chat sends a chat completion request
+func (p *OpenAICompatibleProvider) Chat(ctx context.Context, req *ai.ChatRequest) (*ai.ChatResponse, error) {
+	compatReq := p.openai.convertRequest(p.prepareRequest(req, false))
+
+	// Marshal request
+	body, err := json.Marshal(compatReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	// Create HTTP request
+	httpReq, err := p.newRequest(ctx, "POST", "/chat/completions", body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send request
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Check status
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%s API error (status %d): %s", p.name, resp.StatusCode, string(body))
+	}
+
+	// Parse response
+	var compatResp openAIChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&compatResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	// Convert to our format
+	return p.openai.convertResponse(&compatResp), nil
+}
+
+// StreamChat sends a streaming chat completion request. When streaming is
+// disabled for this instance, a regular completion is made and replayed as
+// a single-chunk stream so callers don't need to care.
+func (p *OpenAICompatibleProvider) StreamChat(ctx context.Context, req *ai.ChatRequest) (ai.ChatStream, error) {
+	if p.disableStreaming {
+		resp, err := p.Chat(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return ai.NewResponseStream(resp), nil
+	}
+
+	compatReq := p.openai.convertRequest(p.prepareRequest(req, true))
+
+	// Marshal request
+	body, err := json.Marshal(compatReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	// Create HTTP request
+	httpReq, err := p.newRequest(ctx, "POST", "/chat/completions", body)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Accept", "text/event-stream")
+
+	// Send request
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	// Check status
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%s API error (status %d): %s", p.name, resp.StatusCode, string(body))
+	}
+
+	// Return stream
+	return &openAIStream{
+		reader:  resp.Body,
+		scanner: bufio.NewScanner(resp.Body),
+	}, nil
+}
+
+// prepareRequest returns a copy of req adjusted for this instance's
+// capabilities, leaving the caller's request untouched
+func (p *OpenAICompatibleProvider) prepareRequest(req *ai.ChatRequest, stream bool) *ai.ChatRequest {
+	prepared := *req
+	prepared.Stream = stream
+	if p.disableTools {
+		prepared.Tools = nil
+	}
+	return &prepared
+}
+
+// ListModels returns the models served by the endpoint (/models)
+func (p *OpenAICompatibleProvider) ListModels(ctx context.Context) ([]ai.RemoteModel, error) {
+	httpReq, err := p.newRequest(ctx, "GET", "/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	return listOpenAIModels(p.httpClient, httpReq, p.name)
+}
+
+// Embed returns embeddings for the request inputs (/embeddings). The
+// endpoint's embedding model must be named explicitly.
+func (p *OpenAICompatibleProvider) Embed(ctx context.Context, req *ai.EmbeddingRequest) (*ai.EmbeddingResponse, error) {
+	if req.Model == "" {
+		return nil, fmt.Errorf("%s requires an embedding model", p.name)
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"model": req.Model, "input": req.Input})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := p.newRequest(ctx, "POST", "/embeddings", body)
+	if err != nil {
+		return nil, err
+	}
+	return embedOpenAI(p.httpClient, httpReq, req.Model, len(req.Input), p.name)
+}
+
+// newRequest builds the HTTP request with auth, extra headers and query params
+func (p *OpenAICompatibleProvider) newRequest(ctx context.Context, method, path string, body []byte) (*http.Request, error) {
+	endpoint := p.baseURL + path
+	if len(p.queryParams) > 0 {
+		query := url.Values{}
+		for key, value := range p.queryParams {
+			query.Set(key, value)
+		}
+		endpoint += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if p.apiKey != "" {
+		value := p.apiKey
+		if p.authScheme != "" {
+			value = p.authScheme + " " + p.apiKey
+		}
+		httpReq.Header.Set(p.authHeader, value)
+	}
+	for key, value := range p.headers {
+		httpReq.Header.Set(key, value)
+	}
+
+	return httpReq, nil
+}
+
+// stringMapFromConfig accepts the map shapes produced by config decoding
+func stringMapFromConfig(value interface{}) map[string]string {
+	result := make(map[string]string)
+	switch m := value.(type) {
+	case map[string]string:
+		for k, v := range m {
+			result[k] = v
+		}
+	case map[string]interface{}:
+		for k, v := range m {
+			result[k] = fmt.Sprint(v)
+		}
+	}
+	return result
+}
+
+// intFromConfig accepts the numeric shapes produced by config decoding
+func intFromConfig(value interface{}) int {
+	switch v := value.(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return 0
+}
@@ -0,0 +1,280 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hammie/rubrduck/internal/ai"
+)
+
+func TestNewOpenAICompatibleProvider(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name: "base url without key",
+			config: map[string]interface{}{
+				"base_url": "http://localhost:8000/v1",
+			},
+			wantErr: false,
+		},
+		{
+			name: "missing base url",
+			config: map[string]interface{}{
+				"api_key": "test-key",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := NewOpenAICompatibleProvider(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewOpenAICompatibleProvider() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && provider == nil {
+				t.Error("NewOpenAICompatibleProvider() returned nil provider")
+			}
+		})
+	}
+}
+
+func TestOpenAICompatibleProvider_HeadersAndQuery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			t.Errorf("Expected path /chat/completions, got %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("tenant") != "platform" {
+			t.Errorf("Expected tenant query param, got %s", r.URL.RawQuery)
+		}
+		if r.Header.Get("X-Gateway-Key") != "secret" {
+			t.Errorf("Expected custom auth header without scheme, got %q", r.Header.Get("X-Gateway-Key"))
+		}
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("Expected no Authorization header, got %s", r.Header.Get("Authorization"))
+		}
+		if r.Header.Get("X-Team") != "platform" {
+			t.Errorf("Expected X-Team header, got %s", r.Header.Get("X-Team"))
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		var req map[string]interface{}
+		_ = json.Unmarshal(body, &req)
+		if _, ok := req["tools"]; ok {
+			t.Errorf("Expected tools to be dropped, got %v", req["tools"])
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"id": "cmpl-1",
+			"choices": []map[string]interface{}{
+				{
+					"index":         0,
+					"message":       map[string]interface{}{"role": "assistant", "content": "hi"},
+					"finish_reason": "stop",
+				},
+			},
+		})
+	}))
+	defer server.Close()
+
+	provider, err := NewOpenAICompatibleProvider(map[string]interface{}{
+		"name":          "gateway",
+		"base_url":      server.URL,
+		"api_key":       "secret",
+		"auth_header":   "X-Gateway-Key",
+		"headers":       map[string]string{"X-Team": "platform"},
+		"query_params":  map[string]interface{}{"tenant": "platform"},
+		"timeout":       5,
+		"disable_tools": true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	if provider.GetName() != "gateway" {
+		t.Errorf("Expected name 'gateway', got '%s'", provider.GetName())
+	}
+
+	req := &ai.ChatRequest{
+		Model:    "qwen2.5-coder",
+		Messages: []ai.Message{{Role: "user", Content: "Hello"}},
+		Tools: []ai.Tool{
+			{Type: "function", Function: ai.ToolFunction{Name: "test_function"}},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := provider.Chat(ctx, req)
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+
+	if resp.Choices[0].Message.Content != "hi" {
+		t.Errorf("Expected content 'hi', got '%s'", resp.Choices[0].Message.Content)
+	}
+
+	if len(req.Tools) != 1 {
+		t.Error("Expected caller's request tools to be left untouched")
+	}
+}
+
+func TestOpenAICompatibleProvider_BearerAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("Expected Bearer auth, got %q", r.Header.Get("Authorization"))
+		}
+		if r.Header.Get("Accept") != "text/event-stream" {
+			t.Errorf("Expected Accept text/event-stream, got %s", r.Header.Get("Accept"))
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"Hello\"},\"finish_reason\":null}]}\n\n")
+		fmt.Fprintf(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	provider, err := NewOpenAICompatibleProvider(map[string]interface{}{
+		"base_url": server.URL,
+		"api_key":  "test-key",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := provider.StreamChat(ctx, &ai.ChatRequest{
+		Model:    "local",
+		Messages: []ai.Message{{Role: "user", Content: "Hello"}},
+	})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+	defer stream.Close()
+
+	chunk, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv() error = %v", err)
+	}
+	if chunk.Choices[0].Delta.Content != "Hello" {
+		t.Errorf("Expected content 'Hello', got '%s'", chunk.Choices[0].Delta.Content)
+	}
+}
+
+func TestOpenAICompatibleProvider_DisableStreaming(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req map[string]interface{}
+		_ = json.Unmarshal(body, &req)
+		if req["stream"] != false {
+			t.Errorf("Expected stream=false, got %v", req["stream"])
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"id": "cmpl-2",
+			"choices": []map[string]interface{}{
+				{
+					"index": 0,
+					"message": map[string]interface{}{
+						"role":    "assistant",
+						"content": "whole answer",
+						"tool_calls": []map[string]interface{}{
+							{
+								"id":       "call_1",
+								"type":     "function",
+								"function": map[string]interface{}{"name": "file_operations", "arguments": `{"type":"list"}`},
+							},
+						},
+					},
+					"finish_reason": "tool_calls",
+				},
+			},
+		})
+	}))
+	defer server.Close()
+
+	provider, err := NewOpenAICompatibleProvider(map[string]interface{}{
+		"base_url":          server.URL,
+		"disable_streaming": true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := provider.StreamChat(ctx, &ai.ChatRequest{
+		Model:    "local",
+		Messages: []ai.Message{{Role: "user", Content: "Hello"}},
+	})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+	defer stream.Close()
+
+	chunk, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv() error = %v", err)
+	}
+	if chunk.Choices[0].Delta.Content != "whole answer" {
+		t.Errorf("Expected full content in one chunk, got '%s'", chunk.Choices[0].Delta.Content)
+	}
+	if len(chunk.Choices[0].Delta.ToolCalls) != 1 {
+		t.Errorf("Expected tool call in chunk, got %d", len(chunk.Choices[0].Delta.ToolCalls))
+	}
+
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("Expected io.EOF after single chunk, got %v", err)
+	}
+}
+
+func TestOpenAICompatibleProvider_ListModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != "/models" {
+			t.Errorf("Expected GET /models, got %s %s", r.Method, r.URL.Path)
+		}
+		if r.URL.Query().Get("tenant") != "platform" {
+			t.Errorf("Expected tenant query param, got %s", r.URL.RawQuery)
+		}
+		if r.Header.Get("X-Gateway-Key") != "secret" {
+			t.Errorf("Expected custom auth header, got %q", r.Header.Get("X-Gateway-Key"))
+		}
+		fmt.Fprint(w, `{"object":"list","data":[{"id":"Qwen/Qwen2.5-Coder-32B-Instruct","owned_by":"vllm"}]}`)
+	}))
+	defer server.Close()
+
+	p, err := NewOpenAICompatibleProvider(map[string]interface{}{
+		"name":         "vllm",
+		"base_url":     server.URL,
+		"api_key":      "secret",
+		"auth_header":  "X-Gateway-Key",
+		"query_params": map[string]string{"tenant": "platform"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	models, err := p.(ai.ModelLister).ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels() error = %v", err)
+	}
+	if len(models) != 1 || models[0].ID != "Qwen/Qwen2.5-Coder-32B-Instruct" {
+		t.Errorf("Unexpected models: %+v", models)
+	}
+}
@@ -308,12 +308,13 @@ func TestOpenAIProvider_ConvertResponse(t *testing.T) {
 				FinishReason: "stop",
 			},
 		},
-		Usage: ai.Usage{
+		Usage: openAIUsage{
 			PromptTokens:     10,
 			CompletionTokens: 5,
 			TotalTokens:      15,
 		},
 	}
+	openAIResp.Usage.PromptTokensDetails.CachedTokens = 8
 
 	resp := provider.convertResponse(openAIResp)
 
@@ -332,6 +333,10 @@ func TestOpenAIProvider_ConvertResponse(t *testing.T) {
 	if resp.Usage.TotalTokens != 15 {
 		t.Errorf("Expected total tokens 15, got %d", resp.Usage.TotalTokens)
 	}
+
+	if resp.Usage.CacheReadTokens != 8 {
+		t.Errorf("Expected 8 cached tokens, got %d", resp.Usage.CacheReadTokens)
+	}
 }
 
 func TestOpenAIProvider_GetName(t *testing.T) {
@@ -340,3 +345,187 @@ func TestOpenAIProvider_GetName(t *testing.T) {
 		t.Errorf("Expected name 'OpenAI', got '%s'", provider.GetName())
 	}
 }
+
+func TestOpenAIProvider_ListModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != "/models" {
+			t.Errorf("Expected GET /models, got %s %s", r.Method, r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("Expected Authorization header, got %s", r.Header.Get("Authorization"))
+		}
+		fmt.Fprint(w, `{"object":"list","data":[{"id":"gpt-4o","owned_by":"system","created":1715367049},{"id":"ft:gpt-4o-mini:acme","owned_by":"acme"}]}`)
+	}))
+	defer server.Close()
+
+	p, err := NewOpenAIProvider(map[string]interface{}{"api_key": "test-key", "base_url": server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	models, err := p.(ai.ModelLister).ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels() error = %v", err)
+	}
+
+	if len(models) != 2 {
+		t.Fatalf("Expected 2 models, got %d", len(models))
+	}
+	if models[0].ID != "gpt-4o" || models[0].ModifiedAt.IsZero() {
+		t.Errorf("Unexpected first model: %+v", models[0])
+	}
+	if models[1].OwnedBy != "acme" {
+		t.Errorf("Expected owner 'acme', got '%s'", models[1].OwnedBy)
+	}
+}
+
+func TestOpenAIProvider_ListModels_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error":{"message":"Incorrect API key provided"}}`)
+	}))
+	defer server.Close()
+
+	p, err := NewOpenAIProvider(map[string]interface{}{"api_key": "bad-key", "base_url": server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	_, err = p.(ai.ModelLister).ListModels(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected 401 error, got %v", err)
+	}
+}
+
+func TestOpenAIProvider_Embed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" {
+			t.Errorf("Expected path /embeddings, got %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("Expected bearer auth, got %s", r.Header.Get("Authorization"))
+		}
+
+		var req map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req["model"] != "text-embedding-3-small" {
+			t.Errorf("Expected default embedding model, got %v", req["model"])
+		}
+
+		// Results out of order to check they're placed by index
+		fmt.Fprint(w, `{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}],"usage":{"prompt_tokens":6,"total_tokens":6}}`)
+	}))
+	defer server.Close()
+
+	p, err := NewOpenAIProvider(map[string]interface{}{"api_key": "test-key", "base_url": server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	resp, err := p.(ai.Embedder).Embed(context.Background(), &ai.EmbeddingRequest{Input: []string{"first", "second"}})
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+
+	if len(resp.Embeddings) != 2 || resp.Embeddings[0][0] != 1 || resp.Embeddings[1][1] != 1 {
+		t.Errorf("Unexpected embeddings: %v", resp.Embeddings)
+	}
+	if resp.Model != "text-embedding-3-small" || resp.Usage.PromptTokens != 6 {
+		t.Errorf("Unexpected model or usage: %+v", resp)
+	}
+}
+
+func TestOpenAIProvider_ConvertRequest_ResponseFormat(t *testing.T) {
+	provider := &OpenAIProvider{}
+	schema := map[string]interface{}{"type": "object"}
+
+	openAIReq := provider.convertRequest(&ai.ChatRequest{
+		Model:          "gpt-4o",
+		ResponseFormat: &ai.ResponseFormat{Type: ai.ResponseFormatJSONSchema, Name: "plan", Schema: schema},
+	})
+	format := openAIReq["response_format"].(map[string]interface{})
+	if format["type"] != "json_schema" {
+		t.Errorf("Expected type 'json_schema', got '%v'", format["type"])
+	}
+	jsonSchema := format["json_schema"].(map[string]interface{})
+	if jsonSchema["name"] != "plan" || jsonSchema["schema"] == nil {
+		t.Errorf("Expected named schema, got %v", jsonSchema)
+	}
+
+	openAIReq = provider.convertRequest(&ai.ChatRequest{
+		Model:          "gpt-4o",
+		ResponseFormat: &ai.ResponseFormat{Type: ai.ResponseFormatJSONObject},
+	})
+	if format := openAIReq["response_format"].(map[string]interface{}); format["type"] != "json_object" {
+		t.Errorf("Expected type 'json_object', got '%v'", format["type"])
+	}
+}
+
+func TestOpenAIProvider_Reasoning(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["reasoning_effort"] != "medium" {
+			t.Errorf("Expected reasoning_effort 'medium', got %v", body["reasoning_effort"])
+		}
+		// Reasoning models take max_completion_tokens instead of max_tokens
+		if _, ok := body["max_tokens"]; ok {
+			t.Errorf("Expected no max_tokens for a reasoning model, got %v", body["max_tokens"])
+		}
+		if body["max_completion_tokens"] == nil {
+			t.Error("Expected max_completion_tokens for a reasoning model")
+		}
+
+		if body["stream"] == true {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprintf(w, "data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{\"reasoning_content\":\"Hmm\"}}]}\n\n")
+			fmt.Fprintf(w, "data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{\"reasoning\":\", yes\"}}]}\n\n")
+			fmt.Fprintf(w, "data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"42\"}}]}\n\n")
+			fmt.Fprintf(w, "data: [DONE]\n\n")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"1","choices":[{"index":0,"message":{"role":"assistant","content":"42","reasoning_content":"Hmm"},"finish_reason":"stop"}]}`)
+	}))
+	defer server.Close()
+
+	provider, err := NewOpenAIProvider(map[string]interface{}{
+		"api_key":  "test-key",
+		"base_url": server.URL,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	req := &ai.ChatRequest{
+		Model:           "o3-mini",
+		Messages:        []ai.Message{{Role: "user", Content: "answer?"}},
+		ReasoningBudget: 4096,
+	}
+
+	resp, err := provider.Chat(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if msg := resp.Choices[0].Message; msg.Reasoning != "Hmm" || msg.Content != "42" {
+		t.Errorf("Expected reasoning_content as reasoning, got %+v", msg)
+	}
+
+	stream, err := provider.StreamChat(context.Background(), req)
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+	defer stream.Close()
+
+	var content, reasoning string
+	for {
+		chunk, err := stream.Recv()
+		if err != nil {
+			break
+		}
+		content += chunk.Choices[0].Delta.Content
+		reasoning += chunk.Choices[0].Delta.Reasoning
+	}
+	if reasoning != "Hmm, yes" || content != "42" {
+		t.Errorf("Expected streamed reasoning 'Hmm, yes' and content '42', got '%s' / '%s'", reasoning, content)
+	}
+}
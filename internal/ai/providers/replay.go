@@ -0,0 +1,185 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/hammie/rubrduck/internal/ai"
+)
+
+func init() {
+	ai.RegisterProvider("replay", NewReplayProvider)
+}
+
+// ErrReplayMismatch is returned (wrapped) when a request doesn't match the
+// next one in the cassette, or the cassette has run out
+var ErrReplayMismatch = errors.New("replay mismatch")
+
+// ReplayProvider plays back a cassette written by ai.RecordingProvider.
+// Requests must arrive in the recorded order and match the recorded
+// messages; the first divergence is reported as ErrReplayMismatch.
+type ReplayProvider struct {
+	path string
+
+	mu      sync.Mutex
+	entries []ai.CassetteEntry
+	next    int
+}
+
+// NewReplayProvider creates a replay provider.
+//
+// Recognised config keys: cassette (required), the path of the JSONL
+// cassette to play back.
+func NewReplayProvider(config map[string]interface{}) (ai.Provider, error) {
+	path, _ := config["cassette"].(string)
+	if path == "" {
+		return nil, fmt.Errorf("replay provider requires a cassette")
+	}
+
+	entries, err := ai.ReadCassette(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReplayProvider{path: path, entries: entries}, nil
+}
+
+// Chat returns the recorded response for the next request
+func (p *ReplayProvider) Chat(ctx context.Context, req *ai.ChatRequest) (*ai.ChatResponse, error) {
+	entry, err := p.take(ai.CassetteChat, req)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Error != "" {
+		return nil, errors.New(entry.Error)
+	}
+	return entry.Response, nil
+}
+
+// StreamChat replays the recorded chunks for the next request
+func (p *ReplayProvider) StreamChat(ctx context.Context, req *ai.ChatRequest) (ai.ChatStream, error) {
+	entry, err := p.take(ai.CassetteStream, req)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Error != "" && len(entry.Chunks) == 0 {
+		return nil, errors.New(entry.Error)
+	}
+	return &replayStream{chunks: entry.Chunks, err: entry.Error}, nil
+}
+
+// GetName returns the provider name
+func (p *ReplayProvider) GetName() string {
+	return "Replay"
+}
+
+// Remaining returns the number of recorded requests not yet replayed
+func (p *ReplayProvider) Remaining() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.entries) - p.next
+}
+
+// take checks req against the next cassette entry and consumes it
+func (p *ReplayProvider) take(method string, req *ai.ChatRequest) (*ai.CassetteEntry, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := p.next + 1
+	if p.next >= len(p.entries) {
+		return nil, fmt.Errorf("%w: request %d: cassette %s has only %d requests", ErrReplayMismatch, n, p.path, len(p.entries))
+	}
+
+	entry := &p.entries[p.next]
+	if entry.Method != method {
+		return nil, fmt.Errorf("%w: request %d: recorded a %s request, got %s", ErrReplayMismatch, n, entry.Method, method)
+	}
+	if diff := diffReplayRequest(entry.Request, req); diff != "" {
+		return nil, fmt.Errorf("%w: request %d: %s", ErrReplayMismatch, n, diff)
+	}
+
+	p.next++
+	return entry, nil
+}
+
+// diffReplayRequest describes the first difference between the recorded and
+// the actual request, or returns "" when they match. Only what shapes the
+// conversation is compared: the model (when both set) and the messages.
+// Limits, caching hints and tool definitions depend on local config.
+func diffReplayRequest(want, got *ai.ChatRequest) string {
+	if want == nil {
+		return ""
+	}
+	if want.Model != "" && got.Model != "" && want.Model != got.Model {
+		return fmt.Sprintf("model: recorded %q, got %q", want.Model, got.Model)
+	}
+	if len(want.Messages) != len(got.Messages) {
+		return fmt.Sprintf("recorded %d messages, got %d", len(want.Messages), len(got.Messages))
+	}
+
+	for i := range want.Messages {
+		w, g := want.Messages[i], got.Messages[i]
+		field := func(name, recorded, actual string) string {
+			if recorded == actual {
+				return ""
+			}
+			return fmt.Sprintf("messages[%d].%s: recorded %q, got %q", i, name, truncateReplay(recorded), truncateReplay(actual))
+		}
+		if d := field("role", w.Role, g.Role); d != "" {
+			return d
+		}
+		if d := field("content", w.Content, g.Content); d != "" {
+			return d
+		}
+		if d := field("tool_call_id", w.ToolCallID, g.ToolCallID); d != "" {
+			return d
+		}
+		if len(w.ToolCalls) != len(g.ToolCalls) {
+			return fmt.Sprintf("messages[%d]: recorded %d tool calls, got %d", i, len(w.ToolCalls), len(g.ToolCalls))
+		}
+		for j := range w.ToolCalls {
+			name := fmt.Sprintf("tool_calls[%d]", j)
+			if d := field(name+".name", w.ToolCalls[j].Function.Name, g.ToolCalls[j].Function.Name); d != "" {
+				return d
+			}
+			if d := field(name+".arguments", w.ToolCalls[j].Function.Arguments, g.ToolCalls[j].Function.Arguments); d != "" {
+				return d
+			}
+		}
+	}
+	return ""
+}
+
+// truncateReplay shortens long message text in mismatch reports
+func truncateReplay(s string) string {
+	const max = 120
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "..."
+}
+
+// replayStream returns recorded chunks, then the recorded stream error or EOF
+type replayStream struct {
+	chunks []*ai.ChatStreamChunk
+	err    string
+}
+
+func (s *replayStream) Recv() (*ai.ChatStreamChunk, error) {
+	if len(s.chunks) == 0 {
+		if s.err != "" {
+			return nil, errors.New(s.err)
+		}
+		return nil, io.EOF
+	}
+	chunk := s.chunks[0]
+	s.chunks = s.chunks[1:]
+	return chunk, nil
+}
+
+func (s *replayStream) Close() error {
+	return nil
+}
@@ -0,0 +1,173 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hammie/rubrduck/internal/ai"
+)
+
+// writeCassette writes entries as a JSONL cassette and returns its path
+func writeCassette(t *testing.T, entries ...ai.CassetteEntry) string {
+	t.Helper()
+	var lines []string
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, string(data))
+	}
+	path := filepath.Join(t.TempDir(), "cassette.jsonl")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func userRequest(content string) *ai.ChatRequest {
+	return &ai.ChatRequest{Model: "gpt-4", Messages: []ai.Message{{Role: "user", Content: content}}}
+}
+
+func TestNewReplayProvider(t *testing.T) {
+	if _, err := NewReplayProvider(map[string]interface{}{}); err == nil {
+		t.Error("Expected error without a cassette")
+	}
+	if _, err := NewReplayProvider(map[string]interface{}{"cassette": filepath.Join(t.TempDir(), "missing.jsonl")}); err == nil {
+		t.Error("Expected error for a missing cassette")
+	}
+}
+
+func TestReplayProvider_Playback(t *testing.T) {
+	path := writeCassette(t,
+		ai.CassetteEntry{Seq: 2, Method: ai.CassetteChat, Request: userRequest("second"),
+			Response: &ai.ChatResponse{Choices: []ai.Choice{{Message: ai.Message{Role: "assistant", Content: "done"}}}}},
+		ai.CassetteEntry{Seq: 1, Method: ai.CassetteStream, Request: userRequest("first"),
+			Chunks: []*ai.ChatStreamChunk{
+				{Choices: []ai.ChatStreamChoice{{Delta: ai.ChatStreamDelta{Content: "Hel"}}}},
+				{Choices: []ai.ChatStreamChoice{{Delta: ai.ChatStreamDelta{Content: "lo"}}}},
+			}},
+	)
+
+	provider, err := NewReplayProvider(map[string]interface{}{"cassette": path})
+	if err != nil {
+		t.Fatalf("NewReplayProvider() error = %v", err)
+	}
+	replay := provider.(*ReplayProvider)
+
+	// Limits aren't compared; they depend on local config
+	req := userRequest("first")
+	req.MaxTokens = 100
+	stream, err := replay.StreamChat(context.Background(), req)
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+	var content string
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv() error = %v", err)
+		}
+		content += chunk.Choices[0].Delta.Content
+	}
+	if content != "Hello" {
+		t.Errorf("Expected 'Hello', got %q", content)
+	}
+
+	resp, err := replay.Chat(context.Background(), userRequest("second"))
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if resp.Choices[0].Message.Content != "done" {
+		t.Errorf("Expected 'done', got %q", resp.Choices[0].Message.Content)
+	}
+	if replay.Remaining() != 0 {
+		t.Errorf("Expected cassette to be used up, %d left", replay.Remaining())
+	}
+
+	_, err = replay.Chat(context.Background(), userRequest("third"))
+	if !errors.Is(err, ErrReplayMismatch) || !strings.Contains(err.Error(), "has only 2 requests") {
+		t.Errorf("Expected exhausted cassette error, got %v", err)
+	}
+}
+
+func TestReplayProvider_Mismatch(t *testing.T) {
+	recorded := userRequest("hi")
+	recorded.Messages = append(recorded.Messages, ai.Message{Role: "assistant", ToolCalls: []ai.ToolCall{{ID: "call_1"}}})
+	recorded.Messages[1].ToolCalls[0].Function.Name = "file_operations"
+	recorded.Messages[1].ToolCalls[0].Function.Arguments = `{"type":"read","path":"a.go"}`
+
+	tests := []struct {
+		name    string
+		method  string
+		req     func() *ai.ChatRequest
+		wantErr string
+	}{
+		{"method", ai.CassetteStream, func() *ai.ChatRequest { return recorded }, "recorded a chat request, got stream"},
+		{"model", ai.CassetteChat, func() *ai.ChatRequest {
+			req := *recorded
+			req.Model = "claude-3-opus"
+			return &req
+		}, `model: recorded "gpt-4", got "claude-3-opus"`},
+		{"message count", ai.CassetteChat, func() *ai.ChatRequest { return userRequest("hi") }, "recorded 2 messages, got 1"},
+		{"tool arguments", ai.CassetteChat, func() *ai.ChatRequest {
+			req := *recorded
+			req.Messages = []ai.Message{recorded.Messages[0], {Role: "assistant", ToolCalls: []ai.ToolCall{{ID: "call_1"}}}}
+			req.Messages[1].ToolCalls[0].Function.Name = "file_operations"
+			req.Messages[1].ToolCalls[0].Function.Arguments = `{"type":"read","path":"b.go"}`
+			return &req
+		}, "messages[1].tool_calls[0].arguments"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeCassette(t, ai.CassetteEntry{Seq: 1, Method: ai.CassetteChat, Request: recorded,
+				Response: &ai.ChatResponse{}})
+			provider, err := NewReplayProvider(map[string]interface{}{"cassette": path})
+			if err != nil {
+				t.Fatalf("NewReplayProvider() error = %v", err)
+			}
+
+			if tt.method == ai.CassetteStream {
+				_, err = provider.StreamChat(context.Background(), tt.req())
+			} else {
+				_, err = provider.Chat(context.Background(), tt.req())
+			}
+			if !errors.Is(err, ErrReplayMismatch) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected mismatch containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestReplayProvider_RecordedError(t *testing.T) {
+	path := writeCassette(t,
+		ai.CassetteEntry{Seq: 1, Method: ai.CassetteStream, Request: userRequest("hi"),
+			Chunks: []*ai.ChatStreamChunk{{Choices: []ai.ChatStreamChoice{{Delta: ai.ChatStreamDelta{Content: "partial"}}}}},
+			Error:  "connection reset"},
+	)
+	provider, err := NewReplayProvider(map[string]interface{}{"cassette": path})
+	if err != nil {
+		t.Fatalf("NewReplayProvider() error = %v", err)
+	}
+
+	stream, err := provider.StreamChat(context.Background(), userRequest("hi"))
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("Expected the recorded chunk first, got %v", err)
+	}
+	if _, err := stream.Recv(); err == nil || err.Error() != "connection reset" {
+		t.Errorf("Expected the recorded stream error, got %v", err)
+	}
+}
@@ -0,0 +1,247 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidStructuredOutput is returned (wrapped) by ChatStructured when the
+// reply still doesn't match the response format after the repair retry
+var ErrInvalidStructuredOutput = errors.New("invalid structured output")
+
+// ChatStructured sends a request with a ResponseFormat and validates the
+// reply against it. If the reply isn't valid, the model is shown its answer
+// and the validation error and asked once to correct it. The returned
+// response's first choice holds the JSON, stripped of any code fence.
+func ChatStructured(ctx context.Context, provider Provider, req *ChatRequest) (*ChatResponse, error) {
+	if req.ResponseFormat == nil {
+		return nil, fmt.Errorf("request has no response format")
+	}
+
+	resp, err := provider.Chat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	content, invalid := checkStructured(resp, req.ResponseFormat)
+	if invalid == nil {
+		return resp, nil
+	}
+
+	// One repair attempt with the offending answer in context
+	repair := *req
+	repair.Messages = append(append([]Message{}, req.Messages...),
+		Message{Role: "assistant", Content: content},
+		Message{Role: "user", Content: fmt.Sprintf(
+			"Your reply was not valid: %v. Reply again with only the corrected JSON.", invalid)},
+	)
+
+	resp, err = provider.Chat(ctx, &repair)
+	if err != nil {
+		return nil, err
+	}
+	if _, invalid := checkStructured(resp, req.ResponseFormat); invalid != nil {
+		return resp, fmt.Errorf("%w: %v", ErrInvalidStructuredOutput, invalid)
+	}
+	return resp, nil
+}
+
+// checkStructured normalizes the reply content in place and validates it,
+// returning the content either way
+func checkStructured(resp *ChatResponse, format *ResponseFormat) (string, error) {
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("no response choices")
+	}
+
+	content := stripCodeFence(resp.Choices[0].Message.Content)
+	resp.Choices[0].Message.Content = content
+
+	switch format.Type {
+	case ResponseFormatJSONSchema:
+		return content, ValidateJSON(content, format.Schema)
+	case ResponseFormatJSONObject:
+		return content, ValidateJSON(content, map[string]interface{}{"type": "object"})
+	default:
+		return content, nil
+	}
+}
+
+// stripCodeFence removes a Markdown code fence some models wrap JSON in
+func stripCodeFence(content string) string {
+	trimmed := strings.TrimSpace(content)
+	if !strings.HasPrefix(trimmed, "```") || !strings.HasSuffix(trimmed, "```") || len(trimmed) < 6 {
+		return content
+	}
+	trimmed = strings.TrimSuffix(strings.TrimPrefix(trimmed, "```"), "```")
+	// Drop the language tag on the opening line
+	if i := strings.IndexByte(trimmed, '\n'); i >= 0 && !strings.ContainsAny(trimmed[:i], "{[") {
+		trimmed = trimmed[i+1:]
+	}
+	return strings.TrimSpace(trimmed)
+}
+
+// ValidateJSON checks that data is JSON matching schema. It supports the
+// subset of JSON Schema used for structured output: type (including type
+// lists and nullable), properties, required, additionalProperties, items,
+// enum, minItems, maxItems and anyOf.
+func ValidateJSON(data string, schema map[string]interface{}) error {
+	var value interface{}
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		return fmt.Errorf("not valid JSON: %w", err)
+	}
+	if schema == nil {
+		return nil
+	}
+
+	// Normalize Go-built schemas ([]string, int, ...) to their JSON form
+	raw, err := json.Marshal(schema)
+	if err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+	var normalized map[string]interface{}
+	if err := json.Unmarshal(raw, &normalized); err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+
+	return validateValue("$", value, normalized)
+}
+
+// validateValue validates one decoded JSON value against a normalized schema
+func validateValue(path string, value interface{}, schema map[string]interface{}) error {
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		for _, option := range anyOf {
+			if sub, ok := option.(map[string]interface{}); ok && validateValue(path, value, sub) == nil {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s: does not match any allowed schema", path)
+	}
+
+	if types := schemaTypes(schema); len(types) > 0 && !matchesType(value, types) {
+		return fmt.Errorf("%s: expected %s, got %s", path, strings.Join(types, " or "), jsonType(value))
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			if reflect.DeepEqual(allowed, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: %v is not one of the allowed values", path, value)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return validateObject(path, v, schema)
+	case []interface{}:
+		if min, ok := schema["minItems"].(float64); ok && float64(len(v)) < min {
+			return fmt.Errorf("%s: expected at least %d items, got %d", path, int(min), len(v))
+		}
+		if max, ok := schema["maxItems"].(float64); ok && float64(len(v)) > max {
+			return fmt.Errorf("%s: expected at most %d items, got %d", path, int(max), len(v))
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				if err := validateValue(fmt.Sprintf("%s[%d]", path, i), item, items); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validateObject checks required, properties and additionalProperties
+func validateObject(path string, obj map[string]interface{}, schema map[string]interface{}) error {
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			key, _ := name.(string)
+			if _, present := obj[key]; !present {
+				return fmt.Errorf("%s: missing required property %q", path, key)
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		sub, known := properties[key].(map[string]interface{})
+		if !known {
+			if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+				return fmt.Errorf("%s: unexpected property %q", path, key)
+			}
+			continue
+		}
+		if err := validateValue(path+"."+key, obj[key], sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// schemaTypes returns the allowed JSON types of a schema
+func schemaTypes(schema map[string]interface{}) []string {
+	var types []string
+	switch t := schema["type"].(type) {
+	case string:
+		types = []string{t}
+	case []interface{}:
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+	}
+	if nullable, _ := schema["nullable"].(bool); nullable && len(types) > 0 {
+		types = append(types, "null")
+	}
+	return types
+}
+
+// matchesType reports whether value is one of the JSON types
+func matchesType(value interface{}, types []string) bool {
+	actual := jsonType(value)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType names the JSON type of a decoded value; whole numbers are integers
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
@@ -0,0 +1,127 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var taskSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"tasks": map[string]interface{}{
+			"type":     "array",
+			"minItems": 1,
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"title":    map[string]interface{}{"type": "string"},
+					"priority": map[string]interface{}{"type": "string", "enum": []string{"low", "high"}},
+					"estimate": map[string]interface{}{"type": []string{"integer", "null"}},
+				},
+				"required":             []string{"title"},
+				"additionalProperties": false,
+			},
+		},
+	},
+	"required": []string{"tasks"},
+}
+
+func TestValidateJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"valid", `{"tasks":[{"title":"Write tests","priority":"high","estimate":3}]}`, ""},
+		{"nullable estimate", `{"tasks":[{"title":"Ship","estimate":null}]}`, ""},
+		{"not JSON", `tasks: none`, "not valid JSON"},
+		{"missing required", `{}`, `$: missing required property "tasks"`},
+		{"too few items", `{"tasks":[]}`, "$.tasks: expected at least 1 items"},
+		{"wrong type", `{"tasks":[{"title":42}]}`, "$.tasks[0].title: expected string, got integer"},
+		{"enum", `{"tasks":[{"title":"x","priority":"urgent"}]}`, "$.tasks[0].priority: urgent is not one of the allowed values"},
+		{"fractional integer", `{"tasks":[{"title":"x","estimate":1.5}]}`, "expected integer or null, got number"},
+		{"extra property", `{"tasks":[{"title":"x","owner":"me"}]}`, `$.tasks[0]: unexpected property "owner"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateJSON(tt.data, taskSchema)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected valid, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestStripCodeFence(t *testing.T) {
+	if got := stripCodeFence("```json\n{\"a\":1}\n```"); got != `{"a":1}` {
+		t.Errorf("Expected fence removed, got %q", got)
+	}
+	if got := stripCodeFence(`{"a":1}`); got != `{"a":1}` {
+		t.Errorf("Expected plain JSON unchanged, got %q", got)
+	}
+}
+
+// scriptedProvider returns one canned reply per call and records requests
+type scriptedProvider struct {
+	MockProvider
+	replies  []string
+	requests []*ChatRequest
+}
+
+func (s *scriptedProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	s.requests = append(s.requests, req)
+	reply := s.replies[len(s.requests)-1]
+	return &ChatResponse{Choices: []Choice{{Message: Message{Role: "assistant", Content: reply}}}}, nil
+}
+
+func TestChatStructuredRepairs(t *testing.T) {
+	provider := &scriptedProvider{replies: []string{
+		`{"tasks":[{"title":7}]}`,
+		"```json\n{\"tasks\":[{\"title\":\"Write tests\"}]}\n```",
+	}}
+	req := &ChatRequest{
+		Messages:       []Message{{Role: "user", Content: "List the tasks"}},
+		ResponseFormat: &ResponseFormat{Type: ResponseFormatJSONSchema, Name: "tasks", Schema: taskSchema},
+	}
+
+	resp, err := ChatStructured(context.Background(), provider, req)
+	if err != nil {
+		t.Fatalf("ChatStructured() error = %v", err)
+	}
+	if resp.Choices[0].Message.Content != `{"tasks":[{"title":"Write tests"}]}` {
+		t.Errorf("Expected repaired JSON without fence, got %s", resp.Choices[0].Message.Content)
+	}
+
+	if len(provider.requests) != 2 {
+		t.Fatalf("Expected one repair retry, got %d requests", len(provider.requests))
+	}
+	repair := provider.requests[1].Messages
+	if len(repair) != 3 || !strings.Contains(repair[2].Content, "expected string, got integer") {
+		t.Errorf("Expected repair prompt with the validation error, got %+v", repair)
+	}
+	if len(req.Messages) != 1 {
+		t.Error("Expected caller's messages to be left untouched")
+	}
+}
+
+func TestChatStructuredGivesUp(t *testing.T) {
+	provider := &scriptedProvider{replies: []string{"not json", "still not json"}}
+	req := &ChatRequest{ResponseFormat: &ResponseFormat{Type: ResponseFormatJSONObject}}
+
+	_, err := ChatStructured(context.Background(), provider, req)
+	if !errors.Is(err, ErrInvalidStructuredOutput) {
+		t.Errorf("Expected ErrInvalidStructuredOutput, got %v", err)
+	}
+	if len(provider.requests) != 2 {
+		t.Errorf("Expected exactly one retry, got %d requests", len(provider.requests))
+	}
+}
@@ -0,0 +1,408 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// traceBodyLimit caps each recorded request or response body
+const traceBodyLimit = 1 << 20
+
+// TraceExchange is one provider HTTP call as written to the trace file.
+// Credentials in headers and query parameters are redacted.
+type TraceExchange struct {
+	ID              string          `json:"id"`
+	Time            time.Time       `json:"time"`
+	Provider        string          `json:"provider"`
+	Method          string          `json:"method"`
+	URL             string          `json:"url"`
+	RequestHeaders  http.Header     `json:"request_headers,omitempty"`
+	RequestBody     json.RawMessage `json:"request_body,omitempty"`
+	Status          int             `json:"status,omitempty"`
+	ResponseHeaders http.Header     `json:"response_headers,omitempty"`
+	ResponseBody    json.RawMessage `json:"response_body,omitempty"`
+	// Frames holds the lines of a streamed (SSE or NDJSON) response
+	Frames     []string `json:"frames,omitempty"`
+	DurationMS int64    `json:"duration_ms"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// TraceSink appends exchanges to a JSONL file, rotating it when it grows
+// past the size limit
+type TraceSink struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+var (
+	traceMu   sync.RWMutex
+	traceSink *TraceSink
+)
+
+// EnableTracing starts writing provider HTTP traffic to path. maxSizeMB
+// bounds each file (0 means 10MB) and maxBackups is how many rotated files
+// are kept. Calling it again with the same path is a no-op.
+func EnableTracing(path string, maxSizeMB, maxBackups int) error {
+	traceMu.Lock()
+	defer traceMu.Unlock()
+
+	if traceSink != nil && traceSink.path == path {
+		return nil
+	}
+	if maxSizeMB <= 0 {
+		maxSizeMB = 10
+	}
+
+	sink := &TraceSink{path: path, maxSize: int64(maxSizeMB) << 20, maxBackups: maxBackups}
+	if err := sink.open(); err != nil {
+		return err
+	}
+	if traceSink != nil {
+		traceSink.Close()
+	}
+	traceSink = sink
+	return nil
+}
+
+// DisableTracing stops tracing and closes the trace file
+func DisableTracing() {
+	traceMu.Lock()
+	defer traceMu.Unlock()
+	if traceSink != nil {
+		traceSink.Close()
+		traceSink = nil
+	}
+}
+
+func currentTraceSink() *TraceSink {
+	traceMu.RLock()
+	defer traceMu.RUnlock()
+	return traceSink
+}
+
+func (s *TraceSink) open() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create trace directory: %w", err)
+	}
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open trace file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open trace file: %w", err)
+	}
+	s.file, s.size = file, info.Size()
+	return nil
+}
+
+// Write appends an exchange; tracing failures never fail the request
+func (s *TraceSink) Write(exchange *TraceExchange) {
+	data, err := json.Marshal(exchange)
+	if err != nil {
+		return
+	}
+	data = append(data, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return
+	}
+	if s.size > 0 && s.size+int64(len(data)) > s.maxSize {
+		s.rotate()
+	}
+	n, _ := s.file.Write(data)
+	s.size += int64(n)
+}
+
+// rotate shifts trace.jsonl to trace.jsonl.1, .1 to .2 and so on, dropping
+// the oldest, then starts a new file
+func (s *TraceSink) rotate() {
+	s.file.Close()
+	s.file = nil
+
+	if s.maxBackups > 0 {
+		os.Remove(fmt.Sprintf("%s.%d", s.path, s.maxBackups))
+		for i := s.maxBackups - 1; i > 0; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", s.path, i), fmt.Sprintf("%s.%d", s.path, i+1))
+		}
+		os.Rename(s.path, s.path+".1")
+	} else {
+		os.Remove(s.path)
+	}
+	s.open()
+}
+
+// Close closes the trace file
+func (s *TraceSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// ReadTraces returns the exchanges in the trace file and its rotated
+// backups, oldest first
+func ReadTraces(path string) ([]TraceExchange, error) {
+	files, _ := filepath.Glob(path + ".*")
+	sort.Slice(files, func(i, j int) bool {
+		// Higher suffixes are older
+		return traceBackupIndex(path, files[i]) > traceBackupIndex(path, files[j])
+	})
+	files = append(files, path)
+
+	var exchanges []TraceExchange
+	for _, name := range files {
+		file, err := os.Open(name)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to open trace file: %w", err)
+		}
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 0, 64*1024), 16*traceBodyLimit)
+		for scanner.Scan() {
+			var exchange TraceExchange
+			// Skip lines cut short by a crash mid-write
+			if json.Unmarshal(scanner.Bytes(), &exchange) == nil {
+				exchanges = append(exchanges, exchange)
+			}
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read trace file %s: %w", name, err)
+		}
+	}
+	return exchanges, nil
+}
+
+func traceBackupIndex(path, name string) int {
+	var n int
+	fmt.Sscanf(strings.TrimPrefix(name, path+"."), "%d", &n)
+	return n
+}
+
+// FindTrace returns the exchange whose ID starts with id
+func FindTrace(path, id string) (*TraceExchange, error) {
+	exchanges, err := ReadTraces(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var found *TraceExchange
+	for i := range exchanges {
+		if strings.HasPrefix(exchanges[i].ID, id) {
+			if found != nil {
+				return nil, fmt.Errorf("trace id %s is ambiguous", id)
+			}
+			found = &exchanges[i]
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("trace %s not found in %s", id, path)
+	}
+	return found, nil
+}
+
+// NewTraceTransport wraps base (http.DefaultTransport when nil) so that,
+// while tracing is enabled, every call is written to the trace file under
+// the provider's name. With tracing off it adds nothing to the request.
+// Headers that look like credentials are redacted, along with
+// secretHeaders, the names a provider is configured to authenticate with.
+func NewTraceTransport(provider string, base http.RoundTripper, secretHeaders ...string) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	secret := make(map[string]bool)
+	for _, name := range secretHeaders {
+		if name != "" {
+			secret[http.CanonicalHeaderKey(name)] = true
+		}
+	}
+	return &traceTransport{provider: provider, base: base, secret: secret}
+}
+
+type traceTransport struct {
+	provider string
+	base     http.RoundTripper
+	// secret holds canonical names of configured credential headers
+	secret map[string]bool
+}
+
+func (t *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	sink := currentTraceSink()
+	if sink == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	exchange := &TraceExchange{
+		ID:             uuid.NewString(),
+		Time:           time.Now(),
+		Provider:       t.provider,
+		Method:         req.Method,
+		URL:            redactURL(req.URL),
+		RequestHeaders: redactHeaders(req.Header, t.secret),
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		// Send a copy so the caller's request isn't modified
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		exchange.RequestBody = traceBody(body)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		exchange.Error = err.Error()
+		exchange.DurationMS = time.Since(exchange.Time).Milliseconds()
+		sink.Write(exchange)
+		return nil, err
+	}
+
+	exchange.Status = resp.StatusCode
+	exchange.ResponseHeaders = redactHeaders(resp.Header, t.secret)
+	resp.Body = &tracedBody{
+		ReadCloser: resp.Body,
+		sink:       sink,
+		exchange:   exchange,
+		streamed:   isStreamingContent(resp.Header.Get("Content-Type")),
+	}
+	return resp, nil
+}
+
+// tracedBody copies the response as the provider reads it and writes the
+// exchange at EOF, on a read error or when the body is closed
+type tracedBody struct {
+	io.ReadCloser
+	sink     *TraceSink
+	exchange *TraceExchange
+	streamed bool
+	buf      bytes.Buffer
+	done     bool
+}
+
+func (b *tracedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	// One byte past the limit marks the body as truncated
+	if room := traceBodyLimit + 1 - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(n, room)])
+	}
+	if err != nil {
+		if err != io.EOF {
+			b.exchange.Error = err.Error()
+		}
+		b.finish()
+	}
+	return n, err
+}
+
+func (b *tracedBody) Close() error {
+	b.finish()
+	return b.ReadCloser.Close()
+}
+
+func (b *tracedBody) finish() {
+	if b.done {
+		return
+	}
+	b.done = true
+
+	b.exchange.DurationMS = time.Since(b.exchange.Time).Milliseconds()
+	if b.streamed {
+		for _, line := range strings.Split(b.buf.String(), "\n") {
+			if line = strings.TrimRight(line, "\r"); line != "" {
+				b.exchange.Frames = append(b.exchange.Frames, line)
+			}
+		}
+	} else {
+		b.exchange.ResponseBody = traceBody(b.buf.Bytes())
+	}
+	b.sink.Write(b.exchange)
+}
+
+// traceBody keeps JSON bodies as JSON and stores anything else, or a body
+// cut at the size limit, as a string
+func traceBody(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	if len(body) <= traceBodyLimit && json.Valid(body) {
+		return json.RawMessage(body)
+	}
+	if len(body) > traceBodyLimit {
+		body = append(body[:traceBodyLimit:traceBodyLimit], "...[truncated]"...)
+	}
+	quoted, _ := json.Marshal(string(body))
+	return quoted
+}
+
+func isStreamingContent(contentType string) bool {
+	return strings.HasPrefix(contentType, "text/event-stream") ||
+		strings.HasPrefix(contentType, "application/x-ndjson")
+}
+
+// isSecretName reports whether a header or query parameter name looks like
+// it carries credentials
+func isSecretName(name string) bool {
+	name = strings.ToLower(name)
+	for _, marker := range []string{"auth", "key", "token", "secret", "cookie", "password", "signature", "credential"} {
+		if strings.Contains(name, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactHeaders(header http.Header, secret map[string]bool) http.Header {
+	redacted := make(http.Header, len(header))
+	for name, values := range header {
+		if isSecretName(name) || secret[http.CanonicalHeaderKey(name)] {
+			values = []string{"[REDACTED]"}
+		}
+		redacted[name] = values
+	}
+	return redacted
+}
+
+func redactURL(u *url.URL) string {
+	copied := *u
+	copied.User = nil
+	query := copied.Query()
+	for name := range query {
+		if isSecretName(name) {
+			query.Set(name, "REDACTED")
+		}
+	}
+	copied.RawQuery = query.Encode()
+	return copied.String()
+}
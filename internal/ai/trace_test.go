@@ -0,0 +1,137 @@
+package ai
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTraceTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"model":"m"}` {
+			t.Errorf("Expected the request body to reach the server, got %s", body)
+		}
+		if strings.HasSuffix(r.URL.Path, "/stream") {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"a\":1}\n\ndata: [DONE]\n\n")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":"bad"}`)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "trace.jsonl")
+	if err := EnableTracing(path, 1, 1); err != nil {
+		t.Fatalf("EnableTracing() error = %v", err)
+	}
+	defer DisableTracing()
+
+	client := &http.Client{Transport: NewTraceTransport("Test", nil, "X-Gateway-Pass")}
+	for _, endpoint := range []string{"/chat?key=sk-123&alt=sse", "/stream"} {
+		req, _ := http.NewRequest("POST", server.URL+endpoint, strings.NewReader(`{"model":"m"}`))
+		req.Header.Set("Authorization", "Bearer sk-123")
+		req.Header.Set("x-api-key", "sk-123")
+		req.Header.Set("X-Gateway-Credential", "sk-123")
+		req.Header.Set("x-gateway-pass", "sk-123") // the configured auth header
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "sk-123") || strings.Contains(string(data), "session=secret") {
+		t.Errorf("Expected credentials to be redacted, got %s", data)
+	}
+
+	exchanges, err := ReadTraces(path)
+	if err != nil {
+		t.Fatalf("ReadTraces() error = %v", err)
+	}
+	if len(exchanges) != 2 {
+		t.Fatalf("Expected 2 exchanges, got %d", len(exchanges))
+	}
+
+	chat, stream := exchanges[0], exchanges[1]
+	if chat.Provider != "Test" || chat.Status != 400 || string(chat.RequestBody) != `{"model":"m"}` || string(chat.ResponseBody) != `{"error":"bad"}` {
+		t.Errorf("Unexpected chat trace: %+v", chat)
+	}
+	if !strings.Contains(chat.URL, "key=REDACTED") || !strings.Contains(chat.URL, "alt=sse") {
+		t.Errorf("Expected the key query parameter redacted, got %s", chat.URL)
+	}
+	if chat.RequestHeaders.Get("Authorization") != "[REDACTED]" {
+		t.Errorf("Expected Authorization redacted, got %q", chat.RequestHeaders.Get("Authorization"))
+	}
+	if len(stream.Frames) != 2 || stream.Frames[1] != "data: [DONE]" || stream.ResponseBody != nil {
+		t.Errorf("Expected SSE frames, got %+v", stream)
+	}
+
+	found, err := FindTrace(path, stream.ID[:8])
+	if err != nil || found.ID != stream.ID {
+		t.Errorf("FindTrace() by prefix = %v, %v", found, err)
+	}
+	if _, err := FindTrace(path, "nope"); err == nil {
+		t.Error("Expected an error for an unknown id")
+	}
+}
+
+func TestTraceTransportDisabled(t *testing.T) {
+	DisableTracing()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewTraceTransport("Test", nil)}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if _, ok := resp.Body.(*tracedBody); ok {
+		t.Error("Expected no tracing while disabled")
+	}
+}
+
+func TestTraceSinkRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.jsonl")
+	sink := &TraceSink{path: path, maxSize: 300, maxBackups: 2}
+	if err := sink.open(); err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	for i := 0; i < 8; i++ {
+		sink.Write(&TraceExchange{ID: fmt.Sprintf("trace-%d", i), Method: "POST", URL: strings.Repeat("x", 100)})
+	}
+
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("Expected at most 2 backups")
+	}
+	exchanges, err := ReadTraces(path)
+	if err != nil {
+		t.Fatalf("ReadTraces() error = %v", err)
+	}
+	if len(exchanges) == 0 || len(exchanges) >= 8 {
+		t.Fatalf("Expected the oldest traces to be dropped, got %d", len(exchanges))
+	}
+	for i := 1; i < len(exchanges); i++ {
+		if exchanges[i-1].ID >= exchanges[i].ID {
+			t.Errorf("Expected oldest first, got %s before %s", exchanges[i-1].ID, exchanges[i].ID)
+		}
+	}
+	if last := exchanges[len(exchanges)-1].ID; last != "trace-7" {
+		t.Errorf("Expected the newest trace last, got %s", last)
+	}
+}
@@ -0,0 +1,97 @@
+package ai
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// NewHTTPClient returns the HTTP client a provider should use, with the
+// given timeout and the transport built by NewTransport
+func NewHTTPClient(provider string, config map[string]interface{}, timeout time.Duration) (*http.Client, error) {
+	transport, err := NewTransport(provider, config)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}, nil
+}
+
+// NewTransport builds the shared provider transport from the network
+// settings in a provider config, wrapped for tracing.
+//
+// Recognised config keys:
+//   - proxy: proxy URL; when empty HTTPS_PROXY, HTTP_PROXY and NO_PROXY apply
+//   - ca_file: PEM bundle trusted in addition to the system roots
+//   - client_cert, client_key: PEM certificate and key for mutual TLS
+//   - insecure_skip_verify: skip certificate verification (testing only)
+//   - auth_header: custom credential header, redacted in traces
+func NewTransport(provider string, config map[string]interface{}) (http.RoundTripper, error) {
+	authHeader, _ := config["auth_header"].(string)
+	proxy, _ := config["proxy"].(string)
+	caFile, _ := config["ca_file"].(string)
+	clientCert, _ := config["client_cert"].(string)
+	clientKey, _ := config["client_key"].(string)
+	insecure, _ := config["insecure_skip_verify"].(bool)
+
+	// Without custom settings share the default transport's connection pool
+	if proxy == "" && caFile == "" && clientCert == "" && clientKey == "" && !insecure {
+		return NewTraceTransport(provider, nil, authHeader), nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil || proxyURL.Scheme == "" || proxyURL.Host == "" {
+			return nil, fmt.Errorf("%s: invalid proxy URL %q", provider, proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: insecure}
+
+	if caFile != "" {
+		pem, err := os.ReadFile(expandPath(caFile))
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to read CA file: %w", provider, err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s: no certificates found in CA file %s", provider, caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if clientCert != "" || clientKey != "" {
+		if clientCert == "" || clientKey == "" {
+			return nil, fmt.Errorf("%s: client_cert and client_key must be set together", provider)
+		}
+		cert, err := tls.LoadX509KeyPair(expandPath(clientCert), expandPath(clientKey))
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to load client certificate: %w", provider, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport.TLSClientConfig = tlsConfig
+	return NewTraceTransport(provider, transport, authHeader), nil
+}
+
+// expandPath expands a leading ~/ to the home directory
+func expandPath(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
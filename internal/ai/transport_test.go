@@ -0,0 +1,188 @@
+package ai
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeServerCA writes the test server's certificate as a PEM CA bundle
+func writeServerCA(t *testing.T, server *httptest.Server) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// writeClientCert writes a self-signed client certificate and key
+func writeClientCert(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "rubrduck-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return certFile, keyFile
+}
+
+func get(t *testing.T, client *http.Client, url string) (string, error) {
+	t.Helper()
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return string(body), nil
+}
+
+func TestNewHTTPClientTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+	caFile := writeServerCA(t, server)
+
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr bool
+	}{
+		{"system roots only", map[string]interface{}{}, true},
+		{"custom CA", map[string]interface{}{"ca_file": caFile}, false},
+		{"insecure", map[string]interface{}{"insecure_skip_verify": true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewHTTPClient("Test", tt.config, 5*time.Second)
+			if err != nil {
+				t.Fatalf("NewHTTPClient() error = %v", err)
+			}
+			body, err := get(t, client, server.URL)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if !tt.wantErr && body != "ok" {
+				t.Errorf("Expected ok, got %q", body)
+			}
+		})
+	}
+}
+
+func TestNewHTTPClientMutualTLS(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "hello %s", r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+	caFile := writeServerCA(t, server)
+
+	client, err := NewHTTPClient("Test", map[string]interface{}{"ca_file": caFile}, 5*time.Second)
+	if err != nil {
+		t.Fatalf("NewHTTPClient() error = %v", err)
+	}
+	if _, err := get(t, client, server.URL); err == nil {
+		t.Error("Expected the server to reject a client without a certificate")
+	}
+
+	certFile, keyFile := writeClientCert(t)
+	client, err = NewHTTPClient("Test", map[string]interface{}{
+		"ca_file":     caFile,
+		"client_cert": certFile,
+		"client_key":  keyFile,
+	}, 5*time.Second)
+	if err != nil {
+		t.Fatalf("NewHTTPClient() error = %v", err)
+	}
+	body, err := get(t, client, server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if body != "hello rubrduck-test" {
+		t.Errorf("Expected the client certificate to be presented, got %q", body)
+	}
+}
+
+func TestNewHTTPClientProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		fmt.Fprint(w, "via proxy")
+	}))
+	defer proxy.Close()
+
+	client, err := NewHTTPClient("Test", map[string]interface{}{"proxy": proxy.URL}, 5*time.Second)
+	if err != nil {
+		t.Fatalf("NewHTTPClient() error = %v", err)
+	}
+	body, err := get(t, client, "http://api.example.invalid/v1/models")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if body != "via proxy" || proxied != "http://api.example.invalid/v1/models" {
+		t.Errorf("Expected the request to go through the proxy, got %q for %q", body, proxied)
+	}
+}
+
+func TestNewHTTPClientInvalidConfig(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.pem")
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	os.WriteFile(empty, []byte("not a certificate"), 0600)
+
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+		want   string
+	}{
+		{"bad proxy", map[string]interface{}{"proxy": "localhost:3128"}, "invalid proxy URL"},
+		{"missing CA", map[string]interface{}{"ca_file": missing}, "failed to read CA file"},
+		{"empty CA", map[string]interface{}{"ca_file": empty}, "no certificates found"},
+		{"cert without key", map[string]interface{}{"client_cert": missing}, "must be set together"},
+		{"missing cert", map[string]interface{}{"client_cert": missing, "client_key": missing}, "failed to load client certificate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewHTTPClient("Test", tt.config, time.Second)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,593 @@
+// Package checkpoint saves the workspace files an agent turn changes so
+// that the whole turn can be rolled back. A turn begins before the agent
+// acts on a prompt: tools call Save before they first change a path, and
+// shell commands run through Track, which scans the workspace right
+// before and after each command to note the files it created. Files the
+// user creates outside those commands are never rolled back. Each
+// checkpoint is kept in its own directory of a per-workspace store, so
+// undo works across sessions.
+package checkpoint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxScanEntries stops the before/after scan in very large trees; files
+// created by shell commands are then not detected
+const maxScanEntries = 100000
+
+var (
+	// ErrNoCheckpoints is returned by Undo when there is nothing to undo
+	ErrNoCheckpoints = errors.New("no checkpoints to restore")
+	// ErrTurnInProgress is returned when restoring while a turn is open
+	ErrTurnInProgress = errors.New("cannot restore while the agent is working")
+)
+
+// Entry is one path a turn changed and what it was before
+type Entry struct {
+	// Path is relative to the workspace
+	Path    string      `json:"path"`
+	Dir     bool        `json:"dir,omitempty"`
+	Existed bool        `json:"existed"`
+	Mode    fs.FileMode `json:"mode,omitempty"`
+	// Blob names the saved content of a file that existed
+	Blob string `json:"blob,omitempty"`
+}
+
+// Checkpoint is the state of the paths one turn changed, before the turn
+type Checkpoint struct {
+	ID      int       `json:"id"`
+	Time    time.Time `json:"time"`
+	Label   string    `json:"label"`
+	Entries []Entry   `json:"entries"`
+}
+
+// Files returns the paths of the files, not directories, in the checkpoint
+func (c Checkpoint) Files() []string {
+	var files []string
+	for _, e := range c.Entries {
+		if !e.Dir {
+			files = append(files, e.Path)
+		}
+	}
+	return files
+}
+
+// Summary describes the checkpoint in one line for listings
+func (c Checkpoint) Summary() string {
+	files := len(c.Files())
+	noun := "files"
+	if files == 1 {
+		noun = "file"
+	}
+	label := c.Label
+	if label == "" {
+		label = "(no prompt)"
+	}
+	return fmt.Sprintf("#%d  %s  %d %s  %s", c.ID, c.Time.Local().Format("2006-01-02 15:04"), files, noun, label)
+}
+
+// Describe lists the turns and files a rollback put back
+func Describe(undone []Checkpoint) string {
+	seen := make(map[string]bool)
+	var files []string
+	for _, cp := range undone {
+		for _, f := range cp.Files() {
+			if !seen[f] {
+				seen[f] = true
+				files = append(files, f)
+			}
+		}
+	}
+
+	turns := "1 turn"
+	if len(undone) != 1 {
+		turns = fmt.Sprintf("%d turns", len(undone))
+	}
+	noun := "files"
+	if len(files) == 1 {
+		noun = "file"
+	}
+	return fmt.Sprintf("%s, putting back %d %s:\n  %s", turns, len(files), noun, strings.Join(files, "\n  "))
+}
+
+// Manager keeps the checkpoints of one workspace
+type Manager struct {
+	root string
+	dir  string
+	keep int
+
+	mu   sync.Mutex
+	turn *turn
+}
+
+// turn is the checkpoint being collected
+type turn struct {
+	cp    Checkpoint
+	saved map[string]bool
+}
+
+// Open returns the manager for workspace, storing its checkpoints in a
+// directory of baseDir named after the workspace path. At most keep
+// checkpoints are kept; 0 keeps them all.
+func Open(baseDir, workspace string, keep int) (*Manager, error) {
+	root, err := filepath.Abs(workspace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve workspace: %w", err)
+	}
+	sum := sha256.Sum256([]byte(root))
+	dir := filepath.Join(baseDir, hex.EncodeToString(sum[:8]))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+	// Name the workspace for anyone browsing the store
+	os.WriteFile(filepath.Join(dir, "workspace"), []byte(root+"\n"), 0600)
+
+	return &Manager{root: root, dir: dir, keep: keep}, nil
+}
+
+// Root returns the workspace directory
+func (m *Manager) Root() string {
+	return m.root
+}
+
+// Begin starts collecting a checkpoint for a turn labelled with the
+// user's prompt, ending any turn still open
+func (m *Manager) Begin(label string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.turn != nil {
+		if _, err := m.end(); err != nil {
+			return err
+		}
+	}
+	ids, err := m.ids()
+	if err != nil {
+		return err
+	}
+	id := 1
+	if len(ids) > 0 {
+		id = ids[len(ids)-1] + 1
+	}
+
+	m.turn = &turn{
+		cp:    Checkpoint{ID: id, Time: time.Now(), Label: label},
+		saved: make(map[string]bool),
+	}
+	return nil
+}
+
+// Save records path as it is now, the first time a turn is about to
+// change it. Paths outside the workspace and calls outside a turn are
+// ignored.
+func (m *Manager) Save(path string) error {
+	return m.saveAt(path, false)
+}
+
+// SaveDir is Save for a path where a directory is about to be created, so
+// that undo removes it as a directory
+func (m *Manager) SaveDir(path string) error {
+	return m.saveAt(path, true)
+}
+
+func (m *Manager) saveAt(path string, dir bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.turn == nil {
+		return nil
+	}
+	rel, ok := m.rel(path)
+	if !ok || m.turn.saved[rel] {
+		return nil
+	}
+	return m.save(rel, dir)
+}
+
+// save records rel; dir marks a missing path the change will make a
+// directory
+func (m *Manager) save(rel string, dir bool) error {
+	full := filepath.Join(m.root, rel)
+	info, err := os.Lstat(full)
+	if os.IsNotExist(err) {
+		// Note missing parents first, so undo removes the directories the
+		// turn had to create after the files in them
+		if parent := filepath.Dir(rel); parent != "." && !m.turn.saved[parent] {
+			if err := m.save(parent, true); err != nil {
+				return err
+			}
+		}
+		m.turn.saved[rel] = true
+		m.turn.cp.Entries = append(m.turn.cp.Entries, Entry{Path: rel, Dir: dir})
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to checkpoint %s: %w", rel, err)
+	}
+
+	m.turn.saved[rel] = true
+	entry := Entry{Path: rel, Existed: true, Mode: info.Mode().Perm()}
+	switch {
+	case info.IsDir():
+		entry.Dir = true
+	case info.Mode().IsRegular():
+		entry.Blob = strconv.Itoa(len(m.turn.cp.Entries))
+		if err := m.copyBlob(full, entry.Blob); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("cannot checkpoint %s: not a regular file", rel)
+	}
+	m.turn.cp.Entries = append(m.turn.cp.Entries, entry)
+	return nil
+}
+
+// copyBlob saves a file's content in the open turn's directory
+func (m *Manager) copyBlob(path, blob string) error {
+	dir := m.checkpointDir(m.turn.cp.ID)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create checkpoint: %w", err)
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to checkpoint %s: %w", path, err)
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(filepath.Join(dir, blob), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create checkpoint: %w", err)
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return fmt.Errorf("failed to checkpoint %s: %w", path, err)
+	}
+	return dst.Close()
+}
+
+// Track runs a shell command for the open turn, noting the files and
+// directories that appear while it runs so undo removes them. Outside a
+// turn, run is only called. Files a command edits in place are not
+// captured.
+func (m *Manager) Track(run func() error) error {
+	m.mu.Lock()
+	open := m.turn != nil
+	m.mu.Unlock()
+	if !open {
+		return run()
+	}
+
+	before := m.scan()
+	err := run()
+	if before == nil {
+		return err
+	}
+	after := m.scan()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.turn == nil {
+		return err
+	}
+	var created []string
+	for path := range after {
+		if !before[path] && !m.turn.saved[path] {
+			created = append(created, path)
+		}
+	}
+	// Parents sort before their children
+	sort.Strings(created)
+	for _, path := range created {
+		info, statErr := os.Lstat(filepath.Join(m.root, path))
+		m.turn.saved[path] = true
+		m.turn.cp.Entries = append(m.turn.cp.Entries, Entry{Path: path, Dir: statErr == nil && info.IsDir()})
+	}
+	return err
+}
+
+// End finishes the open turn and stores its checkpoint. It returns nil when
+// the turn changed nothing.
+func (m *Manager) End() (*Checkpoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.end()
+}
+
+func (m *Manager) end() (*Checkpoint, error) {
+	t := m.turn
+	if t == nil {
+		return nil, nil
+	}
+	m.turn = nil
+
+	dir := m.checkpointDir(t.cp.ID)
+	if len(t.cp.Entries) == 0 {
+		os.RemoveAll(dir)
+		return nil, nil
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint: %w", err)
+	}
+	data, err := json.MarshalIndent(t.cp, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "checkpoint.json"), data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	m.prune()
+	return &t.cp, nil
+}
+
+// List returns the stored checkpoints, oldest first
+func (m *Manager) List() ([]Checkpoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.list()
+}
+
+func (m *Manager) list() ([]Checkpoint, error) {
+	ids, err := m.ids()
+	if err != nil {
+		return nil, err
+	}
+	var checkpoints []Checkpoint
+	for _, id := range ids {
+		data, err := os.ReadFile(filepath.Join(m.checkpointDir(id), "checkpoint.json"))
+		if err != nil {
+			// An unfinished turn from a crashed session
+			continue
+		}
+		var cp Checkpoint
+		if err := json.Unmarshal(data, &cp); err != nil {
+			return nil, fmt.Errorf("invalid checkpoint %d: %w", id, err)
+		}
+		checkpoints = append(checkpoints, cp)
+	}
+	return checkpoints, nil
+}
+
+// Undo rolls back the latest checkpoint and removes it
+func (m *Manager) Undo() (*Checkpoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	checkpoints, err := m.list()
+	if err != nil {
+		return nil, err
+	}
+	if len(checkpoints) == 0 {
+		return nil, ErrNoCheckpoints
+	}
+	latest := checkpoints[len(checkpoints)-1]
+	if err := m.restore([]Checkpoint{latest}); err != nil {
+		return nil, err
+	}
+	return &latest, nil
+}
+
+// Restore returns the workspace to its state before checkpoint id,
+// rolling back that turn and every later one, and removes them. The
+// rolled back checkpoints are returned newest first.
+func (m *Manager) Restore(id int) ([]Checkpoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	checkpoints, err := m.list()
+	if err != nil {
+		return nil, err
+	}
+	var undo []Checkpoint
+	for i := len(checkpoints) - 1; i >= 0 && checkpoints[i].ID >= id; i-- {
+		undo = append(undo, checkpoints[i])
+	}
+	if len(undo) == 0 || undo[len(undo)-1].ID != id {
+		return nil, fmt.Errorf("checkpoint %d not found", id)
+	}
+	if err := m.restore(undo); err != nil {
+		return nil, err
+	}
+	return undo, nil
+}
+
+// restore rolls back checkpoints, newest first. Either every file is
+// restored or, on failure, the files already written are put back.
+func (m *Manager) restore(checkpoints []Checkpoint) error {
+	if m.turn != nil {
+		return ErrTurnInProgress
+	}
+
+	// Keep the current state of every file that is about to change
+	current := make(map[string]*[]byte)
+	modes := make(map[string]fs.FileMode)
+	for _, cp := range checkpoints {
+		for _, e := range cp.Entries {
+			if _, ok := current[e.Path]; ok || e.Dir {
+				continue
+			}
+			full := filepath.Join(m.root, e.Path)
+			data, err := os.ReadFile(full)
+			if err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to read %s: %w", e.Path, err)
+			}
+			if err == nil {
+				current[e.Path] = &data
+				if info, err := os.Stat(full); err == nil {
+					modes[e.Path] = info.Mode().Perm()
+				}
+			} else {
+				current[e.Path] = nil
+			}
+		}
+	}
+
+	for _, cp := range checkpoints {
+		if err := m.apply(cp); err != nil {
+			m.rollback(current, modes)
+			return fmt.Errorf("failed to restore checkpoint %d: %w", cp.ID, err)
+		}
+	}
+	for _, cp := range checkpoints {
+		os.RemoveAll(m.checkpointDir(cp.ID))
+	}
+	return nil
+}
+
+// apply puts back the paths of one checkpoint, children before parents
+func (m *Manager) apply(cp Checkpoint) error {
+	dir := m.checkpointDir(cp.ID)
+	for i := len(cp.Entries) - 1; i >= 0; i-- {
+		e := cp.Entries[i]
+		full := filepath.Join(m.root, e.Path)
+		switch {
+		case e.Dir && e.Existed:
+			if err := os.MkdirAll(full, e.Mode|0700); err != nil {
+				return err
+			}
+		case e.Dir:
+			// Leave directories that now hold files the turn didn't make
+			os.Remove(full)
+		case e.Existed:
+			data, err := os.ReadFile(filepath.Join(dir, e.Blob))
+			if err != nil {
+				return fmt.Errorf("checkpoint content for %s is missing: %w", e.Path, err)
+			}
+			if err := writeFile(full, data, e.Mode); err != nil {
+				return err
+			}
+		default:
+			if err := os.Remove(full); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// rollback puts files back as they were before a failed restore
+func (m *Manager) rollback(current map[string]*[]byte, modes map[string]fs.FileMode) {
+	for path, data := range current {
+		full := filepath.Join(m.root, path)
+		if data == nil {
+			os.Remove(full)
+			continue
+		}
+		writeFile(full, *data, modes[path])
+	}
+}
+
+// writeFile replaces a file through a temporary file in the same
+// directory, so readers never see it half written
+func writeFile(path string, data []byte, mode fs.FileMode) error {
+	if mode == 0 {
+		mode = 0644
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".restore-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// rel returns path relative to the workspace, or false when it is outside
+func (m *Manager) rel(path string) (string, bool) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(m.root, abs)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+// scan lists the workspace's paths, skipping hidden directories such as
+// .git and dependency trees, or returns nil for very large trees
+func (m *Manager) scan() map[string]bool {
+	paths := make(map[string]bool)
+	err := filepath.WalkDir(m.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == m.root {
+			return nil
+		}
+		if d.IsDir() && (strings.HasPrefix(d.Name(), ".") || d.Name() == "node_modules") {
+			return filepath.SkipDir
+		}
+		if len(paths) >= maxScanEntries {
+			return fs.SkipAll
+		}
+		rel, _ := filepath.Rel(m.root, path)
+		paths[rel] = true
+		return nil
+	})
+	if err != nil || len(paths) >= maxScanEntries {
+		return nil
+	}
+	return paths
+}
+
+// ids returns the IDs of the stored checkpoints, ascending
+func (m *Manager) ids() ([]int, error) {
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoints: %w", err)
+	}
+	var ids []int
+	for _, e := range entries {
+		if id, err := strconv.Atoi(e.Name()); err == nil && e.IsDir() {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids, nil
+}
+
+// prune drops the oldest checkpoints beyond the limit
+func (m *Manager) prune() {
+	if m.keep <= 0 {
+		return
+	}
+	ids, err := m.ids()
+	if err != nil {
+		return
+	}
+	for len(ids) > m.keep {
+		os.RemoveAll(m.checkpointDir(ids[0]))
+		ids = ids[1:]
+	}
+}
+
+func (m *Manager) checkpointDir(id int) string {
+	return filepath.Join(m.dir, strconv.Itoa(id))
+}
@@ -0,0 +1,205 @@
+package checkpoint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestUndoRestoresChangedAndCreatedFiles(t *testing.T) {
+	ws := t.TempDir()
+	writeTestFile(t, filepath.Join(ws, "main.go"), "package main\n")
+
+	m, err := Open(t.TempDir(), ws, 10)
+	require.NoError(t, err)
+
+	require.NoError(t, m.Begin("add a feature"))
+	// A tool edit, a new file in a new directory, and a file the tools
+	// didn't save, such as one the user or a shell command created
+	require.NoError(t, m.Save(filepath.Join(ws, "main.go")))
+	writeTestFile(t, filepath.Join(ws, "main.go"), "package main\n\nfunc main() {}\n")
+	require.NoError(t, m.Save(filepath.Join(ws, "pkg", "util", "util.go")))
+	writeTestFile(t, filepath.Join(ws, "pkg", "util", "util.go"), "package util\n")
+	writeTestFile(t, filepath.Join(ws, "build.log"), "ok\n")
+	cp, err := m.End()
+	require.NoError(t, err)
+	require.NotNil(t, cp)
+	assert.Equal(t, "add a feature", cp.Label)
+	assert.ElementsMatch(t, []string{"main.go", filepath.Join("pkg", "util", "util.go")}, cp.Files())
+
+	undone, err := m.Undo()
+	require.NoError(t, err)
+	assert.Equal(t, cp.ID, undone.ID)
+
+	assert.Equal(t, "package main\n", readTestFile(t, filepath.Join(ws, "main.go")))
+	assert.Equal(t, "ok\n", readTestFile(t, filepath.Join(ws, "build.log")), "only saved paths are rolled back")
+	assert.NoDirExists(t, filepath.Join(ws, "pkg"))
+
+	_, err = m.Undo()
+	assert.ErrorIs(t, err, ErrNoCheckpoints)
+}
+
+func TestTrackNotesFilesCreatedByCommands(t *testing.T) {
+	ws := t.TempDir()
+	writeTestFile(t, filepath.Join(ws, "existing.txt"), "before")
+
+	m, err := Open(t.TempDir(), ws, 10)
+	require.NoError(t, err)
+
+	// Outside a turn nothing is recorded
+	require.NoError(t, m.Track(func() error { return nil }))
+
+	require.NoError(t, m.Begin("build"))
+	writeTestFile(t, filepath.Join(ws, "user.txt"), "typed by the user")
+	require.NoError(t, m.Track(func() error {
+		writeTestFile(t, filepath.Join(ws, "out", "build.log"), "ok\n")
+		writeTestFile(t, filepath.Join(ws, "existing.txt"), "edited in place")
+		return nil
+	}))
+	writeTestFile(t, filepath.Join(ws, "later.txt"), "also the user")
+	cp, err := m.End()
+	require.NoError(t, err)
+	require.NotNil(t, cp)
+	assert.Equal(t, []string{filepath.Join("out", "build.log")}, cp.Files())
+
+	_, err = m.Undo()
+	require.NoError(t, err)
+	assert.NoDirExists(t, filepath.Join(ws, "out"))
+	assert.FileExists(t, filepath.Join(ws, "user.txt"))
+	assert.FileExists(t, filepath.Join(ws, "later.txt"))
+	assert.Equal(t, "edited in place", readTestFile(t, filepath.Join(ws, "existing.txt")))
+}
+
+func TestEmptyTurnIsNotStored(t *testing.T) {
+	ws := t.TempDir()
+	m, err := Open(t.TempDir(), ws, 10)
+	require.NoError(t, err)
+
+	require.NoError(t, m.Begin("just a question"))
+	cp, err := m.End()
+	require.NoError(t, err)
+	assert.Nil(t, cp)
+
+	list, err := m.List()
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func TestRestoreRollsBackLaterTurns(t *testing.T) {
+	ws := t.TempDir()
+	path := filepath.Join(ws, "notes.txt")
+	writeTestFile(t, path, "v1")
+
+	m, err := Open(t.TempDir(), ws, 10)
+	require.NoError(t, err)
+
+	for _, content := range []string{"v2", "v3", "v4"} {
+		require.NoError(t, m.Begin("write "+content))
+		require.NoError(t, m.Save(path))
+		// Only the first save in a turn is kept
+		require.NoError(t, m.Save(path))
+		writeTestFile(t, path, content)
+		_, err := m.End()
+		require.NoError(t, err)
+	}
+
+	list, err := m.List()
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+
+	undone, err := m.Restore(list[1].ID)
+	require.NoError(t, err)
+	require.Len(t, undone, 2)
+	assert.Equal(t, list[2].ID, undone[0].ID)
+	assert.Equal(t, "v2", readTestFile(t, path))
+
+	list, err = m.List()
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+
+	_, err = m.Restore(42)
+	assert.Error(t, err)
+}
+
+func TestRestoreIsAllOrNothing(t *testing.T) {
+	ws := t.TempDir()
+	a, b := filepath.Join(ws, "a.txt"), filepath.Join(ws, "b.txt")
+	writeTestFile(t, a, "a1")
+	writeTestFile(t, b, "b1")
+
+	m, err := Open(t.TempDir(), ws, 10)
+	require.NoError(t, err)
+
+	require.NoError(t, m.Begin("edit both"))
+	require.NoError(t, m.Save(a))
+	require.NoError(t, m.Save(b))
+	writeTestFile(t, a, "a2")
+	writeTestFile(t, b, "b2")
+	cp, err := m.End()
+	require.NoError(t, err)
+
+	// Lose the saved copy of a.txt; b.txt is restored first and must be
+	// put back when a.txt fails
+	require.NoError(t, os.Remove(filepath.Join(m.checkpointDir(cp.ID), cp.Entries[0].Blob)))
+	_, err = m.Undo()
+	require.Error(t, err)
+
+	assert.Equal(t, "a2", readTestFile(t, a))
+	assert.Equal(t, "b2", readTestFile(t, b))
+	list, err := m.List()
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+}
+
+func TestSaveIgnoresPathsOutsideWorkspaceOrTurn(t *testing.T) {
+	ws := t.TempDir()
+	outside := filepath.Join(t.TempDir(), "other.txt")
+	writeTestFile(t, outside, "x")
+
+	m, err := Open(t.TempDir(), ws, 10)
+	require.NoError(t, err)
+	require.NoError(t, m.Save(filepath.Join(ws, "a.txt")))
+
+	require.NoError(t, m.Begin("outside"))
+	require.NoError(t, m.Save(outside))
+	cp, err := m.End()
+	require.NoError(t, err)
+	assert.Nil(t, cp)
+}
+
+func TestCheckpointsArePruned(t *testing.T) {
+	ws := t.TempDir()
+	m, err := Open(t.TempDir(), ws, 2)
+	require.NoError(t, err)
+
+	for i := 0; i < 4; i++ {
+		require.NoError(t, m.Begin("turn"))
+		path := filepath.Join(ws, "f"+string(rune('a'+i)))
+		require.NoError(t, m.Save(path))
+		writeTestFile(t, path, "x")
+		_, err := m.End()
+		require.NoError(t, err)
+	}
+
+	list, err := m.List()
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, 3, list[0].ID)
+	assert.Equal(t, 4, list[1].ID)
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
 )
 
@@ -24,20 +26,49 @@ type SandboxPolicy struct {
 	BlockedEnvVars  []string `mapstructure:"blocked_env_vars"`
 }
 
-// TokenConfig represents token limit configuration
+// TokenConfig represents token limit configuration. Completion and context
+// limits come from the model registry; these settings only lower them.
 type TokenConfig struct {
-	MaxCompletionTokens  int                    `mapstructure:"max_completion_tokens"`
-	MaxContextTokens     int                    `mapstructure:"max_context_tokens"`
-	MaxPlanContentLength int                    `mapstructure:"max_plan_content_length"`
-	ProviderLimits       map[string]TokenLimits `mapstructure:"provider_limits"`
+	MaxCompletionTokens  int `mapstructure:"max_completion_tokens"` // 0 uses the model's limit
+	MaxContextTokens     int `mapstructure:"max_context_tokens"`    // 0 uses the model's limit
+	MaxPlanContentLength int `mapstructure:"max_plan_content_length"`
+	// PromptCaching marks stable prompt prefixes (system prompt, tools,
+	// history) for providers with explicit prompt caching
+	PromptCaching bool `mapstructure:"prompt_caching"`
+	// ProviderLimits is deprecated: limits come from the model registry, and
+	// max_completion_tokens and max_context_tokens above lower them. An entry
+	// for the active provider still applies where those aren't set.
+	ProviderLimits map[string]TokenLimits `mapstructure:"provider_limits"`
 }
 
-// TokenLimits represents provider-specific token limits
+// TokenLimits represents provider-specific token limits. Deprecated along
+// with TokenConfig.ProviderLimits.
 type TokenLimits struct {
 	MaxCompletionTokens int `mapstructure:"max_completion_tokens"`
 	MaxContextTokens    int `mapstructure:"max_context_tokens"`
 }
 
+// ModelConfig adds a model to the model registry or overrides a built-in
+// entry. Unset fields keep the built-in values.
+type ModelConfig struct {
+	ID              string   `mapstructure:"id"`
+	Provider        string   `mapstructure:"provider"`
+	Aliases         []string `mapstructure:"aliases"`
+	ContextWindow   int      `mapstructure:"context_window"`
+	MaxOutputTokens int      `mapstructure:"max_output_tokens"`
+	SupportsTools   *bool    `mapstructure:"supports_tools"`
+	SupportsVision  *bool    `mapstructure:"supports_vision"`
+	// SupportsStreamingTools is false for models that can't call tools while streaming
+	SupportsStreamingTools *bool `mapstructure:"supports_streaming_tools"`
+	// SupportsReasoning enables thinking budgets for the model
+	SupportsReasoning *bool `mapstructure:"supports_reasoning"`
+	// Prices in USD per million tokens
+	InputPrice      *float64 `mapstructure:"input_price"`
+	OutputPrice     *float64 `mapstructure:"output_price"`
+	CacheReadPrice  *float64 `mapstructure:"cache_read_price"`
+	CacheWritePrice *float64 `mapstructure:"cache_write_price"`
+}
+
 // Config represents the complete configuration for RubrDuck
 type Config struct {
 	Provider  string              `mapstructure:"provider"`
@@ -53,6 +84,138 @@ type Config struct {
 	Logging LoggingConfig `mapstructure:"logging"`
 	// Prompts holds configuration for prompt templates
 	Prompts PromptsConfig `mapstructure:"prompts"`
+	// Models extends or overrides the built-in model registry
+	Models []ModelConfig `mapstructure:"models"`
+	// Index configures the semantic code index used by code_search
+	Index IndexConfig `mapstructure:"index"`
+	// Thinking sets reasoning budgets for models that can think
+	Thinking ThinkingConfig `mapstructure:"thinking"`
+	// Record writes every provider request and response to this cassette
+	// file, for playback with the replay provider
+	Record string `mapstructure:"record"`
+	// Trace writes raw provider HTTP traffic to a file for debugging
+	Trace TraceConfig `mapstructure:"trace"`
+	// Usage controls cost tracking and spending limits
+	Usage UsageConfig `mapstructure:"usage"`
+	// Checkpoints keeps the files each agent turn changes so the turn can
+	// be undone
+	Checkpoints CheckpointConfig `mapstructure:"checkpoints"`
+}
+
+// CheckpointConfig controls workspace checkpoints. Before the agent's file
+// tools first change a path in a turn its content is saved, and files that
+// each shell command creates are noted, so /undo and `rubrduck undo` can
+// roll the turn back. Shell edits to existing files aren't captured.
+type CheckpointConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Dir     string `mapstructure:"dir"`  // empty uses ~/.rubrduck/checkpoints
+	Keep    int    `mapstructure:"keep"` // checkpoints kept per workspace
+}
+
+// Path returns the checkpoint directory with ~ expanded
+func (c CheckpointConfig) Path() string {
+	return expandHome(c.Dir, "checkpoints")
+}
+
+// UsageConfig controls cost accounting. Each response's cost is estimated
+// from its token counts and the model registry's prices and appended to a
+// daily file. Limits are in USD; 0 disables a limit. Warn limits show a
+// warning, hard limits refuse new turns until the session or day is over.
+type UsageConfig struct {
+	Track        bool    `mapstructure:"track"`
+	Dir          string  `mapstructure:"dir"` // empty uses ~/.rubrduck/usage
+	SessionWarn  float64 `mapstructure:"session_warn"`
+	SessionLimit float64 `mapstructure:"session_limit"`
+	DailyWarn    float64 `mapstructure:"daily_warn"`
+	DailyLimit   float64 `mapstructure:"daily_limit"`
+}
+
+// Path returns the usage directory with ~ expanded
+func (u UsageConfig) Path() string {
+	return expandHome(u.Dir, "usage")
+}
+
+// TraceConfig controls the provider trace log: every HTTP request and
+// response body (or streamed frames) with credentials redacted, one JSON
+// object per line. Inspect it with `rubrduck trace`.
+type TraceConfig struct {
+	Enabled    bool   `mapstructure:"enabled"`
+	File       string `mapstructure:"file"`     // empty uses ~/.rubrduck/trace.jsonl
+	MaxSize    int    `mapstructure:"max_size"` // megabytes per file before rotating
+	MaxBackups int    `mapstructure:"max_backups"`
+}
+
+// Path returns the trace file location with ~ expanded
+func (t TraceConfig) Path() string {
+	return expandHome(t.File, "trace.jsonl")
+}
+
+// expandHome expands a leading ~/ in path; an empty path means name inside
+// ~/.rubrduck
+func expandHome(path, name string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "."
+	}
+	switch {
+	case path == "":
+		return filepath.Join(home, ".rubrduck", name)
+	case strings.HasPrefix(path, "~/"):
+		return filepath.Join(home, path[2:])
+	default:
+		return path
+	}
+}
+
+// ThinkingConfig sets how many tokens reasoning-capable models may spend
+// thinking before they answer. Budget applies to every mode; a mode's own
+// value replaces it, and a negative value turns thinking off in that mode.
+// Models without reasoning support ignore these settings.
+type ThinkingConfig struct {
+	Budget    int `mapstructure:"budget"` // 0 disables thinking
+	Planning  int `mapstructure:"planning"`
+	Building  int `mapstructure:"building"`
+	Debugging int `mapstructure:"debugging"`
+	Enhance   int `mapstructure:"enhance"`
+}
+
+// BudgetFor returns the thinking budget for a mode ("planning", "building",
+// "debugging" or "enhance"); 0 means no thinking
+func (t ThinkingConfig) BudgetFor(mode string) int {
+	var budget int
+	switch mode {
+	case "planning":
+		budget = t.Planning
+	case "building":
+		budget = t.Building
+	case "debugging":
+		budget = t.Debugging
+	case "enhance":
+		budget = t.Enhance
+	}
+	switch {
+	case budget < 0:
+		return 0
+	case budget == 0:
+		return t.Budget
+	default:
+		return budget
+	}
+}
+
+// IndexConfig controls the local vector index behind the code_search tool.
+// The index is stored under .rubrduck/ in the project and built on the
+// first search.
+type IndexConfig struct {
+	// Enabled registers code_search. It is off by default, since indexing
+	// sends the project's source to the embeddings provider.
+	Enabled bool `mapstructure:"enabled"`
+	// Provider serves the embeddings; empty uses the chat provider. Set it
+	// when the chat provider can't embed (e.g. Anthropic).
+	Provider string `mapstructure:"provider"`
+	// EmbeddingModel overrides the provider's default embedding model
+	EmbeddingModel string `mapstructure:"embedding_model"`
+	MaxFileSize    int64  `mapstructure:"max_file_size"` // bytes; larger files are skipped
 }
 
 // TUIConfig holds settings for the terminal UI modes
@@ -76,10 +239,84 @@ type PromptsConfig struct {
 
 // Provider represents an AI provider configuration
 type Provider struct {
+	// Type selects the provider implementation (e.g. "openai_compatible").
+	// When empty, the key under which the provider is configured is used.
+	Type    string `mapstructure:"type"`
 	Name    string `mapstructure:"name"`
 	BaseURL string `mapstructure:"base_url"`
 	APIKey  string `mapstructure:"api_key"`
 	EnvKey  string `mapstructure:"env_key"`
+
+	// APIVersion is sent as the api-version query parameter (Azure OpenAI)
+	APIVersion string `mapstructure:"api_version"`
+	// Deployment names the model deployment to call (Azure OpenAI).
+	// When empty, the configured model name is used as the deployment.
+	Deployment string `mapstructure:"deployment"`
+
+	// Settings for OpenAI-compatible endpoints (vLLM, llama.cpp, gateways)
+	Headers          map[string]string `mapstructure:"headers"`
+	QueryParams      map[string]string `mapstructure:"query_params"`
+	AuthHeader       string            `mapstructure:"auth_header"`
+	AuthScheme       string            `mapstructure:"auth_scheme"`
+	Timeout          int               `mapstructure:"timeout"` // seconds
+	DisableTools     bool              `mapstructure:"disable_tools"`
+	DisableStreaming bool              `mapstructure:"disable_streaming"`
+
+	// NumCtx is the context size requested from Ollama, which otherwise
+	// runs models with its own default; 0 leaves the server's setting
+	NumCtx int `mapstructure:"num_ctx"`
+
+	// Cassette is the recording played back by the replay provider
+	Cassette string `mapstructure:"cassette"`
+	// Script is the YAML script of turns played by the fake provider
+	Script string `mapstructure:"script"`
+
+	// Network settings shared by all HTTP providers. Proxy overrides the
+	// HTTPS_PROXY/HTTP_PROXY environment variables; CAFile adds a PEM bundle
+	// to the system roots; ClientCert and ClientKey enable mutual TLS.
+	Proxy      string `mapstructure:"proxy"`
+	CAFile     string `mapstructure:"ca_file"`
+	ClientCert string `mapstructure:"client_cert"`
+	ClientKey  string `mapstructure:"client_key"`
+	// InsecureSkipVerify disables certificate checks; for local testing only
+	InsecureSkipVerify bool `mapstructure:"insecure_skip_verify"`
+}
+
+// Settings returns the provider configuration in the form accepted by
+// ai.GetProvider factories
+func (p Provider) Settings() map[string]interface{} {
+	return map[string]interface{}{
+		"name":              p.Name,
+		"base_url":          p.BaseURL,
+		"api_key":           p.APIKey,
+		"api_version":       p.APIVersion,
+		"deployment":        p.Deployment,
+		"headers":           p.Headers,
+		"query_params":      p.QueryParams,
+		"auth_header":       p.AuthHeader,
+		"auth_scheme":       p.AuthScheme,
+		"timeout":           p.Timeout,
+		"disable_tools":     p.DisableTools,
+		"disable_streaming": p.DisableStreaming,
+		"num_ctx":           p.NumCtx,
+		"cassette":          p.Cassette,
+		"script":            p.Script,
+
+		"proxy":                p.Proxy,
+		"ca_file":              p.CAFile,
+		"client_cert":          p.ClientCert,
+		"client_key":           p.ClientKey,
+		"insecure_skip_verify": p.InsecureSkipVerify,
+	}
+}
+
+// ProviderType returns the registered provider implementation for the
+// provider configured under name
+func (c *Config) ProviderType(name string) string {
+	if p, ok := c.Providers[name]; ok && p.Type != "" {
+		return p.Type
+	}
+	return name
 }
 
 // AgentConfig represents agent-specific settings
@@ -115,6 +352,23 @@ type LoggingConfig struct {
 
 // Load loads the configuration from viper
 func Load() (*Config, error) {
+	cfg, err := LoadUnvalidated()
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate configuration
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// LoadUnvalidated loads the configuration without validating it. It is used
+// by commands that talk to specific providers, such as model management, so
+// that a missing API key for the default provider doesn't get in the way.
+func LoadUnvalidated() (*Config, error) {
 	// Set defaults
 	setDefaults()
 
@@ -123,6 +377,8 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
+	applyProviderLimits(&cfg)
+
 	// Load API keys from environment variables
 	for providerName, provider := range cfg.Providers {
 		if provider.EnvKey != "" {
@@ -133,19 +389,60 @@ func Load() (*Config, error) {
 		}
 	}
 
-	// Validate configuration
-	if err := cfg.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid configuration: %w", err)
+	return &cfg, nil
+}
+
+// warnedProviderLimits keeps the tokens.provider_limits deprecation warning
+// to once per run, as the config is loaded several times
+var warnedProviderLimits bool
+
+// applyProviderLimits warns about the deprecated tokens.provider_limits and
+// carries the active provider's entry over to the token limits that
+// replaced it, unless the config sets those itself
+func applyProviderLimits(cfg *Config) {
+	if !viper.InConfig("tokens.provider_limits") {
+		return
+	}
+	if !warnedProviderLimits {
+		warnedProviderLimits = true
+		log.Warn().Msg("tokens.provider_limits is deprecated; limits now come from the model registry. " +
+			"Use tokens.max_completion_tokens and tokens.max_context_tokens to lower them, " +
+			"or a models entry to correct a model's limits")
 	}
 
-	return &cfg, nil
+	limits, ok := cfg.Tokens.ProviderLimits[cfg.Provider]
+	if !ok {
+		return
+	}
+	if limits.MaxCompletionTokens > 0 && !viper.InConfig("tokens.max_completion_tokens") {
+		cfg.Tokens.MaxCompletionTokens = limits.MaxCompletionTokens
+	}
+	if limits.MaxContextTokens > 0 && !viper.InConfig("tokens.max_context_tokens") {
+		cfg.Tokens.MaxContextTokens = limits.MaxContextTokens
+	}
+}
+
+// LoadProvider loads a single provider's configuration without validating
+// the rest of the configuration
+func LoadProvider(name string) (Provider, error) {
+	cfg, err := LoadUnvalidated()
+	if err != nil {
+		return Provider{}, err
+	}
+
+	provider, ok := cfg.Providers[name]
+	if !ok {
+		return Provider{}, fmt.Errorf("provider %s not configured", name)
+	}
+
+	return provider, nil
 }
 
 // setDefaults sets default configuration values
 func setDefaults() {
 	// Provider defaults
 	viper.SetDefault("provider", "openai")
-	viper.SetDefault("model", "gpt-4")
+	viper.SetDefault("model", "") // empty uses the provider's default from the model registry
 
 	// Default providers
 	viper.SetDefault("providers.openai.name", "OpenAI")
@@ -154,25 +451,29 @@ func setDefaults() {
 
 	viper.SetDefault("providers.azure.name", "Azure OpenAI")
 	viper.SetDefault("providers.azure.env_key", "AZURE_API_KEY")
+	viper.SetDefault("providers.azure.api_version", "2024-02-15-preview")
 
 	viper.SetDefault("providers.anthropic.name", "Anthropic")
 	viper.SetDefault("providers.anthropic.base_url", "https://api.anthropic.com/v1")
 	viper.SetDefault("providers.anthropic.env_key", "ANTHROPIC_API_KEY")
 
-	// Token defaults
-	viper.SetDefault("tokens.max_completion_tokens", 2048)
-	viper.SetDefault("tokens.max_context_tokens", 8000)
-	viper.SetDefault("tokens.max_plan_content_length", 2000)
+	viper.SetDefault("providers.ollama.name", "Ollama (Local)")
+	viper.SetDefault("providers.ollama.base_url", "http://localhost:11434")
 
-	// Provider-specific token limits
-	viper.SetDefault("tokens.provider_limits.openai.max_completion_tokens", 2048)
-	viper.SetDefault("tokens.provider_limits.openai.max_context_tokens", 8000)
-	viper.SetDefault("tokens.provider_limits.anthropic.max_completion_tokens", 2048)
-	viper.SetDefault("tokens.provider_limits.anthropic.max_context_tokens", 8000)
-	viper.SetDefault("tokens.provider_limits.gemini.max_completion_tokens", 2048)
-	viper.SetDefault("tokens.provider_limits.gemini.max_context_tokens", 8000)
-	viper.SetDefault("tokens.provider_limits.ollama.max_completion_tokens", 1024)
-	viper.SetDefault("tokens.provider_limits.ollama.max_context_tokens", 4000)
+	// Plays back a cassette recorded with --record; see --replay
+	viper.SetDefault("providers.replay.name", "Replay")
+
+	// Answers from a YAML script, for UI work without a network
+	viper.SetDefault("providers.fake.name", "Fake")
+	viper.SetDefault("providers.fake.script", "")
+
+	// Token defaults; completion and context limits come from the model
+	// registry. Answers are still capped, as a model's full output limit
+	// (up to 128k tokens) is rarely wanted and reserves that much context.
+	viper.SetDefault("tokens.max_completion_tokens", 8192)
+	viper.SetDefault("tokens.max_context_tokens", 0)
+	viper.SetDefault("tokens.max_plan_content_length", 2000)
+	viper.SetDefault("tokens.prompt_caching", true)
 
 	// Agent defaults
 	viper.SetDefault("agent.approval_mode", "suggest")
@@ -226,6 +527,42 @@ func setDefaults() {
 
 	// Prompts defaults
 	viper.SetDefault("prompts.custom_dir", "")
+
+	// The code index is off by default: building it sends the project's
+	// source to the embeddings provider
+	viper.SetDefault("index.enabled", false)
+	viper.SetDefault("index.provider", "")
+	viper.SetDefault("index.embedding_model", "")
+	viper.SetDefault("index.max_file_size", 262144)
+
+	// Thinking is off unless a budget is configured
+	viper.SetDefault("thinking.budget", 0)
+	viper.SetDefault("thinking.planning", 0)
+	viper.SetDefault("thinking.building", 0)
+	viper.SetDefault("thinking.debugging", 0)
+	viper.SetDefault("thinking.enhance", 0)
+
+	// Recording is off unless a cassette path is given
+	viper.SetDefault("record", "")
+
+	// Provider tracing is opt-in since traces contain prompts and replies
+	viper.SetDefault("trace.enabled", false)
+	viper.SetDefault("trace.file", "")
+	viper.SetDefault("trace.max_size", 10)
+	viper.SetDefault("trace.max_backups", 3)
+
+	// Cost tracking is on; spending limits are off until set
+	viper.SetDefault("usage.track", true)
+	viper.SetDefault("usage.dir", "")
+	viper.SetDefault("usage.session_warn", 0)
+	viper.SetDefault("usage.session_limit", 0)
+	viper.SetDefault("usage.daily_warn", 0)
+	viper.SetDefault("usage.daily_limit", 0)
+
+	// Checkpoints are opt-in: undo can't roll back shell edits to existing files
+	viper.SetDefault("checkpoints.enabled", false)
+	viper.SetDefault("checkpoints.dir", "")
+	viper.SetDefault("checkpoints.keep", 50)
 }
 
 // Validate validates the configuration
@@ -0,0 +1,186 @@
+// Package ignore reads .gitignore and .rubrduckignore files, so the tools
+// that list, search or index a project leave out what it excludes.
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Files are the ignore files read in each directory, later ones taking precedence
+var Files = []string{".gitignore", ".rubrduckignore"}
+
+// ignorePattern is one line of an ignore file
+type ignorePattern struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// Rules matches paths under root against the .gitignore and
+// .rubrduckignore files of their directories, as git does: the last
+// matching pattern wins, deeper files override shallower ones, and
+// nothing inside an ignored directory can be re-included. The .git
+// directory is always ignored.
+type Rules struct {
+	root     string
+	patterns map[string][]ignorePattern // by directory, relative to root
+}
+
+// New returns the rules for the tree at root. Ignore files are
+// read as directories are first checked.
+func New(root string) *Rules {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		abs = root
+	}
+	return &Rules{root: abs, patterns: make(map[string][]ignorePattern)}
+}
+
+// Ignored reports whether path, or a directory containing it, is ignored
+func (r *Rules) Ignored(path string, isDir bool) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(r.root, abs)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	for i := range parts {
+		last := i == len(parts)-1
+		if r.matches(parts[:i+1], isDir || !last) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches applies the ignore files of each directory above the path
+func (r *Rules) matches(parts []string, isDir bool) bool {
+	name := parts[len(parts)-1]
+	if name == ".git" && isDir {
+		return true
+	}
+
+	ignored := false
+	for depth := 0; depth < len(parts); depth++ {
+		dir := strings.Join(parts[:depth], "/")
+		rel := strings.Join(parts[depth:], "/")
+		for _, p := range r.load(dir) {
+			if p.dirOnly && !isDir {
+				continue
+			}
+			if p.re.MatchString(rel) {
+				ignored = !p.negate
+			}
+		}
+	}
+	return ignored
+}
+
+// load reads the ignore files of dir, once
+func (r *Rules) load(dir string) []ignorePattern {
+	if patterns, ok := r.patterns[dir]; ok {
+		return patterns
+	}
+	var patterns []ignorePattern
+	for _, name := range Files {
+		data, err := os.ReadFile(filepath.Join(r.root, filepath.FromSlash(dir), name))
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if p, ok := parseIgnorePattern(line); ok {
+				patterns = append(patterns, p)
+			}
+		}
+	}
+	r.patterns[dir] = patterns
+	return patterns
+}
+
+// parseIgnorePattern compiles one line of an ignore file
+func parseIgnorePattern(line string) (ignorePattern, bool) {
+	line = strings.TrimRight(line, "\r")
+	if !strings.HasSuffix(line, `\ `) {
+		line = strings.TrimRight(line, " ")
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignorePattern{}, false
+	}
+
+	var p ignorePattern
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return ignorePattern{}, false
+	}
+
+	// A pattern with a slash other than at the end is relative to the
+	// ignore file's directory; otherwise it matches a name at any depth
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	expr := GlobRegexp(line)
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return ignorePattern{}, false
+	}
+	p.re = re
+	return p, true
+}
+
+// GlobRegexp converts a glob with *, ?, [...] and ** to a regular
+// expression over slash-separated paths
+func GlobRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(glob[i])))
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
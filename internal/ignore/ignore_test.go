@@ -0,0 +1,42 @@
+package ignore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIgnorePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		want    bool
+	}{
+		{"*.log", "debug.log", false, true},
+		{"*.log", "logs/debug.log", false, true},
+		{"/todo.txt", "todo.txt", false, true},
+		{"/todo.txt", "docs/todo.txt", false, false},
+		{"docs/*.md", "docs/a.md", false, true},
+		{"docs/*.md", "docs/sub/a.md", false, false},
+		{"docs/**/*.md", "docs/sub/a.md", false, true},
+		{"**/cache", "a/b/cache", true, true},
+		{"build/", "build", true, true},
+		{"build/", "build", false, false},
+		{"file[0-9].txt", "file3.txt", false, true},
+		{"file[!0-9].txt", "file3.txt", false, false},
+		{"a?c", "abc", false, true},
+	}
+	for _, tt := range tests {
+		p, ok := parseIgnorePattern(tt.pattern)
+		require.True(t, ok, tt.pattern)
+		got := p.re.MatchString(tt.path) && (!p.dirOnly || tt.isDir)
+		assert.Equal(t, tt.want, got, "%s against %s", tt.pattern, tt.path)
+	}
+
+	for _, line := range []string{"", "   ", "# comment", "/"} {
+		_, ok := parseIgnorePattern(line)
+		assert.False(t, ok, "%q", line)
+	}
+}
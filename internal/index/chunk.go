@@ -0,0 +1,157 @@
+package index
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// maxChunkLines caps the size of a chunk; longer functions are split into
+// consecutive windows so each piece stays within embedding input limits
+const maxChunkLines = 80
+
+// Chunk is a contiguous range of lines from one file, embedded as a unit
+type Chunk struct {
+	Path      string `json:"path"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	// Symbol names the function, method or type the chunk covers, if any
+	Symbol  string    `json:"symbol,omitempty"`
+	Content string    `json:"content"`
+	Vector  []float32 `json:"vector"`
+}
+
+// indexedExtensions lists the file types worth embedding
+var indexedExtensions = map[string]bool{
+	".go": true, ".js": true, ".jsx": true, ".ts": true, ".tsx": true,
+	".py": true, ".java": true, ".rb": true, ".php": true, ".rs": true,
+	".c": true, ".h": true, ".cpp": true, ".cc": true, ".cxx": true, ".hpp": true,
+	".cs": true, ".kt": true, ".swift": true, ".scala": true, ".sh": true,
+	".sql": true, ".md": true, ".yaml": true, ".yml": true, ".toml": true,
+	".proto": true,
+}
+
+// definitionPattern matches unindented function, class and type definitions
+// in the languages without a dedicated parser
+var definitionPattern = regexp.MustCompile(
+	`^(?:export\s+)?(?:default\s+)?(?:pub(?:\(crate\))?\s+)?(?:async\s+)?(?:public\s+|private\s+|protected\s+)?(?:static\s+)?` +
+		`(?:def|class|function|fn|func|interface|struct|enum|trait|impl|type|module)\s+([A-Za-z_][A-Za-z0-9_]*)`)
+
+// chunkFile splits a file into chunks: top-level declarations for Go,
+// definition boundaries for other code and fixed windows for everything
+// else. Vectors are left empty.
+func chunkFile(path string, content string) []Chunk {
+	lines := strings.Split(content, "\n")
+	if filepath.Ext(path) == ".go" {
+		if chunks, ok := chunkGo(path, content, lines); ok {
+			return chunks
+		}
+	}
+	return chunkByDefinitions(path, lines)
+}
+
+// chunkGo creates one chunk per top-level declaration, including its doc
+// comment. Imports are skipped. It reports false if the file doesn't parse.
+func chunkGo(path, content string, lines []string) ([]Chunk, bool) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, content, parser.ParseComments)
+	if err != nil {
+		return nil, false
+	}
+
+	var chunks []Chunk
+	for _, decl := range file.Decls {
+		var symbol string
+		start := decl.Pos()
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			symbol = d.Name.Name
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				symbol = receiverName(d.Recv.List[0].Type) + "." + symbol
+			}
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				continue
+			}
+			if len(d.Specs) == 1 {
+				if ts, ok := d.Specs[0].(*ast.TypeSpec); ok {
+					symbol = ts.Name.Name
+				}
+			}
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+		}
+
+		chunks = append(chunks, splitLines(path, symbol, lines,
+			fset.Position(start).Line, fset.Position(decl.End()).Line)...)
+	}
+	return chunks, true
+}
+
+// receiverName returns the type name of a method receiver
+func receiverName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(t.X)
+	case *ast.IndexExpr:
+		return receiverName(t.X)
+	case *ast.IndexListExpr:
+		return receiverName(t.X)
+	case *ast.Ident:
+		return t.Name
+	default:
+		return ""
+	}
+}
+
+// chunkByDefinitions starts a new chunk at each unindented definition and
+// caps chunks at maxChunkLines
+func chunkByDefinitions(path string, lines []string) []Chunk {
+	var chunks []Chunk
+	start, symbol := 1, ""
+	for i, line := range lines {
+		lineNo := i + 1
+		if m := definitionPattern.FindStringSubmatch(line); m != nil && lineNo > start {
+			chunks = append(chunks, splitLines(path, symbol, lines, start, lineNo-1)...)
+			start, symbol = lineNo, m[1]
+		} else if m != nil {
+			symbol = m[1]
+		}
+	}
+	return append(chunks, splitLines(path, symbol, lines, start, len(lines))...)
+}
+
+// splitLines turns lines start..end (1-based, inclusive) into one or more
+// chunks of at most maxChunkLines, skipping blank ranges
+func splitLines(path, symbol string, lines []string, start, end int) []Chunk {
+	if end > len(lines) {
+		end = len(lines)
+	}
+
+	var chunks []Chunk
+	for from := start; from <= end; from += maxChunkLines {
+		to := from + maxChunkLines - 1
+		if to > end {
+			to = end
+		}
+		text := strings.Join(lines[from-1:to], "\n")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		chunks = append(chunks, Chunk{
+			Path:      path,
+			StartLine: from,
+			EndLine:   to,
+			Symbol:    symbol,
+			Content:   text,
+		})
+	}
+	return chunks
+}
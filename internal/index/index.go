@@ -0,0 +1,412 @@
+// Package index maintains a local vector index of a repository for semantic
+// code search. Files are split into chunks (top-level declarations where the
+// language allows), embedded through an ai.Embedder and stored as JSON under
+// .rubrduck/. Updates are incremental: only files whose size, modification
+// time and content hash changed are re-embedded.
+package index
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hammie/rubrduck/internal/ai"
+	"github.com/hammie/rubrduck/internal/ignore"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// Dir is the per-repository data directory, relative to the root
+	Dir = ".rubrduck"
+
+	// indexFile is the index location inside Dir
+	indexFile = "index.json"
+
+	// indexVersion is bumped when the stored format or chunking changes,
+	// which forces a rebuild
+	indexVersion = 1
+
+	// embedBatchSize is the number of chunks sent per embedding request
+	embedBatchSize = 32
+
+	// DefaultMaxFileSize is the largest file indexed when Options doesn't set one
+	DefaultMaxFileSize = 256 * 1024
+)
+
+// Options configures an Index
+type Options struct {
+	// Model is the embedding model; changing it rebuilds the index
+	Model string
+	// MaxFileSize skips larger files, in bytes
+	MaxFileSize int64
+}
+
+// Index is a vector index of the files under a root directory
+type Index struct {
+	root        string
+	path        string
+	embedder    ai.Embedder
+	model       string
+	maxFileSize int64
+
+	mu    sync.Mutex
+	files map[string]*fileEntry
+}
+
+// fileEntry records what was indexed for one file
+type fileEntry struct {
+	ModTime time.Time `json:"mod_time"`
+	Size    int64     `json:"size"`
+	Hash    string    `json:"hash"`
+	Chunks  []Chunk   `json:"chunks"`
+}
+
+// indexData is the on-disk format
+type indexData struct {
+	Version int                   `json:"version"`
+	Model   string                `json:"model"`
+	Files   map[string]*fileEntry `json:"files"`
+}
+
+// Stats summarizes an Update
+type Stats struct {
+	Files    int `json:"files"`
+	Chunks   int `json:"chunks"`
+	Embedded int `json:"embedded"`
+	Removed  int `json:"removed"`
+}
+
+// Result is a chunk matched by Search, with its cosine similarity to the query
+type Result struct {
+	Chunk
+	Score float64 `json:"score"`
+}
+
+// Open loads the index stored under root, or starts an empty one if there is
+// none or it was built with a different model or format
+func Open(root string, embedder ai.Embedder, opts Options) (*Index, error) {
+	if opts.MaxFileSize <= 0 {
+		opts.MaxFileSize = DefaultMaxFileSize
+	}
+
+	ix := &Index{
+		root:        root,
+		path:        filepath.Join(root, Dir, indexFile),
+		embedder:    embedder,
+		model:       opts.Model,
+		maxFileSize: opts.MaxFileSize,
+		files:       make(map[string]*fileEntry),
+	}
+
+	data, err := os.ReadFile(ix.path)
+	if os.IsNotExist(err) {
+		return ix, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index: %w", err)
+	}
+
+	var stored indexData
+	if err := json.Unmarshal(data, &stored); err != nil {
+		log.Warn().Err(err).Str("path", ix.path).Msg("Discarding unreadable code index")
+		return ix, nil
+	}
+	if stored.Version != indexVersion || stored.Model != opts.Model {
+		log.Info().Str("model", opts.Model).Msg("Code index was built with a different model or format; rebuilding")
+		return ix, nil
+	}
+	if stored.Files != nil {
+		ix.files = stored.Files
+	}
+
+	return ix, nil
+}
+
+// Update brings the index in line with the files on disk, leaving out what
+// .gitignore and .rubrduckignore exclude. Unchanged files
+// are detected by size and modification time, then by content hash, so only
+// new or edited files are embedded. If embedding fails part way, the files
+// finished so far are kept.
+func (ix *Index) Update(ctx context.Context) (Stats, error) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	var stats Stats
+	seen := make(map[string]bool)
+	var pending []string
+	changed := make(map[string]*fileEntry)
+	dirty := false
+	// Ignored files often hold local config and secrets, which must not be
+	// sent to the embeddings provider
+	rules := ignore.New(ix.root)
+
+	err := filepath.WalkDir(ix.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Skip files we can't access
+		}
+		if d.IsDir() {
+			if path != ix.root && (skipDir(d.Name()) || rules.Ignored(path, true)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if rules.Ignored(path, false) {
+			return nil
+		}
+		if !d.Type().IsRegular() || !indexedExtensions[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil || info.Size() > ix.maxFileSize {
+			return nil
+		}
+
+		rel, err := filepath.Rel(ix.root, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		seen[rel] = true
+
+		entry := ix.files[rel]
+		if entry != nil && entry.Size == info.Size() && entry.ModTime.Equal(info.ModTime()) {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil || bytes.IndexByte(content, 0) >= 0 {
+			return nil // Unreadable or binary
+		}
+
+		sum := sha256.Sum256(content)
+		hash := hex.EncodeToString(sum[:])
+		if entry != nil && entry.Hash == hash {
+			// Touched but not edited
+			entry.ModTime, entry.Size = info.ModTime(), info.Size()
+			dirty = true
+			return nil
+		}
+
+		changed[rel] = &fileEntry{
+			ModTime: info.ModTime(),
+			Size:    info.Size(),
+			Hash:    hash,
+			Chunks:  chunkFile(rel, string(content)),
+		}
+		pending = append(pending, rel)
+		return nil
+	})
+	if err != nil {
+		return stats, fmt.Errorf("failed to walk %s: %w", ix.root, err)
+	}
+
+	for rel := range ix.files {
+		if !seen[rel] {
+			delete(ix.files, rel)
+			stats.Removed++
+			dirty = true
+		}
+	}
+
+	embedded, embedErr := ix.embedFiles(ctx, pending, changed)
+	stats.Embedded = embedded
+	for _, rel := range pending {
+		if entry := changed[rel]; entry != nil {
+			ix.files[rel] = entry
+			dirty = true
+		}
+	}
+
+	for _, entry := range ix.files {
+		stats.Files++
+		stats.Chunks += len(entry.Chunks)
+	}
+
+	if dirty {
+		if err := ix.save(); err != nil {
+			return stats, err
+		}
+	}
+	if embedErr != nil {
+		return stats, embedErr
+	}
+
+	log.Debug().
+		Int("files", stats.Files).
+		Int("chunks", stats.Chunks).
+		Int("embedded", stats.Embedded).
+		Int("removed", stats.Removed).
+		Msg("Updated code index")
+	return stats, nil
+}
+
+// embedFiles fills in the chunk vectors of the pending files in batches. On
+// error, files whose chunks weren't all embedded are removed from changed.
+// It returns the number of chunks embedded.
+func (ix *Index) embedFiles(ctx context.Context, pending []string, changed map[string]*fileEntry) (int, error) {
+	type ref struct {
+		file  string
+		chunk int
+	}
+	var refs []ref
+	for _, rel := range pending {
+		for i := range changed[rel].Chunks {
+			refs = append(refs, ref{rel, i})
+		}
+	}
+
+	for start := 0; start < len(refs); start += embedBatchSize {
+		end := start + embedBatchSize
+		if end > len(refs) {
+			end = len(refs)
+		}
+
+		inputs := make([]string, end-start)
+		for i, r := range refs[start:end] {
+			inputs[i] = embeddingText(changed[r.file].Chunks[r.chunk])
+		}
+
+		resp, err := ix.embedder.Embed(ctx, &ai.EmbeddingRequest{Model: ix.model, Input: inputs})
+		if err == nil && len(resp.Embeddings) != len(inputs) {
+			err = fmt.Errorf("expected %d embeddings, got %d", len(inputs), len(resp.Embeddings))
+		}
+		if err != nil {
+			// Drop files that weren't finished; they are retried next time
+			for _, r := range refs[start:] {
+				delete(changed, r.file)
+			}
+			return start, fmt.Errorf("failed to embed chunks: %w", err)
+		}
+
+		for i, r := range refs[start:end] {
+			changed[r.file].Chunks[r.chunk].Vector = resp.Embeddings[i]
+		}
+	}
+
+	return len(refs), nil
+}
+
+// Search returns the limit chunks most similar to query. When pathPrefix is
+// set, only files under it are considered. Call Update first to pick up
+// changes on disk.
+func (ix *Index) Search(ctx context.Context, query string, limit int, pathPrefix string) ([]Result, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("search query is required")
+	}
+	if limit <= 0 {
+		limit = 5
+	}
+
+	resp, err := ix.embedder.Embed(ctx, &ai.EmbeddingRequest{Model: ix.model, Input: []string{query}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	if len(resp.Embeddings) != 1 {
+		return nil, fmt.Errorf("expected 1 query embedding, got %d", len(resp.Embeddings))
+	}
+	queryVector := resp.Embeddings[0]
+
+	pathPrefix = strings.TrimPrefix(filepath.ToSlash(filepath.Clean(pathPrefix)), "./")
+	if pathPrefix == "." {
+		pathPrefix = ""
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	var results []Result
+	for rel, entry := range ix.files {
+		if pathPrefix != "" && rel != pathPrefix && !strings.HasPrefix(rel, strings.TrimSuffix(pathPrefix, "/")+"/") {
+			continue
+		}
+		for _, chunk := range entry.Chunks {
+			if len(chunk.Vector) != len(queryVector) {
+				continue
+			}
+			results = append(results, Result{Chunk: chunk, Score: cosine(queryVector, chunk.Vector)})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		if results[i].Path != results[j].Path {
+			return results[i].Path < results[j].Path
+		}
+		return results[i].StartLine < results[j].StartLine
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// save writes the index atomically
+func (ix *Index) save() error {
+	data, err := json.Marshal(indexData{Version: indexVersion, Model: ix.model, Files: ix.files})
+	if err != nil {
+		return fmt.Errorf("failed to marshal index: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(ix.path), 0755); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+
+	tmp := ix.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	if err := os.Rename(tmp, ix.path); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	return nil
+}
+
+// skipDir reports whether a directory should be left out of the index
+func skipDir(name string) bool {
+	if strings.HasPrefix(name, ".") {
+		return true
+	}
+	switch name {
+	case "node_modules", "vendor", "dist", "build", "target":
+		return true
+	}
+	return false
+}
+
+// embeddingText is the text embedded for a chunk; the path and symbol help
+// match queries that name them
+func embeddingText(chunk Chunk) string {
+	header := chunk.Path
+	if chunk.Symbol != "" {
+		header += " " + chunk.Symbol
+	}
+	return header + "\n\n" + chunk.Content
+}
+
+// cosine returns the cosine similarity of two vectors of equal length
+func cosine(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
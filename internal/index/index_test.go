@@ -0,0 +1,241 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hammie/rubrduck/internal/ai"
+)
+
+// wordEmbedder embeds text as a bag of hashed words, so texts sharing words
+// are similar. Every text it is sent is kept in inputs.
+type wordEmbedder struct {
+	fail   bool
+	inputs []string
+}
+
+func (e *wordEmbedder) Embed(ctx context.Context, req *ai.EmbeddingRequest) (*ai.EmbeddingResponse, error) {
+	if e.fail {
+		return nil, errors.New("embedding service unavailable")
+	}
+
+	e.inputs = append(e.inputs, req.Input...)
+	resp := &ai.EmbeddingResponse{Model: req.Model}
+	for _, text := range req.Input {
+		vector := make([]float32, 64)
+		for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !(r >= 'a' && r <= 'z')
+		}) {
+			h := fnv.New32a()
+			h.Write([]byte(word))
+			vector[h.Sum32()%64]++
+		}
+		resp.Embeddings = append(resp.Embeddings, vector)
+	}
+	return resp, nil
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+}
+
+const authSource = `package auth
+
+import "errors"
+
+// ValidateToken checks a session token signature
+func ValidateToken(token string) error {
+	if token == "" {
+		return errors.New("missing token")
+	}
+	return nil
+}
+
+type Session struct {
+	User string
+}
+
+func (s *Session) Expired() bool {
+	return false
+}
+`
+
+func TestChunkGo(t *testing.T) {
+	chunks := chunkFile("auth/auth.go", authSource)
+	if len(chunks) != 3 {
+		t.Fatalf("Expected 3 declaration chunks, got %d: %+v", len(chunks), chunks)
+	}
+
+	if chunks[0].Symbol != "ValidateToken" || chunks[0].StartLine != 5 || chunks[0].EndLine != 11 {
+		t.Errorf("Expected ValidateToken with its doc comment, got %+v", chunks[0])
+	}
+	if chunks[1].Symbol != "Session" {
+		t.Errorf("Expected Session type, got %s", chunks[1].Symbol)
+	}
+	if chunks[2].Symbol != "Session.Expired" {
+		t.Errorf("Expected method symbol, got %s", chunks[2].Symbol)
+	}
+}
+
+func TestChunkByDefinitions(t *testing.T) {
+	source := "import os\n\ndef load(path):\n    return open(path)\n\nclass Cache:\n    pass\n"
+	chunks := chunkFile("cache.py", source)
+	if len(chunks) != 3 {
+		t.Fatalf("Expected header and 2 definitions, got %+v", chunks)
+	}
+	if chunks[1].Symbol != "load" || chunks[2].Symbol != "Cache" || chunks[2].StartLine != 6 {
+		t.Errorf("Unexpected chunks: %+v", chunks)
+	}
+
+	long := strings.Repeat("line\n", maxChunkLines*2+10)
+	if chunks := chunkFile("notes.md", long); len(chunks) != 3 {
+		t.Errorf("Expected long file split into 3 windows, got %d", len(chunks))
+	}
+}
+
+func TestIndexUpdateIsIncremental(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "auth/auth.go", authSource)
+	writeFile(t, dir, "README.md", "# Project\n\nDocs about deployment.\n")
+	writeFile(t, dir, "node_modules/lib/index.js", "function skipped() {}\n")
+	writeFile(t, dir, "logo.png", "\x89PNG\x00")
+
+	embedder := &wordEmbedder{}
+	ix, err := Open(dir, embedder, Options{Model: "test-embed"})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	stats, err := ix.Update(context.Background())
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if stats.Files != 2 || stats.Chunks != 4 || stats.Embedded != 4 {
+		t.Errorf("Unexpected first update stats: %+v", stats)
+	}
+
+	// Nothing changed
+	stats, _ = ix.Update(context.Background())
+	if stats.Embedded != 0 {
+		t.Errorf("Expected no re-embedding, got %+v", stats)
+	}
+
+	// Touching without editing only refreshes the mtime
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(filepath.Join(dir, "README.md"), later, later)
+	stats, _ = ix.Update(context.Background())
+	if stats.Embedded != 0 {
+		t.Errorf("Expected hash match to skip embedding, got %+v", stats)
+	}
+
+	// Edits and deletions are picked up
+	writeFile(t, dir, "README.md", "# Project\n\nDocs about deployment and rollback.\n")
+	os.Remove(filepath.Join(dir, "auth/auth.go"))
+	stats, _ = ix.Update(context.Background())
+	if stats.Embedded != 1 || stats.Removed != 1 || stats.Files != 1 {
+		t.Errorf("Unexpected stats after edit: %+v", stats)
+	}
+
+	// The index persists across Open with the same model
+	reopened, err := Open(dir, embedder, Options{Model: "test-embed"})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	stats, _ = reopened.Update(context.Background())
+	if stats.Embedded != 0 || stats.Files != 1 {
+		t.Errorf("Expected stored index to be reused, got %+v", stats)
+	}
+
+	// A different model rebuilds
+	rebuilt, _ := Open(dir, embedder, Options{Model: "other-embed"})
+	stats, _ = rebuilt.Update(context.Background())
+	if stats.Embedded != 1 {
+		t.Errorf("Expected rebuild for a new model, got %+v", stats)
+	}
+}
+
+func TestIndexUpdateSkipsIgnoredFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, ".gitignore", "secrets.yaml\nlocal/\n")
+	writeFile(t, dir, ".rubrduckignore", "*.sql\n")
+	writeFile(t, dir, "secrets.yaml", "api_key: hunter2\n")
+	writeFile(t, dir, "deploy/secrets.yaml", "password: hunter2\n")
+	writeFile(t, dir, "local/dev.toml", "token = \"hunter2\"\n")
+	writeFile(t, dir, "seed.sql", "INSERT INTO users VALUES ('hunter2');\n")
+	writeFile(t, dir, "auth/auth.go", authSource)
+
+	embedder := &wordEmbedder{}
+	ix, err := Open(dir, embedder, Options{Model: "test-embed"})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	stats, err := ix.Update(context.Background())
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if stats.Files != 1 {
+		t.Errorf("Expected only auth/auth.go to be indexed, got %+v", stats)
+	}
+	for _, input := range embedder.inputs {
+		if strings.Contains(input, "hunter2") {
+			t.Errorf("Ignored file content was sent to Embed: %q", input)
+		}
+	}
+}
+
+func TestIndexUpdateKeepsProgressOnError(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.go", authSource)
+
+	embedder := &wordEmbedder{fail: true}
+	ix, _ := Open(dir, embedder, Options{Model: "test-embed"})
+	if _, err := ix.Update(context.Background()); err == nil {
+		t.Fatal("Expected embedding error")
+	}
+
+	embedder.fail = false
+	stats, err := ix.Update(context.Background())
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if stats.Embedded != 3 {
+		t.Errorf("Expected failed file to be retried, got %+v", stats)
+	}
+}
+
+func TestIndexSearch(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "auth/auth.go", authSource)
+	writeFile(t, dir, "deploy/deploy.go", "package deploy\n\n// Rollback reverts the last deployment release\nfunc Rollback(release string) {}\n")
+
+	ix, _ := Open(dir, &wordEmbedder{}, Options{Model: "test-embed"})
+	if _, err := ix.Update(context.Background()); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	results, err := ix.Search(context.Background(), "token signature checks", 2, "")
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results) != 2 || results[0].Symbol != "ValidateToken" {
+		t.Errorf("Expected ValidateToken first, got %+v", results)
+	}
+
+	results, _ = ix.Search(context.Background(), "token signature checks", 5, "deploy")
+	if len(results) != 1 || results[0].Path != "deploy/deploy.go" {
+		t.Errorf("Expected path filter to limit results, got %+v", results)
+	}
+}
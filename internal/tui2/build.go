@@ -6,6 +6,7 @@ import (
 
 	"github.com/hammie/rubrduck/internal/agent"
 	"github.com/hammie/rubrduck/pkg/plans"
+	"github.com/rs/zerolog/log"
 )
 
 // GetBuildingSystemPrompt returns the system prompt for building mode
@@ -34,24 +35,19 @@ func ProcessBuildingRequest(ctx context.Context, agent *agent.Agent, userInput,
 	planContext, err := getBuildingContext()
 	if err != nil {
 		// Log error but continue without context
-		fmt.Printf("Warning: failed to get building context: %v\n", err)
+		log.Warn().Err(err).Msg("Failed to get building context")
 	}
 
-	// Build contextual input
-	var contextualInput string
+	// Plan context goes with the system prompt, refreshed on every request
 	if planContext != nil && (planContext.CurrentPlan != nil || len(planContext.RelatedPlans) > 0) {
 		formatter := plans.NewContextFormatter()
 		formatter.SetIncludeMetadata(false)
 		formatter.SetMaxContentLength(500)
-		contextStr := formatter.FormatContext(planContext)
-
-		contextualInput = fmt.Sprintf("System context: %s\n\nPlan Context:\n%s\n\nUser request: %s",
-			systemPrompt, contextStr, userInput)
-	} else {
-		contextualInput = fmt.Sprintf("System context: %s\n\nUser request: %s", systemPrompt, userInput)
+		systemPrompt = fmt.Sprintf("%s\n\nPlan Context:\n%s", systemPrompt, formatter.FormatContext(planContext))
 	}
 
-	return agent.StreamEvents(ctx, contextualInput)
+	agent.SetSystemPrompt("building", systemPrompt)
+	return agent.StreamEvents(ctx, userInput)
 }
 
 // getBuildingContext retrieves relevant plan context for building mode
@@ -0,0 +1,71 @@
+package tui2
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hammie/rubrduck/internal/checkpoint"
+)
+
+// slashCommand runs a chat command such as /undo and returns its output.
+// ok is false when input is not a known command and should go to the
+// model.
+func slashCommand(checkpoints *checkpoint.Manager, input string) (output string, ok bool) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
+		return "", false
+	}
+
+	switch fields[0] {
+	case "/undo", "/checkpoints", "/restore":
+	default:
+		return "", false
+	}
+
+	if checkpoints == nil {
+		return "Checkpoints are off; set checkpoints.enabled to undo changes.", true
+	}
+
+	switch fields[0] {
+	case "/undo":
+		cp, err := checkpoints.Undo()
+		if errors.Is(err, checkpoint.ErrNoCheckpoints) {
+			return "Nothing to undo.", true
+		}
+		if err != nil {
+			return fmt.Sprintf("Undo failed: %v", err), true
+		}
+		return "Undid " + checkpoint.Describe([]checkpoint.Checkpoint{*cp}), true
+
+	case "/checkpoints":
+		list, err := checkpoints.List()
+		if err != nil {
+			return fmt.Sprintf("Failed to list checkpoints: %v", err), true
+		}
+		if len(list) == 0 {
+			return "No checkpoints yet. One is saved for each turn that changes files.", true
+		}
+		var b strings.Builder
+		b.WriteString("Checkpoints, newest last (/restore <n> undoes turn n and every later one):\n")
+		for _, cp := range list {
+			b.WriteString(cp.Summary() + "\n")
+		}
+		return strings.TrimRight(b.String(), "\n"), true
+
+	default: // /restore
+		if len(fields) != 2 {
+			return "Usage: /restore <n>; see /checkpoints for the numbers.", true
+		}
+		id, err := strconv.Atoi(strings.TrimPrefix(fields[1], "#"))
+		if err != nil {
+			return fmt.Sprintf("Invalid checkpoint %q; see /checkpoints for the numbers.", fields[1]), true
+		}
+		undone, err := checkpoints.Restore(id)
+		if err != nil {
+			return fmt.Sprintf("Restore failed: %v", err), true
+		}
+		return "Restored " + checkpoint.Describe(undone), true
+	}
+}
@@ -29,6 +29,6 @@ func ProcessDebuggingRequest(ctx context.Context, agent *agent.Agent, userInput,
 		return nil, err
 	}
 
-	contextualInput := fmt.Sprintf("System context: %s\n\nUser request: %s", systemPrompt, userInput)
-	return agent.StreamEvents(ctx, contextualInput)
+	agent.SetSystemPrompt("debugging", systemPrompt)
+	return agent.StreamEvents(ctx, userInput)
 }
@@ -29,6 +29,6 @@ func ProcessEnhanceRequest(ctx context.Context, agent *agent.Agent, userInput, m
 		return nil, err
 	}
 
-	contextualInput := fmt.Sprintf("System context: %s\n\nUser request: %s", systemPrompt, userInput)
-	return agent.StreamEvents(ctx, contextualInput)
+	agent.SetSystemPrompt("enhance", systemPrompt)
+	return agent.StreamEvents(ctx, userInput)
 }
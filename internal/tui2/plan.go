@@ -8,6 +8,7 @@ import (
 
 	"github.com/hammie/rubrduck/internal/agent"
 	"github.com/hammie/rubrduck/pkg/plans"
+	"github.com/rs/zerolog/log"
 )
 
 var planManager *plans.Manager
@@ -57,24 +58,19 @@ func ProcessPlanningRequest(ctx context.Context, agent *agent.Agent, userInput,
 	planContext, err := getPlanningContext()
 	if err != nil {
 		// Log error but continue without context
-		fmt.Printf("Warning: failed to get plan context: %v\n", err)
+		log.Warn().Err(err).Msg("Failed to get plan context")
 	}
 
-	// Build contextual input
-	var contextualInput string
+	// Plan context goes with the system prompt, refreshed on every request
 	if planContext != nil && len(planContext.RelatedPlans) > 0 {
 		formatter := plans.NewContextFormatter()
 		formatter.SetIncludeMetadata(false)
 		formatter.SetMaxContentLength(500)
-		contextStr := formatter.FormatContext(planContext)
-
-		contextualInput = fmt.Sprintf("System context: %s\n\nPlan Context:\n%s\n\nUser request: %s",
-			systemPrompt, contextStr, userInput)
-	} else {
-		contextualInput = fmt.Sprintf("System context: %s\n\nUser request: %s", systemPrompt, userInput)
+		systemPrompt = fmt.Sprintf("%s\n\nPlan Context:\n%s", systemPrompt, formatter.FormatContext(planContext))
 	}
 
-	return agent.StreamEvents(ctx, contextualInput)
+	agent.SetSystemPrompt("planning", systemPrompt)
+	return agent.StreamEvents(ctx, userInput)
 }
 
 // getPlanningContext retrieves relevant plan context for planning mode
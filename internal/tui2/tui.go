@@ -13,6 +13,7 @@ import (
 	"github.com/charmbracelet/lipgloss"
 
 	"github.com/hammie/rubrduck/internal/agent"
+	"github.com/hammie/rubrduck/internal/ai"
 	_ "github.com/hammie/rubrduck/internal/ai/providers" // Register AI providers
 	"github.com/hammie/rubrduck/internal/config"
 )
@@ -127,9 +128,10 @@ func Run(cfg *config.Config) error {
 }
 
 type message struct {
-	sender string // "user" or "ai"
-	text   string
-	mode   ViewMode
+	sender    string // "user", "ai" or "system" for command output
+	text      string
+	mode      ViewMode
+	reasoning string // model thinking shown above an AI reply
 }
 
 type model struct {
@@ -148,6 +150,8 @@ type model struct {
 	partial        string
 	streamCh       <-chan agent.StreamEvent
 	streamChunks   int // Track number of chunks received
+	reasoning      string
+	showReasoning  bool // Whether reasoning blocks are expanded
 
 	// Tool call and thinking tracking
 	toolCalls       []toolCallInfo
@@ -282,7 +286,21 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				progressIndicator = fmt.Sprintf(" [%d chunks received]", m.streamChunks)
 			}
 
-			content := m.renderChatContent() + lipgloss.NewStyle().Foreground(lipgloss.Color("4")).Bold(true).Render("AI:    ") + m.partial + progressIndicator
+			content := m.renderChatContent() + m.renderReasoning(m.reasoning, true) + lipgloss.NewStyle().Foreground(lipgloss.Color("4")).Bold(true).Render("AI:    ") + m.partial + progressIndicator
+			m.viewport.SetContent(content)
+			if !m.userScrolling {
+				m.viewport.GotoBottom()
+			}
+			if msg.cancel != nil {
+				return m, listenStreamWithCancel(msg.ch, msg.cancel)
+			}
+			return m, listenStream(msg.ch)
+		case agent.EventReasoning:
+			m.reasoning += msg.event.Token
+			m.isThinking = true
+			m.thinkingText = "Reasoning..."
+
+			content := m.renderChatContent() + m.renderReasoning(m.reasoning, true)
 			m.viewport.SetContent(content)
 			if !m.userScrolling {
 				m.viewport.GotoBottom()
@@ -348,7 +366,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				progressIndicator = fmt.Sprintf(" [%d chunks received]", m.streamChunks)
 			}
 
-			content := m.renderChatContent() + lipgloss.NewStyle().Foreground(lipgloss.Color("4")).Bold(true).Render("AI:    ") + m.partial + progressIndicator
+			content := m.renderChatContent() + m.renderReasoning(m.reasoning, true) + lipgloss.NewStyle().Foreground(lipgloss.Color("4")).Bold(true).Render("AI:    ") + m.partial + progressIndicator
 			m.viewport.SetContent(content)
 			if !m.userScrolling {
 				m.viewport.GotoBottom()
@@ -381,7 +399,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			// Check for pseudo-tool calls in the response and convert them to actual tool calls
 			processedContent := m.processPseudoToolCalls(m.partial)
-			m.messages = append(m.messages, message{sender: "ai", text: processedContent, mode: m.viewMode})
+			m.messages = append(m.messages, message{sender: "ai", text: processedContent, mode: m.viewMode, reasoning: m.reasoning})
 
 			// Auto-save planning responses as plans
 			if m.viewMode == ViewModePlanning && processedContent != "" {
@@ -422,6 +440,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			// Clear streaming state
 			m.partial = ""
+			m.reasoning = ""
 			m.streamChunks = 0 // Reset chunk counter
 			m.isThinking = false
 			m.thinkingText = ""
@@ -676,6 +695,18 @@ func (m model) updateChatMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		// Clear viewport content for mode selection
 		m.viewport.SetContent("")
 		return m, nil
+	case tea.KeyCtrlT:
+		// Expand or collapse reasoning blocks
+		m.showReasoning = !m.showReasoning
+		content := m.renderChatContent()
+		if m.streaming {
+			content += m.renderReasoning(m.reasoning, true)
+		}
+		m.viewport.SetContent(content)
+		if !m.userScrolling {
+			m.viewport.GotoBottom()
+		}
+		return m, nil
 	case tea.KeyUp:
 		// Allow scrolling up in the viewport
 		m.viewport.ScrollUp(1)
@@ -697,6 +728,16 @@ func (m model) updateChatMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				text:   userText,
 				mode:   m.viewMode,
 			})
+
+			// Chat commands such as /undo are answered here, not by the model
+			if output, ok := slashCommand(m.agent.Checkpoints(), userText); ok {
+				m.messages = append(m.messages, message{sender: "system", text: output, mode: m.viewMode})
+				m.viewport.SetContent(m.renderChatContent())
+				m.viewport.GotoBottom()
+				m.userScrolling = false
+				m.input.Reset()
+				return m, nil
+			}
 			// Clear previous tool calls and thinking state for new conversation
 			m.toolCalls = make([]toolCallInfo, 0)
 			m.currentToolCall = nil
@@ -820,11 +861,12 @@ func (m model) renderConfigSummary() string {
 
 	summary.WriteString(titleStyle.Render("Configuration Summary") + "\n")
 
-	// Provider and model info
+	// Provider and model info, with limits from the model registry
 	provider := m.config.Provider
-	model := m.config.Model
+	modelInfo := m.agent.Model()
 	summary.WriteString(infoStyle.Render("Provider: ") + highlightStyle.Render(provider) + "\n")
-	summary.WriteString(infoStyle.Render("Model: ") + highlightStyle.Render(model) + "\n")
+	summary.WriteString(infoStyle.Render("Model: ") + highlightStyle.Render(modelInfo.ID) + "\n")
+	summary.WriteString(infoStyle.Render("Context: ") + highlightStyle.Render(formatModelLimits(modelInfo)) + "\n")
 
 	// Approval mode
 	approvalMode := m.config.Agent.ApprovalMode
@@ -844,6 +886,21 @@ func (m model) renderConfigSummary() string {
 	return boxStyle.Render(summary.String())
 }
 
+// formatModelLimits summarises a model's context window and capabilities
+func formatModelLimits(info ai.ModelInfo) string {
+	limits := fmt.Sprintf("%dk tokens, %dk output", info.ContextWindow/1000, info.MaxOutputTokens/1000)
+	if !info.SupportsTools {
+		limits += ", no tools"
+	}
+	if info.SupportsVision {
+		limits += ", vision"
+	}
+	if !info.Known {
+		limits += " (unregistered model)"
+	}
+	return limits
+}
+
 // renderChatMode renders the chat interface for the current mode
 func (m model) renderChatMode() string {
 	var inputView string
@@ -886,6 +943,9 @@ func (m model) renderChatMode() string {
 	header := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("205")).
 		Render(fmt.Sprintf("%s %s Mode (timeout: %ds) - ESC to return", currentMode.Icon, currentMode.Name, timeout))
+	if spending := m.renderSpending(); spending != "" {
+		header += "  " + spending
+	}
 
 	footer := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("240")).
@@ -906,6 +966,22 @@ func (m model) renderChatMode() string {
 	)
 }
 
+// renderSpending shows the session's running cost, highlighted once a
+// spending limit has been passed
+func (m model) renderSpending() string {
+	status, ok := m.agent.Spending()
+	if !ok {
+		return ""
+	}
+	text := fmt.Sprintf("$%.4f session, $%.2f today", status.SessionCost, status.DailyCost)
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	if status.Warning != "" {
+		text += " - " + status.Warning
+		style = style.Foreground(lipgloss.Color("3")).Bold(true)
+	}
+	return style.Render(text)
+}
+
 // renderChatContent formats the chat history for the viewport
 func (m model) renderChatContent() string {
 	// Filter messages for current mode
@@ -925,6 +1001,11 @@ func (m model) renderChatContent() string {
 				Foreground(lipgloss.Color("2")).
 				Bold(true).
 				Render("You:   ")
+		} else if msg.sender == "system" {
+			prefix = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("3")).
+				Bold(true).
+				Render("Duck:  ")
 		} else {
 			prefix = lipgloss.NewStyle().
 				Foreground(lipgloss.Color("4")).
@@ -937,6 +1018,7 @@ func (m model) renderChatContent() string {
 			Width(m.viewport.Width - 7).
 			Render(msg.text)
 
+		fullContent.WriteString(m.renderReasoning(msg.reasoning, false))
 		fullContent.WriteString(prefix + wrappedText + "\n\n")
 	}
 
@@ -974,6 +1056,38 @@ func (m model) renderChatContent() string {
 	return content
 }
 
+// renderReasoning renders a model's thinking as a block above its reply,
+// collapsed to a one-line summary unless expanded with Ctrl+T
+func (m model) renderReasoning(reasoning string, live bool) string {
+	if reasoning == "" {
+		return ""
+	}
+
+	headerStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("240")).
+		Italic(true)
+
+	words := len(strings.Fields(reasoning))
+	summary := fmt.Sprintf("💭 Thought for %d words", words)
+	if live {
+		summary = fmt.Sprintf("💭 Thinking... (%d words)", words)
+	}
+
+	if !m.showReasoning {
+		return headerStyle.Render(summary+" - Ctrl+T to expand") + "\n\n"
+	}
+
+	body := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("245")).
+		Border(lipgloss.NormalBorder(), false, false, false, true).
+		BorderForeground(lipgloss.Color("240")).
+		PaddingLeft(1).
+		Width(m.viewport.Width - 3).
+		Render(strings.TrimSpace(reasoning))
+
+	return headerStyle.Render(summary+" - Ctrl+T to collapse") + "\n" + body + "\n\n"
+}
+
 // renderFullWidthBar renders the full-width bar underneath agent outputs
 func (m model) renderFullWidthBar() string {
 	if !m.isThinking && len(m.toolCalls) == 0 {
@@ -1316,6 +1430,10 @@ func makeAIRequest(input string, mode ViewMode, ag *agent.Agent, cfg *config.Con
 		// Create a context with appropriate timeout
 		ctx, cancel := context.WithTimeout(context.Background(), timeout)
 
+		// Each mode has its own thinking budget
+		ag.SetThinkingBudget(cfg.Thinking.BudgetFor(modeConfigName(mode)))
+		ag.SetMode(modeConfigName(mode))
+
 		var ch <-chan agent.StreamEvent
 		var err error
 
@@ -1346,6 +1464,22 @@ func makeAIRequest(input string, mode ViewMode, ag *agent.Agent, cfg *config.Con
 	}
 }
 
+// modeConfigName returns the name a mode is configured under
+func modeConfigName(mode ViewMode) string {
+	switch mode {
+	case ViewModePlanning:
+		return "planning"
+	case ViewModeBuilding:
+		return "building"
+	case ViewModeDebugging:
+		return "debugging"
+	case ViewModeEnhance:
+		return "enhance"
+	default:
+		return ""
+	}
+}
+
 func listenStream(ch <-chan agent.StreamEvent) tea.Cmd {
 	return func() tea.Msg {
 		ev, ok := <-ch
@@ -0,0 +1,279 @@
+// Package usage keeps a local ledger of token usage and estimated cost.
+// Every response is appended as one JSON line to a file per day
+// (YYYY-MM-DD.jsonl) so that sessions, days, models and modes can be
+// totalled later, and so that spending limits hold across sessions.
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// dateFormat names the daily ledger files
+const dateFormat = "2006-01-02"
+
+// ErrLimitReached is returned (wrapped) by Tracker.Check once a hard
+// spending limit has been reached
+var ErrLimitReached = errors.New("spending limit reached")
+
+// Record is the usage and cost of one model response
+type Record struct {
+	Time             time.Time `json:"time"`
+	Session          string    `json:"session"`
+	Provider         string    `json:"provider"`
+	Model            string    `json:"model"`
+	Mode             string    `json:"mode,omitempty"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	CacheReadTokens  int       `json:"cache_read_tokens,omitempty"`
+	CacheWriteTokens int       `json:"cache_write_tokens,omitempty"`
+	// Cost is the estimate in USD from the model registry's prices
+	Cost float64 `json:"cost"`
+}
+
+// Limits are spending limits in USD; zero disables a limit
+type Limits struct {
+	SessionWarn  float64
+	SessionLimit float64
+	DailyWarn    float64
+	DailyLimit   float64
+}
+
+// Status is the running cost against the limits
+type Status struct {
+	SessionCost float64
+	DailyCost   float64
+	// Warning describes the first warn or hard limit passed, if any
+	Warning string
+}
+
+// Tracker records usage for one session and enforces the limits
+type Tracker struct {
+	dir     string
+	session string
+	limits  Limits
+
+	mu          sync.Mutex
+	sessionCost float64
+	day         string
+	dailyCost   float64
+}
+
+// NewTracker starts a session ledger in dir, picking up what has already
+// been spent today
+func NewTracker(dir string, limits Limits) (*Tracker, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create usage directory: %w", err)
+	}
+
+	t := &Tracker{dir: dir, session: uuid.NewString(), limits: limits}
+	if err := t.loadDay(time.Now()); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Session returns the session ID written with each record
+func (t *Tracker) Session() string {
+	return t.session
+}
+
+// loadDay totals what the ledger of now's day already holds
+func (t *Tracker) loadDay(now time.Time) error {
+	day := now.Format(dateFormat)
+	records, err := readFile(filepath.Join(t.dir, day+".jsonl"))
+	if err != nil {
+		return err
+	}
+	t.day, t.dailyCost = day, 0
+	for _, r := range records {
+		t.dailyCost += r.Cost
+	}
+	return nil
+}
+
+// Add appends a record to today's ledger and returns the updated status
+func (t *Tracker) Add(r Record) (Status, error) {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	r.Session = t.session
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return t.Status(), err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Sessions running past midnight start a new daily total
+	if day := r.Time.Format(dateFormat); day != t.day {
+		t.day, t.dailyCost = day, 0
+	}
+	t.sessionCost += r.Cost
+	t.dailyCost += r.Cost
+
+	file, err := os.OpenFile(filepath.Join(t.dir, t.day+".jsonl"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return t.status(), fmt.Errorf("failed to open usage ledger: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return t.status(), fmt.Errorf("failed to write usage ledger: %w", err)
+	}
+	return t.status(), nil
+}
+
+// Status returns the running costs and any limit warning
+func (t *Tracker) Status() Status {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.status()
+}
+
+func (t *Tracker) status() Status {
+	status := Status{SessionCost: t.sessionCost, DailyCost: t.dailyCost}
+	if t.day != time.Now().Format(dateFormat) {
+		status.DailyCost = 0
+	}
+
+	checks := []struct {
+		name  string
+		cost  float64
+		limit float64
+	}{
+		{"session limit", status.SessionCost, t.limits.SessionLimit},
+		{"daily limit", status.DailyCost, t.limits.DailyLimit},
+		{"session warning", status.SessionCost, t.limits.SessionWarn},
+		{"daily warning", status.DailyCost, t.limits.DailyWarn},
+	}
+	for _, c := range checks {
+		if c.limit > 0 && c.cost >= c.limit {
+			status.Warning = fmt.Sprintf("%s of $%.2f reached ($%.2f spent)", c.name, c.limit, c.cost)
+			break
+		}
+	}
+	return status
+}
+
+// Check returns an error wrapping ErrLimitReached once the session or
+// daily hard limit has been reached
+func (t *Tracker) Check() error {
+	status := t.Status()
+	if t.limits.SessionLimit > 0 && status.SessionCost >= t.limits.SessionLimit {
+		return fmt.Errorf("%w: session has spent $%.2f of $%.2f", ErrLimitReached, status.SessionCost, t.limits.SessionLimit)
+	}
+	if t.limits.DailyLimit > 0 && status.DailyCost >= t.limits.DailyLimit {
+		return fmt.Errorf("%w: $%.2f spent today of $%.2f", ErrLimitReached, status.DailyCost, t.limits.DailyLimit)
+	}
+	return nil
+}
+
+// Load returns the records from since's day onwards, oldest first
+func Load(dir string, since time.Time) ([]Record, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	first := since.Format(dateFormat)
+	var records []Record
+	for _, file := range files {
+		if strings.TrimSuffix(filepath.Base(file), ".jsonl") < first {
+			continue
+		}
+		day, err := readFile(file)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, day...)
+	}
+	return records, nil
+}
+
+// readFile reads one ledger; a missing file is empty
+func readFile(path string) ([]Record, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open usage ledger: %w", err)
+	}
+	defer file.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var r Record
+		// Skip lines cut short by a crash mid-write
+		if json.Unmarshal(scanner.Bytes(), &r) == nil {
+			records = append(records, r)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read usage ledger %s: %w", path, err)
+	}
+	return records, nil
+}
+
+// Summary totals the records sharing a key
+type Summary struct {
+	Key              string  `json:"key"`
+	Requests         int     `json:"requests"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	CacheReadTokens  int     `json:"cache_read_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// Summarize groups records by key and totals each group, sorted by key
+func Summarize(records []Record, key func(Record) string) []Summary {
+	groups := make(map[string]*Summary)
+	for _, r := range records {
+		k := key(r)
+		s, ok := groups[k]
+		if !ok {
+			s = &Summary{Key: k}
+			groups[k] = s
+		}
+		s.Requests++
+		s.PromptTokens += r.PromptTokens
+		s.CompletionTokens += r.CompletionTokens
+		s.CacheReadTokens += r.CacheReadTokens
+		s.Cost += r.Cost
+	}
+
+	summaries := make([]Summary, 0, len(groups))
+	for _, s := range groups {
+		summaries = append(summaries, *s)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Key < summaries[j].Key })
+	return summaries
+}
+
+// ByDay groups records by local date
+func ByDay(r Record) string { return r.Time.Local().Format(dateFormat) }
+
+// ByModel groups records by provider and model
+func ByModel(r Record) string { return r.Provider + "/" + r.Model }
+
+// ByMode groups records by TUI mode
+func ByMode(r Record) string {
+	if r.Mode == "" {
+		return "(none)"
+	}
+	return r.Mode
+}
@@ -0,0 +1,81 @@
+package usage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrackerPersistsAndResumesDailyTotal(t *testing.T) {
+	dir := t.TempDir()
+
+	first, err := NewTracker(dir, Limits{})
+	require.NoError(t, err)
+	_, err = first.Add(Record{Provider: "openai", Model: "gpt-4o", Mode: "planning", PromptTokens: 1000, Cost: 0.25})
+	require.NoError(t, err)
+
+	// A later session starts with today's spending but its own session total
+	second, err := NewTracker(dir, Limits{})
+	require.NoError(t, err)
+	status, err := second.Add(Record{Provider: "openai", Model: "gpt-4o", Cost: 0.5})
+	require.NoError(t, err)
+	assert.InDelta(t, 0.5, status.SessionCost, 1e-9)
+	assert.InDelta(t, 0.75, status.DailyCost, 1e-9)
+	assert.NotEqual(t, first.Session(), second.Session())
+
+	records, err := Load(dir, time.Now())
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, first.Session(), records[0].Session)
+	assert.Equal(t, "planning", records[0].Mode)
+}
+
+func TestTrackerLimits(t *testing.T) {
+	tracker, err := NewTracker(t.TempDir(), Limits{SessionWarn: 1, SessionLimit: 2, DailyLimit: 10})
+	require.NoError(t, err)
+
+	status, err := tracker.Add(Record{Cost: 1.5})
+	require.NoError(t, err)
+	assert.Contains(t, status.Warning, "session warning of $1.00")
+	assert.NoError(t, tracker.Check(), "warn limits don't block turns")
+
+	status, err = tracker.Add(Record{Cost: 0.5})
+	require.NoError(t, err)
+	assert.Contains(t, status.Warning, "session limit of $2.00")
+	err = tracker.Check()
+	assert.True(t, errors.Is(err, ErrLimitReached), "got %v", err)
+}
+
+func TestLoadSkipsOldDaysAndBadLines(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "2020-01-01.jsonl"), []byte(`{"cost":9}`+"\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "2020-01-03.jsonl"), []byte(`{"cost":1}`+"\n"+`{"cost":`), 0600))
+
+	records, err := Load(dir, time.Date(2020, 1, 2, 12, 0, 0, 0, time.Local))
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, 1.0, records[0].Cost)
+}
+
+func TestSummarize(t *testing.T) {
+	records := []Record{
+		{Provider: "openai", Model: "gpt-4o", Mode: "building", PromptTokens: 100, CompletionTokens: 10, Cost: 0.1},
+		{Provider: "anthropic", Model: "claude-sonnet-4-20250514", Mode: "building", PromptTokens: 200, Cost: 0.2},
+		{Provider: "openai", Model: "gpt-4o", PromptTokens: 300, CompletionTokens: 30, Cost: 0.3},
+	}
+
+	byModel := Summarize(records, ByModel)
+	require.Len(t, byModel, 2)
+	assert.Equal(t, "anthropic/claude-sonnet-4-20250514", byModel[0].Key)
+	assert.Equal(t, Summary{Key: "openai/gpt-4o", Requests: 2, PromptTokens: 400, CompletionTokens: 40, Cost: 0.4}, byModel[1])
+
+	byMode := Summarize(records, ByMode)
+	require.Len(t, byMode, 2)
+	assert.Equal(t, "(none)", byMode[0].Key)
+	assert.Equal(t, 2, byMode[1].Requests)
+}